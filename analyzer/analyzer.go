@@ -0,0 +1,133 @@
+// Package analyzer provides an analyzer reporting stale code generated by
+// toe: code generated from an interface that has changed since.
+package analyzer
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/phildrip/toe/generator"
+	"github.com/phildrip/toe/model"
+)
+
+// Analyzer reports files generated by toe whose recorded interface hash no
+// longer matches the interface they were generated from.
+var Analyzer = &analysis.Analyzer{
+	Name: "toestale",
+	Doc: "report code generated by toe from interfaces that have changed since\n\n" +
+		"Files generated by toe record the interface they implement and a hash of its methods " +
+		"in their header. The analyzer reports files whose hash no longer matches the interface.",
+	Run: run,
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	for _, file := range pass.Files {
+		h, ok := generator.ParseHeader(file)
+		if !ok {
+			continue
+		}
+		filename := filepath.Base(pass.Fset.Position(file.Package).Filename)
+
+		for _, recorded := range h.Interfaces() {
+			obj, fset, err := findInterface(pass, recorded.Path, recorded.Name)
+			if err != nil {
+				pass.Reportf(file.Package, "%s was generated from %s.%s, which can't be found: %v",
+					filename, recorded.Path, recorded.Name, err)
+				continue
+			}
+			named, ok := obj.Type().(*types.Named)
+			if _, isFunc := obj.Type().Underlying().(*types.Signature); !ok || !types.IsInterface(named) && !isFunc {
+				pass.Reportf(file.Package, "%s was generated from %s.%s, which is no longer an interface or func type",
+					filename, recorded.Path, recorded.Name)
+				continue
+			}
+
+			if model.Hash(named) == recorded.Hash {
+				continue
+			}
+			diag := analysis.Diagnostic{
+				Pos: file.Package,
+				Message: fmt.Sprintf("%s is stale: %s.%s (%s) has changed since it was generated; regenerate it",
+					filename, recorded.Path, recorded.Name, fset.Position(obj.Pos())),
+			}
+			if fset == pass.Fset {
+				diag.Related = []analysis.RelatedInformation{{
+					Pos:     obj.Pos(),
+					Message: fmt.Sprintf("%s is declared here", recorded.Name),
+				}}
+			}
+			pass.Report(diag)
+		}
+	}
+	return nil, nil
+}
+
+// findInterface returns the type name path.Name and the file set holding
+// its position. It looks in the package being analyzed and its
+// dependencies, then loads the package at path: stubs generated into
+// another package need not import the interface's package.
+func findInterface(pass *analysis.Pass, path string, name string) (types.Object, *token.FileSet, error) {
+	if pkg := findPackage(pass.Pkg, path, make(map[*types.Package]bool)); pkg != nil {
+		if obj := pkg.Scope().Lookup(name); obj != nil {
+			return obj, pass.Fset, nil
+		}
+		return nil, nil, fmt.Errorf("%s has no declaration %s", path, name)
+	}
+
+	loaded, err := loadPackage(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	obj := loaded.Types.Scope().Lookup(name)
+	if obj == nil {
+		return nil, nil, fmt.Errorf("%s has no declaration %s", path, name)
+	}
+	return obj, loaded.Fset, nil
+}
+
+// findPackage returns the package with the given path among pkg and its
+// dependencies, or nil.
+func findPackage(pkg *types.Package, path string, seen map[*types.Package]bool) *types.Package {
+	if pkg.Path() == path {
+		return pkg
+	}
+	seen[pkg] = true
+	for _, imp := range pkg.Imports() {
+		if seen[imp] {
+			continue
+		}
+		if found := findPackage(imp, path, seen); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+var loaded sync.Map // package path to *packages.Package
+
+// loadPackage loads the package with the given path, caching the result
+// for the other files and packages analyzed by the process.
+func loadPackage(path string) (*packages.Package, error) {
+	if pkg, ok := loaded.Load(path); ok {
+		return pkg.(*packages.Package), nil
+	}
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedTypes |
+			packages.NeedTypesInfo | packages.NeedImports | packages.NeedDeps,
+	}
+	pkgs, err := packages.Load(cfg, path)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 || len(pkgs[0].Errors) > 0 {
+		return nil, fmt.Errorf("errors loading %s", path)
+	}
+	loaded.Store(path, pkgs[0])
+	return pkgs[0], nil
+}
@@ -0,0 +1,15 @@
+// Command toestale reports stale code generated by toe. It can be run
+// directly, or by go vet:
+//
+//	go vet -vettool=$(which toestale) ./...
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"github.com/phildrip/toe/analyzer"
+)
+
+func main() {
+	singlechecker.Main(analyzer.Analyzer)
+}
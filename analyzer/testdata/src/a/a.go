@@ -0,0 +1,5 @@
+package a
+
+type Thinger interface {
+	Thing(n int) error
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/phildrip/toe/generator"
+)
+
+// stubConfig is an entry of the config file's stubs list, an interface to
+// generate code for in batch mode.
+type stubConfig struct {
+	// Dir is the directory of the interface's package, relative to the
+	// config file. Output is relative to it, as with go:generate.
+	Dir string `json:"dir"`
+	generator.Options
+}
+
+// batchResult counts the outcomes of the entries of a batch run.
+type batchResult struct {
+	generated, skipped, failed int
+}
+
+// manifest is the record of a batch run written to the config's manifest
+// file, for build systems and other tools consuming the generated code.
+type manifest struct {
+	Stubs []manifestEntry `json:"stubs"`
+}
+
+// manifestEntry records the generated files of an interface.
+type manifestEntry struct {
+	// Interface is the interface's import path and name, such as
+	// "github.com/phildrip/toe/ref.Thinger".
+	Interface string         `json:"interface"`
+	Style     string         `json:"style"`
+	Files     []manifestFile `json:"files"`
+}
+
+// manifestFile is a generated file, with its path relative to the config
+// file and the hex SHA-256 hash of its content.
+type manifestFile struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+}
+
+// defaultManifest is the name of the manifest written next to the config
+// file when it doesn't name one.
+const defaultManifest = "toe.manifest.json"
+
+// runBatch generates the code for each of the stubs in cfg, read from a
+// config file in configDir, reporting the progress of each package on
+// stderr, followed by a summary. Entries whose files are unchanged are
+// skipped rather than rewritten. The files of the entries that succeeded
+// are recorded in the manifest. Files recorded in the previous manifest
+// whose interface no longer exists are reported as orphaned, and deleted
+// if prune is set. It exits with an error if any entry failed.
+func runBatch(cfg *config, configDir string, postCmd string, prune bool) {
+	dirs, byDir := groupStubs(cfg, configDir)
+	models := make(map[string]*generator.Model)
+	loadErrs := make(map[string]error)
+	for _, dir := range dirs {
+		models[dir], loadErrs[dir] = generator.Load(dir)
+	}
+	outputs, err := checkOutputs(cfg, configDir, models)
+	if err != nil {
+		fatal("checking outputs", err)
+	}
+	manifestPath := cfg.Manifest
+	if manifestPath == "" {
+		manifestPath = defaultManifest
+	}
+	manifestPath = filepath.Join(configDir, manifestPath)
+	previous, err := readManifest(manifestPath)
+	if err != nil {
+		fatal("reading manifest", err)
+	}
+
+	var result batchResult
+	var record manifest
+	for i, dir := range dirs {
+		var lines []string
+		model, loadErr := models[dir], loadErrs[dir]
+		for _, stub := range byDir[dir] {
+			err := loadErr
+			var files []generator.File
+			var written []string
+			if err == nil {
+				files, written, err = generateBatchEntry(model, dir, stub, cfg, postCmd)
+			}
+			switch {
+			case err != nil:
+				result.failed++
+				lines = append(lines, fmt.Sprintf("%s failed: %v", stub.Interface, err))
+				continue
+			case len(written) == 0:
+				result.skipped++
+				lines = append(lines, stub.Interface+" unchanged")
+			default:
+				result.generated++
+				lines = append(lines, stub.Interface+" generated "+strings.Join(written, ", "))
+			}
+			record.Stubs = append(record.Stubs, newManifestEntry(model, configDir, dir, stub, files))
+		}
+		fmt.Fprintf(os.Stderr, "[%d/%d] %s: %s\n", i+1, len(dirs), dir, strings.Join(lines, "; "))
+	}
+	fmt.Fprintf(os.Stderr, "%d generated, %d skipped as unchanged, %d failed\n",
+		result.generated, result.skipped, result.failed)
+
+	pruneOrphans(findOrphans(previous, outputs, configDir), prune, &record)
+	if err := writeManifest(manifestPath, record); err != nil {
+		fatal("writing manifest", err)
+	}
+	if result.failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// checkOutputs returns the paths of the files the stubs of cfg, read from
+// a config file in configDir, would write, and an error naming those that
+// would write the same file, or declare types of the same name in the same
+// directory, with the other's. models are the packages of the stubs by
+// directory; stubs whose package or outputs can't be loaded are left to
+// fail when they are generated, and their output is taken to be as
+// configured.
+func checkOutputs(cfg *config, configDir string, models map[string]*generator.Model) (map[string]bool, error) {
+	var errs []error
+	files := make(map[string]stubConfig)
+	types := make(map[string]stubConfig)
+	for _, stub := range cfg.Stubs {
+		dir := filepath.Join(configDir, stub.Dir)
+		model := models[dir]
+		if model == nil {
+			files[filepath.Join(dir, stub.Output)] = stub
+			continue
+		}
+		names, typeName, err := generator.OutputFiles(model, stubOptions(stub, cfg))
+		if err != nil {
+			files[filepath.Join(dir, stub.Output)] = stub
+			continue
+		}
+		conflict := false
+		for _, name := range names {
+			path := filepath.Join(dir, name)
+			if other, ok := files[path]; ok {
+				errs = append(errs, fmt.Errorf("%s and %s both write %s", describeStub(other), describeStub(stub), path))
+				conflict = true
+			}
+			files[path] = stub
+		}
+		if conflict {
+			continue
+		}
+		key := filepath.Join(dir, filepath.Dir(names[0])) + " " + typeName
+		if other, ok := types[key]; ok {
+			errs = append(errs, fmt.Errorf("%s and %s both declare %s in %s",
+				describeStub(other), describeStub(stub), typeName, filepath.Join(dir, filepath.Dir(names[0]))))
+		}
+		types[key] = stub
+	}
+	outputs := make(map[string]bool)
+	for path := range files {
+		outputs[path] = true
+	}
+	return outputs, errors.Join(errs...)
+}
+
+// describeStub identifies stub in error messages.
+func describeStub(stub stubConfig) string {
+	return fmt.Sprintf("%s in %s (output %s)", stub.Interface, stub.Dir, stub.Output)
+}
+
+// groupStubs groups the stubs of cfg, read from a config file in
+// configDir, by package directory, so that each package is loaded once. It
+// returns the directories in the order their packages first appear.
+func groupStubs(cfg *config, configDir string) ([]string, map[string][]stubConfig) {
+	var dirs []string
+	byDir := make(map[string][]stubConfig)
+	for _, stub := range cfg.Stubs {
+		dir := filepath.Join(configDir, stub.Dir)
+		if _, ok := byDir[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+		byDir[dir] = append(byDir[dir], stub)
+	}
+	return dirs, byDir
+}
+
+// stubOptions returns the options of stub, with the settings of cfg it
+// doesn't override.
+func stubOptions(stub stubConfig, cfg *config) generator.Options {
+	opts := stub.Options
+	if opts.ArgNaming == "" {
+		opts.ArgNaming = cfg.ArgNaming
+	}
+	if opts.Assertions == "" {
+		opts.Assertions = cfg.Assertions
+	}
+	opts.Imports = append(append([]string(nil), cfg.Imports...), opts.Imports...)
+	opts.Defaults = mergeTypeMaps(cfg.Defaults, opts.Defaults)
+	opts.ReplaceTypes = mergeTypeMaps(cfg.ReplaceTypes, opts.ReplaceTypes)
+	return opts
+}
+
+// generateBatchEntry generates the files of stub in the package in dir,
+// loaded as model, returning them along with the names of those written:
+// the files whose content changed.
+func generateBatchEntry(model *generator.Model, dir string, stub stubConfig, cfg *config, postCmd string) ([]generator.File, []string, error) {
+	opts := stubOptions(stub, cfg)
+	if opts.Output == "" {
+		return nil, nil, fmt.Errorf("no output file")
+	}
+	files, err := generator.Generate(model, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var written []string
+	for i := range files {
+		file := &files[i]
+		path := filepath.Join(dir, file.Name)
+		if file.Content, err = keepRegions(path, file.Content); err != nil {
+			return nil, nil, err
+		}
+		if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, file.Content) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, nil, err
+		}
+		if err := os.WriteFile(path, file.Content, 0644); err != nil {
+			return nil, nil, err
+		}
+		if postCmd != "" {
+			if err := runPostCmd(postCmd, path); err != nil {
+				return nil, nil, err
+			}
+		}
+		written = append(written, file.Name)
+	}
+	return files, written, nil
+}
+
+// newManifestEntry returns the manifest entry of stub, generated as files
+// in the package in dir, loaded as model.
+func newManifestEntry(model *generator.Model, configDir, dir string, stub stubConfig, files []generator.File) manifestEntry {
+	entry := manifestEntry{
+		Interface: model.Path + "." + stub.Interface,
+		Style:     stub.Style,
+	}
+	if entry.Style == "" {
+		entry.Style = "stub"
+	}
+	for _, file := range files {
+		path := filepath.Join(dir, file.Name)
+		if rel, err := filepath.Rel(configDir, path); err == nil {
+			path = rel
+		}
+		sum := sha256.Sum256(file.Content)
+		entry.Files = append(entry.Files, manifestFile{
+			Path:   filepath.ToSlash(path),
+			SHA256: hex.EncodeToString(sum[:]),
+		})
+	}
+	return entry
+}
+
+// writeManifest writes m to path as indented JSON, unless the file already
+// holds it.
+func writeManifest(path string, m manifest) error {
+	b, err := json.MarshalIndent(m, "", "    ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, b) {
+		return nil
+	}
+	return os.WriteFile(path, b, 0644)
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/phildrip/toe/generator"
+	"github.com/phildrip/toe/model"
+)
+
+// runBazel implements the bazel command, which prints Bazel rules
+// generating the code for each of the stubs in a config file, for
+// repositories built with Bazel rather than go generate.
+func runBazel(args []string) {
+	fs := flag.NewFlagSet("bazel", flag.ExitOnError)
+	var configFile, tool string
+	fs.StringVar(&configFile, "config", "", "JSON file listing the stubs")
+	fs.StringVar(&tool, "tool", "//tools/toe", "label of the toe binary")
+	addBuildFlags(fs)
+	args = parseInterspersed(fs, args)
+
+	if len(args) != 0 || configFile == "" {
+		fmt.Fprintf(os.Stderr, "Usage: %s bazel [-tool <label>] -config <file>\n", os.Args[0])
+		os.Exit(1)
+	}
+	cfg, err := loadConfig(configFile)
+	if err != nil {
+		fatal("loading config", err)
+	}
+
+	// Labels are relative to the config file's directory, the workspace
+	// root.
+	root, err := filepath.Abs(filepath.Dir(configFile))
+	if err != nil {
+		fatal("loading config", err)
+	}
+	var moduleFiles []string
+	for _, name := range []string{"go.mod", "go.sum"} {
+		if _, err := os.Stat(filepath.Join(root, name)); err == nil {
+			moduleFiles = append(moduleFiles, filepath.Join(root, name))
+		}
+	}
+
+	dirs, byDir := groupStubs(cfg, root)
+	for i, dir := range dirs {
+		pkg, err := generator.Load(dir)
+		if err != nil {
+			fatal("finding interface", err)
+		}
+		deps, err := model.LoadDependencies(dir)
+		if err != nil {
+			fatal("listing dependencies", err)
+		}
+		rel, err := filepath.Rel(root, dir)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			fatal("writing rules", fmt.Errorf("%s is outside the workspace %s", dir, root))
+		}
+		// The root package's directory is empty, as in its labels.
+		rel = strings.TrimPrefix(filepath.ToSlash(rel), ".")
+
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("# %s\n", path.Join(rel, "BUILD.bazel"))
+		for _, stub := range byDir[dir] {
+			rules, err := bazelRules(pkg, root, rel, deps, moduleFiles, tool, stubOptions(stub, cfg))
+			if err != nil {
+				fatal("writing rules for "+stub.Interface, err)
+			}
+			fmt.Print(rules)
+		}
+	}
+}
+
+// bazelRules returns a genrule generating the files of the stub with
+// options opts in pkg, the package in dir rel of the workspace at root,
+// followed by a go_library of them unless they are test files.
+func bazelRules(pkg *generator.Model, root, rel string, deps *model.Dependencies, moduleFiles []string, tool string, opts generator.Options) (string, error) {
+	var err error
+	if opts.Output == "" {
+		return "", fmt.Errorf("no output file")
+	}
+	if opts.PartialsDir != "" || opts.TemplateModule != "" || opts.FuncsPlugin != "" {
+		return "", fmt.Errorf("templateDir, templateModule and funcsPlugin aren't supported by Bazel rules")
+	}
+	if opts.Output, err = generator.OutputName(pkg, opts); err != nil {
+		return "", err
+	}
+	files, err := generator.Generate(pkg, opts)
+	if err != nil {
+		return "", err
+	}
+	outs := make(map[string]bool)
+	for _, file := range files {
+		outs[filepath.Join(pkg.Dir, file.Name)] = true
+	}
+
+	var srcs []string
+	for _, file := range append(append([]string(nil), moduleFiles...), deps.Files...) {
+		if outs[file] {
+			continue
+		}
+		if label, ok := bazelLabel(root, rel, file); ok {
+			srcs = append(srcs, label)
+		}
+	}
+
+	args := []string{"$(location " + tool + ")"}
+	if opts.Style != "" {
+		args = append(args, "-style", opts.Style)
+	}
+	if opts.PackageName != "" {
+		args = append(args, "-pkg", opts.PackageName)
+	}
+	if opts.TemplateFile != "" {
+		label, ok := bazelLabel(root, rel, filepath.Join(root, opts.TemplateFile))
+		if !ok {
+			return "", fmt.Errorf("template %s is outside the workspace", opts.TemplateFile)
+		}
+		srcs = append(srcs, label)
+		args = append(args, "-template", "$(location "+label+")")
+	}
+	if opts.ArgNaming != "" {
+		args = append(args, "-arg-naming", opts.ArgNaming)
+	}
+	for _, imp := range opts.Imports {
+		args = append(args, "-import", imp)
+	}
+	args = append(args, typeMapArgs("-default", opts.Defaults)...)
+	args = append(args, typeMapArgs("-replace-type", opts.ReplaceTypes)...)
+	if opts.EOL != "" {
+		args = append(args, "-eol", opts.EOL)
+	}
+	if opts.Assertions != "" {
+		args = append(args, "-assertions", opts.Assertions)
+	}
+	if len(opts.Methods) > 0 {
+		args = append(args, "-methods", strings.Join(opts.Methods, ","))
+	}
+	if len(opts.ExcludeMethods) > 0 {
+		args = append(args, "-exclude-methods", strings.Join(opts.ExcludeMethods, ","))
+	}
+	for _, f := range []struct {
+		set  bool
+		name string
+	}{
+		{opts.ErrorUnconfigured, "-error-unconfigured"},
+		{opts.CallChannels, "-call-channels"},
+		{opts.EmbedInterface, "-embed-interface"},
+		{opts.FuncAdapter, "-func-adapter"},
+		{opts.SplitHelpers, "-split-helpers"},
+		{opts.WithExample, "-with-example"},
+		{opts.WithRaceTest, "-with-race-test"},
+		{opts.WithFuzz, "-with-fuzz"},
+		{opts.DisableFormatting, "-no-fmt"},
+	} {
+		if f.set {
+			args = append(args, f.name)
+		}
+	}
+	dir := "./" + rel
+	if rel == "" {
+		dir = "."
+	}
+	args = append(args, "-o", "$(RULEDIR)/"+filepath.ToSlash(opts.Output), dir, opts.Interface)
+	for i, arg := range args {
+		// Bazel expands the locations before the shell runs.
+		if !strings.HasPrefix(arg, "$(location ") {
+			args[i] = shellQuote(arg)
+		}
+	}
+
+	name := strings.TrimSuffix(filepath.Base(opts.Output), ".go")
+	var b strings.Builder
+	fmt.Fprintf(&b, "\ngenrule(\n")
+	fmt.Fprintf(&b, "    name = %q,\n", name+"_gen")
+	writeBazelList(&b, "srcs", srcs)
+	var names []string
+	for _, file := range files {
+		names = append(names, filepath.ToSlash(file.Name))
+	}
+	writeBazelList(&b, "outs", names)
+	fmt.Fprintf(&b, "    cmd = %q,\n", strings.Join(args, " "))
+	fmt.Fprintf(&b, "    tools = [%q],\n", tool)
+	// The toe binary loads packages with the go command, which needs the
+	// module cache.
+	fmt.Fprintf(&b, "    local = True,\n")
+	fmt.Fprintf(&b, ")\n")
+
+	var libSrcs []string
+	for _, name := range names {
+		if !strings.HasSuffix(name, "_test.go") {
+			libSrcs = append(libSrcs, name)
+		}
+	}
+	if len(libSrcs) > 0 {
+		// Gazelle fills in the deps of the library.
+		fmt.Fprintf(&b, "\ngo_library(\n")
+		fmt.Fprintf(&b, "    name = %q,\n", name)
+		writeBazelList(&b, "srcs", libSrcs)
+		fmt.Fprintf(&b, "    importpath = %q,\n", path.Join(pkg.Path, path.Dir(filepath.ToSlash(opts.Output))))
+		fmt.Fprintf(&b, ")\n")
+	}
+	return b.String(), nil
+}
+
+// typeMapArgs returns the arguments passing the values of m, sorted, with
+// the flag name.
+func typeMapArgs(name string, m map[string]string) []string {
+	var pairs []string
+	for typ, v := range m {
+		pairs = append(pairs, typ+"="+v)
+	}
+	sort.Strings(pairs)
+	var args []string
+	for _, pair := range pairs {
+		args = append(args, name, pair)
+	}
+	return args
+}
+
+// bazelLabel returns the label of file, relative to the package in dir rel
+// of the workspace at root, if file is in the workspace.
+func bazelLabel(root, rel, file string) (string, bool) {
+	fileRel, err := filepath.Rel(root, file)
+	if err != nil || strings.HasPrefix(fileRel, "..") {
+		return "", false
+	}
+	fileRel = filepath.ToSlash(fileRel)
+	dir, name := path.Split(fileRel)
+	dir = strings.TrimSuffix(dir, "/")
+	if dir == rel {
+		return name, true
+	}
+	return "//" + dir + ":" + name, true
+}
+
+// writeBazelList writes the attribute name with the list of strings
+// values, sorted, as in a BUILD file.
+func writeBazelList(b *strings.Builder, name string, values []string) {
+	values = append([]string(nil), values...)
+	sort.Strings(values)
+	fmt.Fprintf(b, "    %s = [\n", name)
+	for _, v := range values {
+		fmt.Fprintf(b, "        %q,\n", v)
+	}
+	fmt.Fprintf(b, "    ],\n")
+}
+
+// shellQuote quotes s for a genrule's cmd, unless it is made of characters
+// that need no quoting, or of Make variables such as $(RULEDIR), which
+// Bazel expands before the shell runs.
+func shellQuote(s string) string {
+	return quoteWord(s, "$()")
+}
+
+// quoteWord quotes s for sh, unless it is made of characters that need no
+// quoting and those in literal.
+func quoteWord(s string, literal string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./,=:@"+literal) == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
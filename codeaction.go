@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/phildrip/toe/server"
+)
+
+// runCodeAction implements the code-action command, which prints as JSON
+// the code generated for the interface enclosing a position in a file,
+// with a suggested output file name.
+func runCodeAction(args []string) {
+	fs := flag.NewFlagSet("code-action", flag.ExitOnError)
+	var style string
+	fs.StringVar(&style, "style", "stub", "kind of code to generate")
+	addBuildFlags(fs)
+	args = parseInterspersed(fs, args)
+
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: %s code-action [-style <style>] <file>:<line>:<column>\n", os.Args[0])
+		os.Exit(1)
+	}
+	file, line, column, err := parsePosition(args[0])
+	if err != nil {
+		fatal("parsing position", err)
+	}
+
+	actionArgs := server.CodeActionArgs{File: file, Line: line, Column: column}
+	actionArgs.Options.Style = style
+	reply, err := server.New().CodeAction(actionArgs)
+	if err != nil {
+		fatal("generating stub", err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(reply); err != nil {
+		fatal("writing stub", err)
+	}
+}
+
+// parsePosition parses a position of the form file:line:column.
+func parsePosition(pos string) (file string, line int, column int, err error) {
+	parts := strings.Split(pos, ":")
+	if len(parts) < 3 {
+		return "", 0, 0, fmt.Errorf("%q is not of the form file:line:column", pos)
+	}
+	n := len(parts)
+	line, err = strconv.Atoi(parts[n-2])
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("%q is not of the form file:line:column", pos)
+	}
+	column, err = strconv.Atoi(parts[n-1])
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("%q is not of the form file:line:column", pos)
+	}
+	return strings.Join(parts[:n-2], ":"), line, column, nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// config holds the settings read from the JSON file given with -config.
+type config struct {
+	// ArgNaming is the argument naming scheme: "param", "arg" or "camel".
+	ArgNaming string `json:"argNaming"`
+	// Imports are added to the imports of the generated code, as
+	// "path" or "name=path".
+	Imports []string `json:"imports"`
+	// Defaults maps types to the expressions stub methods return for
+	// results of the type when unconfigured, such as "uuid.Nil" for
+	// "uuid.UUID".
+	Defaults map[string]string `json:"defaults"`
+	// ReplaceTypes maps types to the types that replace them in the
+	// generated code, both qualified by their package's import path.
+	ReplaceTypes map[string]string `json:"replaceTypes"`
+	// Assertions is the library the generated assertion helpers and tests
+	// fail tests with: "std", "testify", "cmp" or "quicktest".
+	Assertions string `json:"assertions"`
+	// Stubs are the interfaces to generate code for when no interface is
+	// given on the command line.
+	Stubs []stubConfig `json:"stubs"`
+	// Manifest is the file, relative to the config file, recording the
+	// files generated for Stubs; it defaults to toe.manifest.json.
+	Manifest string `json:"manifest"`
+}
+
+// loadConfig reads the config file at path.
+func loadConfig(path string) (*config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config: %v", err)
+	}
+	var cfg config
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return nil, fmt.Errorf("error parsing config %s: %v", path, err)
+	}
+	return &cfg, nil
+}
+
+// stringList is a flag.Value collecting the values of a flag given several
+// times.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// typeMap is a flag.Value collecting the values given for types as
+// type=value with a flag given several times.
+type typeMap map[string]string
+
+func (l typeMap) String() string {
+	var pairs []string
+	for typ, value := range l {
+		pairs = append(pairs, typ+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (l typeMap) Set(value string) error {
+	typ, v, ok := strings.Cut(value, "=")
+	if !ok || typ == "" || v == "" {
+		return fmt.Errorf("%q is not of the form type=value", value)
+	}
+	l[typ] = v
+	return nil
+}
+
+// mergeTypeMaps returns the values of base, overridden by those of
+// overrides, or nil if there are none.
+func mergeTypeMaps(base, overrides map[string]string) map[string]string {
+	if len(base) == 0 && len(overrides) == 0 {
+		return nil
+	}
+	merged := make(map[string]string)
+	for typ, v := range base {
+		merged[typ] = v
+	}
+	for typ, v := range overrides {
+		merged[typ] = v
+	}
+	return merged
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/phildrip/toe/model"
+)
+
+// runDeps implements the deps command, which prints the source files and
+// modules that generating code for an interface depends on, for build
+// systems declaring the inputs of generation.
+func runDeps(args []string) {
+	fs := flag.NewFlagSet("deps", flag.ExitOnError)
+	var asJSON bool
+	fs.BoolVar(&asJSON, "json", false, "print the dependencies as JSON")
+	addBuildFlags(fs)
+	args = parseInterspersed(fs, args)
+
+	if len(args) != 2 {
+		fmt.Fprintf(os.Stderr, "Usage: %s deps [-json] <input_directory> <interface>\n", os.Args[0])
+		os.Exit(1)
+	}
+
+	pkg, err := model.Load(args[0])
+	if err != nil {
+		fatal("finding interface", err)
+	}
+	if _, err := pkg.Lookup(args[1]); err != nil {
+		fatal("finding interface", err)
+	}
+	deps, err := model.LoadDependencies(args[0])
+	if err != nil {
+		fatal("listing dependencies", err)
+	}
+	for i, file := range deps.Files {
+		deps.Files[i] = relativePath(file)
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(deps); err != nil {
+			fatal("writing dependencies", err)
+		}
+		return
+	}
+
+	for _, file := range deps.Files {
+		fmt.Printf("file %s\n", file)
+	}
+	for _, m := range deps.Modules {
+		fmt.Printf("module %s\n", m)
+	}
+}
+
+// relativePath returns path relative to the working directory, if it is
+// within it.
+func relativePath(path string) string {
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return path
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/phildrip/toe/model"
+)
+
+// runDescribe implements the describe command, which prints the method
+// set of an interface.
+func runDescribe(args []string) {
+	fs := flag.NewFlagSet("describe", flag.ExitOnError)
+	var asJSON bool
+	fs.BoolVar(&asJSON, "json", false, "print the interface as JSON")
+	addBuildFlags(fs)
+	args = parseInterspersed(fs, args)
+
+	if len(args) != 2 {
+		fmt.Fprintf(os.Stderr, "Usage: %s describe [-json] <input_directory> <interface>\n", os.Args[0])
+		os.Exit(1)
+	}
+
+	pkg, err := model.Load(args[0])
+	if err != nil {
+		fatal("finding interface", err)
+	}
+	iface, err := pkg.Lookup(args[1])
+	if err != nil {
+		fatal("finding interface", err)
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(iface); err != nil {
+			fatal("writing interface", err)
+		}
+		return
+	}
+
+	fmt.Printf("%s.%s (%s)\n", iface.Package, iface.Name, iface.Pos)
+	for _, m := range iface.Methods {
+		fmt.Printf("\t%s(%s)%s\n", m.Name, describeParams(m.Params, m.Variadic), describeResults(m.Results))
+	}
+}
+
+// describeParams formats a parameter list as it would be written in Go.
+func describeParams(params []*model.Param, variadic bool) string {
+	var list []string
+	for i, p := range params {
+		typ := p.TypeString
+		if variadic && i == len(params)-1 {
+			typ = "..." + strings.TrimPrefix(typ, "[]")
+		}
+		list = append(list, strings.TrimSpace(p.Name+" "+typ))
+	}
+	return strings.Join(list, ", ")
+}
+
+// describeResults formats a result list as it would be written in Go.
+func describeResults(results []*model.Param) string {
+	switch {
+	case len(results) == 0:
+		return ""
+	case len(results) == 1 && results[0].Name == "":
+		return " " + results[0].TypeString
+	}
+	return " (" + describeParams(results, false) + ")"
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/phildrip/toe/model"
+)
+
+// ANSI escape sequences for the colors of diagnostics.
+const (
+	bold  = "\x1b[1m"
+	red   = "\x1b[31m"
+	reset = "\x1b[0m"
+)
+
+// fatal reports err, which happened while doing what, on stderr and exits.
+// Errors about a declaration are prefixed with its position, as go vet
+// reports diagnostics. When stderr is a terminal and NO_COLOR isn't set,
+// the position is bold and "Error" red.
+func fatal(what string, err error) {
+	colored := useColor()
+	paint := func(color string, s string) string {
+		if !colored {
+			return s
+		}
+		return color + s + reset
+	}
+
+	var posErr *model.Error
+	if errors.As(err, &posErr) {
+		fmt.Fprintf(os.Stderr, "%s: %s %s: %s\n",
+			paint(bold, model.RelativePosition(posErr.Pos)), paint(red, "Error"), what, posErr.Msg)
+	} else {
+		fmt.Fprintf(os.Stderr, "%s %s: %v\n", paint(red, "Error"), what, err)
+	}
+	os.Exit(1)
+}
+
+// useColor reports whether diagnostics should be colored: when stderr is a
+// terminal, unless the NO_COLOR environment variable is set.
+func useColor() bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := os.Stderr.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
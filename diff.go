@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+
+	"github.com/phildrip/toe/generator"
+	"github.com/phildrip/toe/model"
+)
+
+// runDiff implements the diff command, which reports how the methods of a
+// generated file differ from those of the interface it was generated from,
+// as it is now. It exits with status 1 if they differ.
+func runDiff(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	addBuildFlags(fs)
+	args = parseInterspersed(fs, args)
+
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: %s diff <generated_file>\n", os.Args[0])
+		os.Exit(1)
+	}
+	file := args[0]
+
+	existing, err := os.ReadFile(file)
+	if err != nil {
+		fatal("reading generated file", err)
+	}
+	f, err := parser.ParseFile(token.NewFileSet(), file, existing, parser.PackageClauseOnly|parser.ParseComments)
+	if err != nil {
+		fatal("reading generated file", err)
+	}
+	h, ok := generator.ParseHeader(f)
+	if !ok {
+		fatal("reading generated file", fmt.Errorf("%s was not generated by toe", file))
+	}
+	pkg, err := model.LoadImport(filepath.Dir(file), h.Path)
+	if err != nil {
+		fatal("finding interface", err)
+	}
+	diffs, err := generator.Diff(pkg, existing)
+	if err != nil {
+		fatal("comparing methods", err)
+	}
+
+	if len(diffs) == 0 {
+		fmt.Printf("%s is up to date with %s.%s\n", file, h.Path, h.Name)
+		return
+	}
+	fmt.Printf("%s differs from %s.%s:\n", file, h.Path, h.Name)
+	for _, d := range diffs {
+		switch {
+		case d.Old == "":
+			fmt.Printf("+ %s\n", d.New)
+		case d.New == "":
+			fmt.Printf("- %s\n", d.Old)
+		default:
+			fmt.Printf("~ %s\n    was %s\n", d.New, d.Old)
+		}
+	}
+	os.Exit(1)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/phildrip/toe/generator"
+)
+
+// runExtract implements the extract command, which writes an interface
+// covering the exported methods of a concrete type.
+func runExtract(args []string) {
+	fs := flag.NewFlagSet("extract", flag.ExitOnError)
+	var outputFile string
+	var interfaceName string
+	var postCmd string
+	fs.StringVar(&outputFile, "o", "", "output file name")
+	fs.StringVar(&interfaceName, "name", "", "name of the interface (default <type>Interface)")
+	fs.StringVar(&postCmd, "post-cmd", "",
+		"command run with sh after the output file is written; {{.Output}} expands to its name")
+	addBuildFlags(fs)
+	args = parseInterspersed(fs, args)
+
+	if len(args) != 2 {
+		fmt.Fprintf(os.Stderr,
+			"Usage: %s extract [-name <interface>] -o <output.go> <input_directory> <type>\n",
+			os.Args[0])
+		os.Exit(1)
+	}
+
+	inputDir := args[0]
+	typeName := args[1]
+	model, err := generator.Load(inputDir)
+	if err != nil {
+		fatal("extracting interface", err)
+	}
+	files, err := generator.Extract(model, typeName, generator.Options{
+		Interface: interfaceName,
+		Output:    outputFile,
+	})
+	if err != nil {
+		fatal("extracting interface", err)
+	}
+	writeFiles(files, postCmd)
+}
+
+// parseInterspersed parses args with fs, allowing flags to follow the
+// positional arguments, and returns the positional arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		_ = fs.Parse(args)
+		if fs.NArg() == 0 {
+			return positional
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
@@ -0,0 +1,273 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/phildrip/toe/model"
+)
+
+// aggregateData is the data the aggregate template is executed with.
+type aggregateData struct {
+	PackageName string
+	Imports     []importData
+	RuntimePath string
+	// Name is the aggregate's type name, Stub<name>.
+	Name     string
+	Receiver string
+	// TypeParamsDecl and TypeArgs declare and list the type parameters of
+	// the generic interfaces, which the aggregate has too; see
+	// templateData.
+	TypeParamsDecl string
+	TypeArgs       string
+	Stubs          []aggregateStub
+	// Forwarded are the methods the aggregate forwards to one of its stubs
+	// explicitly, as they would be ambiguous if promoted.
+	Forwarded []forwardedMethod
+	// Verify and Scope are the names of the aggregate's helpers verifying
+	// and scoping every stub it embeds.
+	Verify string
+	Scope  string
+	// ToolVersion and ToolOptions are recorded in the header; see
+	// templateData.
+	ToolVersion string
+	ToolOptions string
+}
+
+// aggregateStub is a stub embedded in an aggregate.
+type aggregateStub struct {
+	// StubName is the stub's type name, which names the embedded field, and
+	// TypeArgs its type arguments, if it is generic.
+	StubName      string
+	TypeArgs      string
+	InterfaceType string
+	// InterfacePath and InterfaceHash are recorded in the aggregate's
+	// header, for each of its interfaces.
+	InterfacePath string
+	InterfaceHash string
+	// Verify and Scope are the names of the stub's helpers.
+	Verify string
+	Scope  string
+}
+
+// forwardedMethod is a method of an aggregate calling the method of the
+// same name of one of its stubs.
+type forwardedMethod struct {
+	Stub    string
+	Name    string
+	Params  []string
+	Args    string
+	Results []string
+}
+
+// GenerateAggregate generates Stub<name>, embedding the stubs of the named
+// interfaces in m into the package opts.PackageName, or m's package if it
+// is empty. The stubs' methods are promoted from the embedded stubs, but
+// those they would have in common, such as those of a method the
+// interfaces share, are forwarded to the stub of the first interface
+// declaring them. The stubs of generic interfaces are instantiated with the
+// interfaces' type parameters, which the aggregate has too: type parameters
+// of the same name are shared, and must have the same constraint. Only
+// opts.Output, opts.PackageName,
+// opts.DisableFormatting and opts.EOL are otherwise used.
+func GenerateAggregate(m *Model, name string, interfaceNames []string, opts Options) ([]File, error) {
+	data := &aggregateData{
+		PackageName: opts.PackageName,
+		RuntimePath: runtimePath,
+		Name:        "Stub" + name,
+		ToolVersion: Version,
+	}
+	if data.PackageName == "" {
+		data.PackageName = m.Name
+	}
+	var err error
+	if data.ToolOptions, err = headerOptions(opts); err != nil {
+		return nil, err
+	}
+
+	var ifaces []*model.Interface
+	var stubs []*templateData
+	owners := make(map[string][]int)  // member name to the stubs that have it
+	methods := make(map[string][]int) // method name to the interfaces that have it
+	declared := make(map[string]bool) // method names of every interface
+	for i, interfaceName := range interfaceNames {
+		iface, err := m.Lookup(interfaceName)
+		if err != nil {
+			return nil, err
+		}
+		if iface.Func {
+			return nil, model.Errorf(iface.Pos, "%s is a func type, whose stub can't be aggregated", iface.Name)
+		}
+		stub, err := newTemplateData(iface, Options{Interface: interfaceName, Style: "stub", PackageName: data.PackageName})
+		if err != nil {
+			return nil, err
+		}
+		members, err := memberNames(iface, stub)
+		if err != nil {
+			return nil, err
+		}
+		for member := range members {
+			owners[member] = append(owners[member], i)
+		}
+		for _, method := range stub.Methods {
+			methods[method.Name] = append(methods[method.Name], i)
+			declared[method.Name] = true
+		}
+		ifaces = append(ifaces, iface)
+		stubs = append(stubs, stub)
+	}
+
+	var typeArgs, typeParamDecls []string
+	constraints := make(map[string]string) // type parameter to constraint
+	for i, stub := range stubs {
+		for _, tp := range stub.TypeParams {
+			constraint, ok := constraints[tp.Name]
+			if !ok {
+				constraints[tp.Name] = tp.Constraint
+				typeArgs = append(typeArgs, tp.Name)
+				typeParamDecls = append(typeParamDecls, tp.Name+" "+tp.Constraint)
+			} else if constraint != tp.Constraint {
+				return nil, model.Errorf(ifaces[i].Pos, "the type parameter %s of %s is constrained by %s, but by %s in another interface",
+					tp.Name, ifaces[i].Name, tp.Constraint, constraint)
+			}
+		}
+	}
+	if len(typeArgs) > 0 {
+		data.TypeArgs = "[" + strings.Join(typeArgs, ", ") + "]"
+		data.TypeParamsDecl = "[" + strings.Join(typeParamDecls, ", ") + "]"
+	}
+
+	imps := newImportSet(nil)
+	for _, stub := range stubs {
+		for _, imp := range stub.Imports {
+			if err := imps.add(imp); err != nil {
+				return nil, fmt.Errorf("cannot aggregate the stubs of %s: %v", strings.Join(interfaceNames, ", "), err)
+			}
+		}
+		data.Stubs = append(data.Stubs, aggregateStub{
+			StubName:      stub.StubName,
+			TypeArgs:      stub.TypeArgs,
+			InterfaceType: stub.InterfaceType,
+			InterfacePath: stub.InterfacePath,
+			InterfaceHash: stub.InterfaceHash,
+			Verify:        stub.Helpers["Verify"],
+			Scope:         stub.Helpers["Scope"],
+		})
+	}
+	data.Imports = imps.list()
+
+	// A method the stubs have in common would be ambiguous, so the aggregate
+	// wouldn't implement the interfaces declaring it, or it would be left
+	// out. The aggregate forwards those of the interfaces' methods to the
+	// stub of the first interface declaring them, and the configurators of
+	// the methods they share to the stub of the first.
+	names := make(map[string]bool)
+	forwarded := make(map[string]bool)
+	forward := func(stub int, method methodData, name string, params []string, args string, results []string) {
+		data.Forwarded = append(data.Forwarded, forwardedMethod{
+			Stub:    stubs[stub].StubName,
+			Name:    name,
+			Params:  params,
+			Args:    args,
+			Results: results,
+		})
+		forwarded[name] = true
+		for _, p := range method.ParamNames {
+			names[p] = true
+		}
+	}
+	for i, stub := range stubs {
+		for _, method := range stub.Methods {
+			if len(owners[method.Name]) < 2 || forwarded[method.Name] {
+				continue
+			}
+			for _, j := range methods[method.Name] {
+				other := methodByName(stubs[j], method.Name)
+				if strings.Join(other.ParamTypes, ", ") != strings.Join(method.ParamTypes, ", ") ||
+					strings.Join(other.ResultTypes, ", ") != strings.Join(method.ResultTypes, ", ") ||
+					other.Variadic != method.Variadic {
+					return nil, model.Errorf(ifaces[j].Pos, "interfaces %s and %s both have a method %s, with different signatures",
+						ifaces[i].Name, ifaces[j].Name, method.Name)
+				}
+			}
+			forward(i, method, method.Name, method.Params, callArgs(method), method.Results)
+		}
+	}
+	for i, stub := range stubs {
+		for _, method := range stub.Methods {
+			if len(methods[method.Name]) < 2 || methods[method.Name][0] != i {
+				continue
+			}
+			then := "*" + stub.StubName + method.Name + "Then" + stub.TypeArgs
+			if name := "On" + method.Name; !forwarded[name] {
+				forward(i, method, name, []string{"args ...any"}, "args...", []string{then})
+			}
+			if name := "Expect" + method.Name; !forwarded[name] {
+				forward(i, method, name, []string{"args ...any"}, "args...", []string{"*runtime.Verification"})
+			}
+			if name := "Record" + method.Name; !forwarded[name] {
+				forward(i, method, name, method.Params, callArgs(method), nil)
+			}
+		}
+	}
+
+	data.Verify, data.Scope = "Verify", "Scope"
+	if declared["Verify"] {
+		data.Verify = "StubVerify"
+	}
+	if declared["Scope"] {
+		data.Scope = "StubScope"
+	}
+	for _, helper := range []string{data.Verify, data.Scope} {
+		if declared[helper] || forwarded[helper] {
+			return nil, fmt.Errorf("cannot generate %s: its helper %s would have the name of a method", data.Name, helper)
+		}
+	}
+	data.Receiver = receiverName(data.Name, names)
+
+	code, err := generateAggregateCode(data, opts.DisableFormatting)
+	if err != nil {
+		return nil, err
+	}
+	files := []File{{Name: opts.Output, Content: []byte(code)}}
+	setEOL(files, opts.EOL)
+	return files, nil
+}
+
+// methodByName returns the method of data named name.
+func methodByName(data *templateData, name string) methodData {
+	for _, m := range data.Methods {
+		if m.Name == name {
+			return m
+		}
+	}
+	return methodData{}
+}
+
+// callArgs returns the arguments of a call passing on the parameters of
+// method.
+func callArgs(method methodData) string {
+	args := strings.Join(method.ParamNames, ", ")
+	if method.Variadic {
+		args += "..."
+	}
+	return args
+}
+
+func generateAggregateCode(data *aggregateData, disableFormatting bool) (string, error) {
+	tmpl := template.Must(
+		template.New("aggregate").
+			Funcs(template.FuncMap{"join": strings.Join}).
+			Parse(aggregateTemplate))
+
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("error generating stub: %v", err)
+	}
+
+	if disableFormatting {
+		return dropUnusedImports(buf.String()), nil
+	}
+	return formatCode(buf.String())
+}
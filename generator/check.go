@@ -0,0 +1,113 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/phildrip/toe/model"
+)
+
+// checkSource is the synthetic package CheckTemplate generates code for,
+// with the shapes of methods templates meet.
+const checkSource = `package check
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Item is a type declared alongside the interfaces.
+type Item struct {
+	ID   int
+	Name string
+}
+
+// Closer is embedded in Synthetic.
+type Closer interface {
+	Close() error
+}
+
+// Synthetic has methods of many shapes, and embeds interfaces.
+type Synthetic interface {
+	Closer
+	io.Reader
+	Get(ctx context.Context, id int) (*Item, error)
+	Put(ctx context.Context, items ...Item) error
+	List(ctx context.Context, filter func(Item) bool, limit int) (items []Item, next string, err error)
+	Watch(ctx context.Context) (<-chan Item, error)
+	Send(ch chan<- Item, timeout time.Duration)
+	Lookup(ids map[string][]*Item) (map[string]Item, bool)
+	Ping()
+}
+
+// Generic is a generic interface.
+type Generic[K comparable, V any] interface {
+	Load(ctx context.Context, key K) (V, error)
+	Store(key K, values ...V) error
+	Keys() []K
+}
+`
+
+// checkInterfaces are the interfaces of checkSource CheckTemplate
+// generates code for.
+var checkInterfaces = []string{"Synthetic", "Generic"}
+
+// CheckTemplate generates code with the template of opts, for
+// opts.TemplateFile, and type-checks it, for template authors. The code
+// is generated for the interfaces of a synthetic package, covering generic
+// and embedded interfaces and variadic, channel, func and map parameters,
+// into that package, and the packages it imports are resolved from dir.
+// Test files in external test packages aren't type-checked, as they can't
+// import the synthetic package. It returns an error naming each interface
+// whose code fails.
+func CheckTemplate(opts Options, dir string) error {
+	tmp, err := os.MkdirTemp("", "toe-check")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	file := filepath.Join(tmp, "check.go")
+	if err := os.WriteFile(file, []byte(checkSource), 0644); err != nil {
+		return err
+	}
+	m, err := model.LoadFile(file)
+	if err != nil {
+		return err
+	}
+
+	var failures []string
+	for _, name := range checkInterfaces {
+		opts.Interface = name
+		opts.PackageName = m.Name
+		opts.Output = "check_" + strings.ToLower(name) + ".go"
+		if err := checkGenerated(m, opts, dir); err != nil {
+			// Positions in the synthetic package are relative to it.
+			msg := strings.ReplaceAll(err.Error(), tmp+string(filepath.Separator), "")
+			failures = append(failures, fmt.Sprintf("%s:\n%s", name, msg))
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("the template fails for %s", strings.Join(failures, "\n"))
+	}
+	return nil
+}
+
+// checkGenerated generates the code for an interface of m with opts and
+// type-checks it along with m, resolving imports from dir.
+func checkGenerated(m *Model, opts Options, dir string) error {
+	files, err := Generate(m, opts)
+	if err != nil {
+		return err
+	}
+	sources := map[string][]byte{"check.go": []byte(checkSource)}
+	for _, f := range files {
+		if strings.HasSuffix(f.Name, "_test.go") && strings.Contains(string(f.Content), "package "+m.Name+"_test") {
+			continue
+		}
+		sources[f.Name] = f.Content
+	}
+	return model.TypeCheck(dir, sources)
+}
@@ -0,0 +1,173 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// MethodDiff is a difference between the methods a generated type
+// implements and those it would implement if regenerated.
+type MethodDiff struct {
+	Name string
+	// Old and New are the method's signatures, without parameter names,
+	// in the existing file and as regenerated: Old is empty for an added
+	// method, and New for a removed one.
+	Old string
+	New string
+}
+
+// Diff compares the methods of the type declared in existing, a file
+// generated from an interface in m, with those it would have if the file
+// were regenerated from the interface as it is now, returning the added,
+// removed and changed methods by name. Methods generated alongside a
+// method of the interface and named after it, such as a stub's On and
+// Expect methods, are left out for removed methods.
+func Diff(m *Model, existing []byte) ([]MethodDiff, error) {
+	fset := token.NewFileSet()
+	old, err := parser.ParseFile(fset, "", existing, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	h, ok := ParseHeader(old)
+	if !ok {
+		return nil, fmt.Errorf("the file was not generated by toe")
+	}
+	if h.Style == "aggregate" {
+		return nil, fmt.Errorf("the file is an aggregate, whose methods are those of the stubs it embeds")
+	}
+	opts := h.Options
+	opts.Interface, opts.Style, opts.PackageName = h.Name, h.Style, old.Name.Name
+	// Only the file declaring the type is compared.
+	opts.WithExample, opts.WithRaceTest, opts.WithFuzz = false, false, false
+	_, typeName, err := OutputFiles(m, opts)
+	if err != nil {
+		return nil, err
+	}
+	files, err := Generate(m, opts)
+	if err != nil {
+		return nil, err
+	}
+	regenerated, err := parser.ParseFile(fset, "", files[0].Content, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	oldMethods := methodSignatures(fset, old, typeName)
+	newMethods := methodSignatures(fset, regenerated, typeName)
+	var diffs []MethodDiff
+	for name, sig := range newMethods {
+		if oldSig, ok := oldMethods[name]; !ok || oldSig != sig {
+			diffs = append(diffs, MethodDiff{Name: name, Old: oldSig, New: sig})
+		}
+	}
+	var removed []string
+	for name := range oldMethods {
+		if _, ok := newMethods[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	for _, name := range removed {
+		if !generatedFor(name, removed) {
+			diffs = append(diffs, MethodDiff{Name: name, Old: oldMethods[name]})
+		}
+	}
+
+	// Added methods' own helpers are left out in the same way.
+	var added []string
+	for _, d := range diffs {
+		if d.Old == "" {
+			added = append(added, d.Name)
+		}
+	}
+	var result []MethodDiff
+	for _, d := range diffs {
+		if d.Old == "" && generatedFor(d.Name, added) {
+			continue
+		}
+		result = append(result, d)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// generatedFor reports whether the method name is named after another of
+// names, as the methods generated alongside each stubbed method are.
+func generatedFor(name string, names []string) bool {
+	for _, other := range names {
+		if other == name {
+			continue
+		}
+		for _, generated := range []string{"On" + other, "Expect" + other, "Record" + other, "Assert" + other + "CalledWith"} {
+			if name == generated {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// methodSignatures returns the signatures, without parameter names, of the
+// methods of typeName declared in file, by name.
+func methodSignatures(fset *token.FileSet, file *ast.File, typeName string) map[string]string {
+	sigs := make(map[string]string)
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv == nil || len(fn.Recv.List) != 1 || receiverTypeName(fn.Recv.List[0].Type) != typeName {
+			continue
+		}
+		params := strings.Join(fieldTypes(fset, fn.Type.Params), ", ")
+		sigs[fn.Name.Name] = fn.Name.Name + "(" + params + ")" + resultTypes(fset, fn.Type.Results)
+	}
+	return sigs
+}
+
+// receiverTypeName returns the name of the type of a receiver such as *T or
+// T[K, V].
+func receiverTypeName(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.StarExpr:
+		return receiverTypeName(expr.X)
+	case *ast.IndexExpr:
+		return receiverTypeName(expr.X)
+	case *ast.IndexListExpr:
+		return receiverTypeName(expr.X)
+	case *ast.Ident:
+		return expr.Name
+	}
+	return ""
+}
+
+// fieldTypes returns the types of fields, one for each name.
+func fieldTypes(fset *token.FileSet, fields *ast.FieldList) []string {
+	if fields == nil {
+		return nil
+	}
+	var list []string
+	for _, field := range fields.List {
+		var typ bytes.Buffer
+		printer.Fprint(&typ, fset, field.Type)
+		for i := 0; i < max(len(field.Names), 1); i++ {
+			list = append(list, typ.String())
+		}
+	}
+	return list
+}
+
+// resultTypes formats a result list as it would be written in a
+// signature without result names.
+func resultTypes(fset *token.FileSet, results *ast.FieldList) string {
+	types := fieldTypes(fset, results)
+	switch len(types) {
+	case 0:
+		return ""
+	case 1:
+		return " " + types[0]
+	}
+	return " (" + strings.Join(types, ", ") + ")"
+}
@@ -0,0 +1,75 @@
+package generator
+
+import (
+	"go/types"
+)
+
+// exampleValue returns an expression of type typ for use in the example
+// test, and how fmt.Println prints its value if that is predictable.
+func exampleValue(typ types.Type, qualifier types.Qualifier) (expr string, output string, printable bool) {
+	// fmt calls the String and Error methods of values that have them,
+	// other than nil errors.
+	printable = types.Identical(typ, types.Universe.Lookup("error").Type()) || !hasFormatMethod(typ)
+
+	if named, ok := typ.(*types.Named); ok && named.Obj().Pkg() != nil &&
+		named.Obj().Pkg().Path() == "context" && named.Obj().Name() == "Context" {
+		return qualifier(named.Obj().Pkg()) + ".Background()", "context.Background", true
+	}
+
+	switch u := typ.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsBoolean != 0:
+			return "true", "true", printable
+		case u.Info()&types.IsInteger != 0:
+			return "42", "42", printable
+		case u.Info()&types.IsFloat != 0:
+			return "1.5", "1.5", printable
+		case u.Info()&types.IsString != 0:
+			return `"example"`, "example", printable
+		}
+	case *types.Pointer, *types.Signature, *types.Chan, *types.Interface:
+		if _, ok := typ.(*types.TypeParam); !ok {
+			return "nil", "<nil>", printable
+		}
+	case *types.Slice:
+		return "nil", "[]", printable
+	case *types.Map:
+		return "nil", "map[]", printable
+	case *types.Struct, *types.Array:
+		return types.TypeString(typ, qualifier) + "{}", "", false
+	}
+	return "*new(" + types.TypeString(typ, qualifier) + ")", "", false
+}
+
+// hasFormatMethod reports whether values of type typ have a String or
+// Error method.
+func hasFormatMethod(typ types.Type) bool {
+	for _, name := range []string{"String", "Error"} {
+		obj, _, _ := types.LookupFieldOrMethod(typ, true, nil, name)
+		if _, ok := obj.(*types.Func); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// fuzzType returns the type of the fuzz argument for a parameter of type
+// typ, one of the types the testing package can fuzz, or "" if typ can't be
+// fuzzed. It is typ's underlying type, which arguments are converted from.
+func fuzzType(typ types.Type) string {
+	switch u := typ.Underlying().(type) {
+	case *types.Basic:
+		switch u.Kind() {
+		case types.Bool, types.String, types.Float32, types.Float64,
+			types.Int, types.Int8, types.Int16, types.Int32, types.Int64,
+			types.Uint, types.Uint8, types.Uint16, types.Uint32, types.Uint64:
+			return u.Name()
+		}
+	case *types.Slice:
+		if elem, ok := u.Elem().(*types.Basic); ok && elem.Kind() == types.Uint8 {
+			return "[]byte"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,69 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/phildrip/toe/model"
+)
+
+// Explain describes the interface in m that Generate would generate code
+// for with opts, as it is resolved: each method of its method set, with
+// methods promoted from embedded interfaces expanded, at the position of
+// its declaration, with its signature as written in the generated code,
+// followed by the imports the generated code qualifies types with.
+func Explain(m *Model, opts Options) (string, error) {
+	if err := setDefaults(&opts); err != nil {
+		return "", err
+	}
+	iface, err := m.Lookup(opts.Interface)
+	if err != nil {
+		return "", err
+	}
+	data, err := newTemplateData(iface, opts)
+	if err != nil {
+		return "", err
+	}
+
+	methods := make(map[string]methodData)
+	unstubbed := make(map[string]bool)
+	for _, method := range data.Methods {
+		methods[method.Name] = method
+	}
+	for _, method := range data.Unstubbed {
+		methods[method.Name] = method
+		unstubbed[method.Name] = true
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s%s, %d methods\n", model.RelativePosition(iface.Pos), data.InterfacePath,
+		data.TypeParamsDecl, len(iface.Methods))
+	for _, m := range iface.Methods {
+		method := methods[m.Name]
+		fmt.Fprintf(&b, "%s: \t%s(%s)%s", model.RelativePosition(m.Pos), method.Name,
+			strings.Join(method.Params, ", "), resultList(method))
+		if unstubbed[m.Name] {
+			b.WriteString(" (not stubbed)")
+		}
+		b.WriteString("\n")
+	}
+	if len(data.Imports) > 0 {
+		b.WriteString("imports:\n")
+		for _, imp := range data.Imports {
+			fmt.Fprintf(&b, "\t%s\n", imp)
+		}
+	}
+	return b.String(), nil
+}
+
+// resultList formats the results of method as they follow its parameters
+// in its signature.
+func resultList(method methodData) string {
+	switch {
+	case len(method.Results) == 0:
+		return ""
+	case len(method.Results) == 1 && !method.ResultList[0].Named:
+		return " " + method.Results[0]
+	}
+	return " (" + strings.Join(method.Results, ", ") + ")"
+}
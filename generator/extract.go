@@ -0,0 +1,90 @@
+package generator
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+)
+
+// Extract generates a file declaring an interface named opts.Interface
+// with the exported methods of the type typeName in m, generic with the
+// type's type parameters if it has any. The interface is named
+// <typeName>Interface if opts.Interface is empty. Only opts.Output and
+// opts.DisableFormatting are otherwise used. The file has no generated-code
+// header: it is a starting point for code of the user's own.
+func Extract(m *Model, typeName string, opts Options) ([]File, error) {
+	interfaceName := opts.Interface
+	if interfaceName == "" {
+		interfaceName = typeName + "Interface"
+	}
+	code, err := extractInterface(m.Types, typeName, interfaceName)
+	if err != nil {
+		return nil, err
+	}
+	if !opts.DisableFormatting {
+		code, err = formatCode(code)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return []File{{Name: opts.Output, Content: []byte(code)}}, nil
+}
+
+// extractInterface returns the source of a file declaring an interface
+// named interfaceName with the exported methods of the type typeName in
+// pkg.
+func extractInterface(pkg *types.Package, typeName string, interfaceName string) (string, error) {
+	obj, ok := pkg.Scope().Lookup(typeName).(*types.TypeName)
+	if !ok {
+		return "", fmt.Errorf("type %s not found", typeName)
+	}
+	if types.IsInterface(obj.Type()) {
+		return "", fmt.Errorf("%s is already an interface", typeName)
+	}
+
+	imps := newImportSet(pkg)
+	var methods []string
+	mset := types.NewMethodSet(types.NewPointer(obj.Type()))
+	for i := 0; i < mset.Len(); i++ {
+		fn := mset.At(i).Obj()
+		if !fn.Exported() {
+			continue
+		}
+		sig := fn.Type().(*types.Signature)
+		methods = append(methods, fn.Name()+
+			strings.TrimPrefix(types.TypeString(sig, imps.qualifier), "func"))
+	}
+	if len(methods) == 0 {
+		return "", fmt.Errorf("%s has no exported methods", typeName)
+	}
+
+	// The methods of a generic type refer to its type parameters, which
+	// the interface declares in turn.
+	var tparams []string
+	if named, ok := obj.Type().(*types.Named); ok {
+		for i := 0; i < named.TypeParams().Len(); i++ {
+			tp := named.TypeParams().At(i)
+			tparams = append(tparams, tp.Obj().Name()+" "+types.TypeString(tp.Constraint(), imps.qualifier))
+		}
+	}
+	decl := interfaceName
+	if len(tparams) > 0 {
+		decl += "[" + strings.Join(tparams, ", ") + "]"
+	}
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "package %s\n\n", pkg.Name())
+	fmt.Fprintf(&buf, "import (\n")
+	for _, imp := range imps.specs() {
+		fmt.Fprintf(&buf, "\t%s\n", imp)
+	}
+	fmt.Fprintf(&buf, ")\n\n")
+	fmt.Fprintf(&buf, "// %s is the set of exported methods of %s.\n", interfaceName, typeName)
+	fmt.Fprintf(&buf, "type %s interface {\n", decl)
+	for _, m := range methods {
+		fmt.Fprintf(&buf, "\t%s\n", m)
+	}
+	fmt.Fprintf(&buf, "}\n")
+
+	return buf.String(), nil
+}
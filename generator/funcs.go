@@ -0,0 +1,253 @@
+package generator
+
+import (
+	"fmt"
+	"go/types"
+	"plugin"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// builtinFuncs returns the functions available to every template.
+func builtinFuncs() template.FuncMap {
+	return template.FuncMap{
+		"join":      strings.Join,
+		"zip":       zip,
+		"joinl":     joinl,
+		"last":      last,
+		"export":    export,
+		"fieldType": fieldType,
+		"scope":     scope,
+
+		"lower":  strings.ToLower,
+		"upper":  strings.ToUpper,
+		"camel":  camel,
+		"pascal": pascal,
+		"snake":  snake,
+		"kebab":  kebab,
+		"plural": plural,
+
+		"isPointer": isPointer,
+		"isSlice":   isSlice,
+		"isMap":     isMap,
+		"isChan":    isChan,
+		"isFunc":    isFunc,
+		"isError":   isError,
+		"isContext": isContext,
+	}
+}
+
+// loadFuncs returns the template functions exported by the Go plugin at
+// path. The plugin exports them as a variable or function named Funcs:
+//
+//	var Funcs = template.FuncMap{"shout": strings.ToUpper}
+//
+// or
+//
+//	func Funcs() template.FuncMap
+func loadFuncs(path string) (template.FuncMap, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error loading template functions: %v", err)
+	}
+	sym, err := p.Lookup("Funcs")
+	if err != nil {
+		return nil, fmt.Errorf("error loading template functions: %v", err)
+	}
+	switch funcs := sym.(type) {
+	case *template.FuncMap:
+		return *funcs, nil
+	case *map[string]any:
+		return *funcs, nil
+	case func() template.FuncMap:
+		return funcs(), nil
+	default:
+		return nil, fmt.Errorf("error loading template functions: %s: Funcs is a %T, not a template.FuncMap",
+			path, sym)
+	}
+}
+
+func zip(a []string, b []string, fmtStr string) []string {
+	if len(a) != len(b) {
+		panic("unequal length")
+	}
+	var zipped []string
+	for i := range a {
+		zipped = append(zipped, fmt.Sprintf(fmtStr, a[i], b[i]))
+	}
+	return zipped
+}
+
+// joinl joins a list of strings with a separator, with arguments reversed
+// compared to strings.Join.
+func joinl(sep string, a []string) string {
+	return strings.Join(a, sep)
+}
+
+// last returns the last element of a.
+func last(a []string) string {
+	return a[len(a)-1]
+}
+
+// export returns name with its first letter upper-cased.
+func export(name string) string {
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// fieldType returns the type of a struct field holding a parameter of type
+// paramType, which is a slice for variadic parameters.
+func fieldType(paramType string) string {
+	if strings.HasPrefix(paramType, "...") {
+		return "[]" + paramType[3:]
+	}
+	return paramType
+}
+
+// words splits an identifier into words at underscores, hyphens and
+// changes of case, keeping initialisms together: "HTTPServer_id" is split
+// into "HTTP", "Server" and "id".
+func words(name string) []string {
+	var result []string
+	runes := []rune(name)
+	start := 0
+	for i := 0; i <= len(runes); i++ {
+		switch {
+		case i == len(runes) || runes[i] == '_' || runes[i] == '-':
+			if start < i {
+				result = append(result, string(runes[start:i]))
+			}
+			start = i + 1
+		case i > start && unicode.IsUpper(runes[i]) &&
+			(!unicode.IsUpper(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])):
+			result = append(result, string(runes[start:i]))
+			start = i
+		}
+	}
+	return result
+}
+
+// camel returns name in camelCase.
+func camel(name string) string {
+	p := pascal(name)
+	if p == "" {
+		return p
+	}
+	w := words(name)[0]
+	return strings.ToLower(w) + p[len(w):]
+}
+
+// pascal returns name in PascalCase.
+func pascal(name string) string {
+	var b strings.Builder
+	for _, w := range words(name) {
+		b.WriteString(export(w))
+	}
+	return b.String()
+}
+
+// snake returns name in snake_case.
+func snake(name string) string {
+	return strings.ToLower(strings.Join(words(name), "_"))
+}
+
+// kebab returns name in kebab-case.
+func kebab(name string) string {
+	return strings.ToLower(strings.Join(words(name), "-"))
+}
+
+// plural returns the English plural of the noun word.
+func plural(word string) string {
+	lower := strings.ToLower(word)
+	switch {
+	case word == "":
+		return word
+	case strings.HasSuffix(lower, "s"), strings.HasSuffix(lower, "x"), strings.HasSuffix(lower, "z"),
+		strings.HasSuffix(lower, "ch"), strings.HasSuffix(lower, "sh"):
+		return word + "es"
+	case len(lower) > 1 && lower[len(lower)-1] == 'y' && !strings.ContainsRune("aeiou", rune(lower[len(lower)-2])):
+		return word[:len(word)-1] + "ies"
+	}
+	return word + "s"
+}
+
+// typePredicates returns the type predicates for the templates executed
+// with data. The types of its methods' parameters and results are
+// classified by their underlying types, so that a named map type such as
+// http.Header is a map; other types are classified by how they are
+// written, as by the predicates of builtinFuncs.
+func typePredicates(data *templateData) template.FuncMap {
+	known := make(map[string]types.Type)
+	for _, methods := range [][]methodData{data.Methods, data.Unstubbed} {
+		for _, m := range methods {
+			for typ, t := range m.types {
+				known[typ] = t
+			}
+		}
+	}
+	is := func(written func(string) bool, underlying func(types.Type) bool) func(string) bool {
+		return func(typ string) bool {
+			if t, ok := known[typ]; ok {
+				return underlying(t)
+			}
+			return written(typ)
+		}
+	}
+	return template.FuncMap{
+		"isPointer": is(isPointer, func(t types.Type) bool {
+			_, ok := t.Underlying().(*types.Pointer)
+			return ok
+		}),
+		"isSlice": is(isSlice, func(t types.Type) bool {
+			_, ok := t.Underlying().(*types.Slice)
+			return ok
+		}),
+		"isMap": is(isMap, func(t types.Type) bool {
+			_, ok := t.Underlying().(*types.Map)
+			return ok
+		}),
+		"isChan": is(isChan, func(t types.Type) bool {
+			_, ok := t.Underlying().(*types.Chan)
+			return ok
+		}),
+		"isFunc": is(isFunc, func(t types.Type) bool {
+			_, ok := t.Underlying().(*types.Signature)
+			return ok
+		}),
+		"isError": is(isError, func(t types.Type) bool {
+			return types.Identical(t, types.Universe.Lookup("error").Type())
+		}),
+		"isContext": is(isContext, isContextType),
+	}
+}
+
+// The type predicates report the kind of a type as it is written in
+// ParamTypes and ResultTypes.
+
+func isPointer(typ string) bool {
+	return strings.HasPrefix(typ, "*")
+}
+
+func isSlice(typ string) bool {
+	return strings.HasPrefix(typ, "[]") || strings.HasPrefix(typ, "...")
+}
+
+func isMap(typ string) bool {
+	return strings.HasPrefix(typ, "map[")
+}
+
+func isChan(typ string) bool {
+	return strings.HasPrefix(typ, "chan ") || strings.HasPrefix(typ, "chan<-") || strings.HasPrefix(typ, "<-chan")
+}
+
+func isFunc(typ string) bool {
+	return strings.HasPrefix(typ, "func(")
+}
+
+func isError(typ string) bool {
+	return typ == "error"
+}
+
+func isContext(typ string) bool {
+	return typ == "context.Context"
+}
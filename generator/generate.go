@@ -0,0 +1,1358 @@
+package generator
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"unicode"
+
+	"golang.org/x/tools/imports"
+
+	"github.com/phildrip/toe/model"
+)
+
+// templateData is the data the templates are executed with.
+type templateData struct {
+	PackageName string
+	// Imports are the packages referred to by the method signatures.
+	Imports     []importData
+	RuntimePath string
+	Style       string
+	// InterfaceName is the interface's name, and InterfaceType the type
+	// expression referring to it from the generated code, qualified with
+	// its package and instantiated with TypeArgs.
+	InterfaceName string
+	InterfaceType string
+	// InterfacePath is the interface's import path and name, such as
+	// "github.com/phildrip/toe/ref.Thinger", and InterfaceHash the hash of its method set,
+	// recorded in the header so stale code can be detected.
+	InterfacePath string
+	InterfaceHash string
+	// Func reports whether the interface is a named func type, whose
+	// signature is the single method model.FuncMethod's.
+	Func bool
+	// PackagePath is the import path of the interface's package, and
+	// ModulePath the path of its module, or empty outside a module.
+	PackagePath string
+	ModulePath  string
+	// SourceFile is the file declaring the interface, relative to the root
+	// of its module, or its base name outside a module, with forward
+	// slashes, and SourceLine the line of the interface's name in it.
+	SourceFile string
+	SourceLine int
+	// ToolVersion is the version of toe, and ToolOptions the options the
+	// code is generated with, as JSON, also recorded in the header; see
+	// Header.
+	ToolVersion string
+	ToolOptions string
+	StubName    string
+	// Receiver is the receiver name of the generated methods, chosen not to
+	// collide with any parameter or result name.
+	Receiver string
+	// TypeParams are the interface's type parameters. TypeParamsDecl is
+	// their declaration, such as "[K comparable, V any]", and TypeArgs
+	// the list of their names, such as "[K, V]"; both are empty for
+	// non-generic interfaces.
+	TypeParams     []typeParamData
+	TypeParamsDecl string
+	TypeArgs       string
+	Methods        []methodData
+	// Unstubbed are the methods left out by Options.Methods and
+	// Options.ExcludeMethods, which the stub implements by panicking.
+	Unstubbed []methodData
+	// HasContext is true when a method takes a context.Context.
+	HasContext bool
+	// HasStreams is true when a method returns a stream.
+	HasStreams bool
+	// HasClock is true when a method is backed by a runtime.Clock.
+	HasClock bool
+	// HasFuzz is true when a method has a parameter that can be fuzzed.
+	HasFuzz bool
+	// Assertions is the library assertion helpers fail tests with; see
+	// Options.Assertions.
+	Assertions string
+	// HasRead and HasWrite are true when the interface has io.Reader's
+	// Read or io.Writer's Write method.
+	HasRead  bool
+	HasWrite bool
+	// ErrorUnconfigured is true when methods returning an error should
+	// return one when called without a configured result.
+	ErrorUnconfigured bool
+	// CallChannels is true when the stub sends each call's record on a
+	// channel for the method.
+	CallChannels bool
+	// EmbedInterface is true when the stub embeds the interface, so that it
+	// still implements it once methods are added.
+	EmbedInterface bool
+	// FuncAdapter is the single method of the interface when a
+	// <Interface>Func type adapting funcs to it is generated, and nil
+	// otherwise.
+	FuncAdapter *methodData
+	// SplitHelpers is true when the helpers partial is generated into a
+	// separate file, and should be left out of the main one.
+	SplitHelpers bool
+	// Helpers maps the name of each helper method of the style, such as
+	// the stub's Verify, to the name it is generated with: the name itself,
+	// or the name prefixed like StubName, such as StubVerify, when the
+	// interface has a method of that name.
+	Helpers map[string]string
+}
+
+// importData is an import of the generated code. It prints as an import
+// spec.
+type importData struct {
+	Name string
+	Path string
+}
+
+func (i importData) String() string {
+	if i.Name == assumedName(i.Path) {
+		return fmt.Sprintf("%q", i.Path)
+	}
+	return fmt.Sprintf("%s %q", i.Name, i.Path)
+}
+
+type typeParamData struct {
+	Name       string
+	Constraint string
+}
+
+type methodData struct {
+	Name string
+	// Doc is the method's doc comment in the interface, if any.
+	Doc string
+
+	// ParamList and ResultList describe each parameter and result.
+	ParamList  []paramData
+	ResultList []resultData
+
+	// The remaining fields flatten ParamList and ResultList into lists of
+	// strings.
+	Params      []string
+	ParamNames  []string
+	ParamTypes  []string
+	Variadic    bool
+	Results     []string
+	ResultTypes []string
+	ResultNames []string
+	// ResultVars are local variable names for the results, for templates
+	// that need to capture the results of a delegated call. They are
+	// distinct from the names of the parameters and named results.
+	ResultVars []string
+	// HasError is true when the last result is an error.
+	HasError bool
+	// HasDefaults is true when a result has a default; see
+	// resultData.Default.
+	HasDefaults bool
+	// OutParams is true when a parameter is a pointer, through which
+	// SetArg can store values.
+	OutParams bool
+	// ReferenceParams is true when a parameter other than a context holds
+	// pointers, maps, funcs, channels or interfaces, so that printing it
+	// doesn't capture its value.
+	ReferenceParams bool
+	// Fuzz is true when a parameter can be fuzzed; see paramData.FuzzType.
+	Fuzz bool
+	// Context is the name of the first context.Context parameter, if
+	// any.
+	Context string
+	// RoundTrip is true when the method is http.RoundTripper's RoundTrip.
+	RoundTrip bool
+	// Clock is the method of runtime.Clock backing the method when
+	// unconfigured, for the Now, Since, Until, After and Sleep methods of
+	// clock-like interfaces, and is empty otherwise.
+	Clock string
+	// Rows is true when the method returns a database/sql/driver.Rows and
+	// an error, as the Query methods of the driver interfaces do.
+	Rows bool
+	// IO is true when the method is io.Reader's Read or io.Writer's
+	// Write, which the stub backs with its content when unconfigured.
+	IO bool
+	// Stream describes the stream returned by the method, such as a gRPC
+	// client stream, or is nil if it doesn't return one.
+	Stream *streamData
+
+	// receiver is the receiver name of the generated type's methods, which
+	// Local avoids.
+	receiver string
+	// types are the types of the parameters and results, by their type
+	// strings in ParamTypes and ResultTypes; see typePredicates.
+	types map[string]types.Type
+}
+
+// Local returns the name of a local variable for the method's generated
+// body: name, or name followed by a number if a parameter, a result or the
+// receiver already has it.
+func (m methodData) Local(name string) string {
+	taken := map[string]bool{m.receiver: true}
+	for _, names := range [][]string{m.ParamNames, m.ResultNames, m.ResultVars} {
+		for _, n := range names {
+			taken[n] = true
+		}
+	}
+	local := name
+	for n := 2; taken[local]; n++ {
+		local = fmt.Sprintf("%s%d", name, n)
+	}
+	return local
+}
+
+// streamData describes a stream returned by a method: an interface with a
+// Send or Recv method, for which the stub style generates a companion stub.
+type streamData struct {
+	// Type is the stream's type, embedded in the companion stub so that it
+	// implements the stream's other methods.
+	Type string
+	// Send and Recv are the types of the messages sent and received, empty
+	// if the stream doesn't send or receive them, and RecvMethods the
+	// stream's methods returning the next message received: Recv,
+	// CloseAndRecv or both.
+	Send        string
+	Recv        string
+	RecvMethods []string
+	CloseSend   bool
+}
+
+type paramData struct {
+	// Name is the parameter's name, synthesized as argN for unnamed and
+	// blank parameters.
+	Name string
+	// Type is the parameter's type as written in the signature, with a
+	// "..." prefix for variadic parameters.
+	Type string
+	// FieldName and FieldType are the name and type of the field holding
+	// the parameter in the method's Params struct.
+	FieldName string
+	FieldType string
+	Variadic  bool
+	// Context is true when the parameter is a context.Context.
+	Context bool
+	// Example is an example argument for the parameter, for the example
+	// test, and ExampleOutput how fmt prints the field holding it, if
+	// Printable is true. For variadic parameters Example is a single
+	// element.
+	Example       string
+	ExampleOutput string
+	Printable     bool
+	// FuzzName and FuzzType are the name and type of the argument of the
+	// fuzz test for the parameter, and FuzzArg the argument passed to the
+	// stub, converted from it. FuzzType is empty for parameters that can't
+	// be fuzzed, including variadic ones, which are passed Example.
+	FuzzName string
+	FuzzType string
+	FuzzArg  string
+}
+
+type resultData struct {
+	// Name is the result's name, or RN for unnamed results.
+	Name string
+	Type string
+	// Var is a local variable name for the result.
+	Var string
+	// Named is true when the result is named in the signature.
+	Named bool
+	// Default is the expression unconfigured calls return for the result,
+	// set with Options.Defaults, or empty for its zero value.
+	Default string
+	// Example is an example value for the result, for the example test,
+	// and ExampleOutput how fmt.Println prints it, if Printable is true.
+	Example       string
+	ExampleOutput string
+	Printable     bool
+}
+
+// methodScope is the data the per-method partials of the templates are
+// executed with: the template's data, and the method.
+type methodScope struct {
+	*templateData
+	Method methodData
+}
+
+func scope(data *templateData, method methodData) methodScope {
+	return methodScope{data, method}
+}
+
+// generateStubCode generates the files for iface: the code, followed by
+// the helpers split out of it and the example test when opts asks for
+// them.
+func generateStubCode(iface *model.Interface, opts Options) ([]File, error) {
+	data, err := newTemplateData(iface, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	templateName, templateText, err := readTemplate(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	funcMap := builtinFuncs()
+	for name, fn := range typePredicates(data) {
+		funcMap[name] = fn
+	}
+	if opts.FuncsPlugin != "" {
+		extra, err := loadFuncs(opts.FuncsPlugin)
+		if err != nil {
+			return nil, err
+		}
+		for name, fn := range extra {
+			funcMap[name] = fn
+		}
+	}
+
+	tmpl, err := template.New(templateName).
+		Funcs(funcMap).
+		Parse(templateText)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing template: %v", err)
+	}
+	if opts.PartialsDir != "" {
+		if err := parsePartials(tmpl, opts.PartialsDir); err != nil {
+			return nil, err
+		}
+	}
+
+	code, err := execute(tmpl, data, opts.DisableFormatting)
+	if err != nil {
+		return nil, err
+	}
+	files := []File{{Name: opts.Output, Content: []byte(code)}}
+
+	if opts.SplitHelpers {
+		// The helpers file has the same header as the code.
+		if tmpl.Lookup("helpers") == nil || tmpl.Lookup("header") == nil {
+			return nil, fmt.Errorf("the template has no helpers partial to split out")
+		}
+		helpersTmpl, err := tmpl.New("helpers file").Parse(`{{template "header" .}}{{template "helpers" .}}`)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing template: %v", err)
+		}
+		helpers, err := execute(helpersTmpl, data, opts.DisableFormatting)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, File{
+			Name:    strings.TrimSuffix(opts.Output, ".go") + "_helpers.go",
+			Content: []byte(helpers),
+		})
+	}
+
+	var declared []string
+	for _, t := range tmpl.Templates() {
+		declared = append(declared, t.Name())
+	}
+	suffixes, err := fileSuffixes(declared)
+	if err != nil {
+		return nil, err
+	}
+	for _, suffix := range suffixes {
+		if opts.Output == "" {
+			return nil, fmt.Errorf("generating the files declared by the template requires an output file name")
+		}
+		code, err := execute(tmpl.Lookup(filePrefix+suffix), data, opts.DisableFormatting)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, File{
+			Name:    strings.TrimSuffix(opts.Output, ".go") + suffix,
+			Content: []byte(code),
+		})
+	}
+
+	if opts.WithExample {
+		file, err := generateTest(exampleTemplate, "_example_test.go", data, funcMap, opts)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	if opts.WithRaceTest {
+		file, err := generateTest(raceTestTemplate, "_race_test.go", data, funcMap, opts)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	if opts.WithFuzz {
+		if !data.HasFuzz {
+			return nil, model.Errorf(iface.Pos, "no method of %s has parameters that can be fuzzed", iface.Name)
+		}
+		file, err := generateTest(fuzzTemplate, "_fuzz_test.go", data, funcMap, opts)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	if err := checkFileNames(files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// filePrefix starts the names of the templates declaring extra files to
+// generate: executing the template "file:_suffix.go" generates the file
+// named after Options.Output with the suffix, formatted like the code.
+const filePrefix = "file:"
+
+// readTemplate returns the name and text of the template to generate the
+// code with: opts.TemplateFile, or else the style's.
+func readTemplate(opts Options) (string, string, error) {
+	if opts.TemplateFile == "" {
+		return "stub", styles[opts.Style].template, nil
+	}
+	b, err := os.ReadFile(opts.TemplateFile)
+	if err != nil {
+		return "", "", fmt.Errorf("error reading template: %v", err)
+	}
+	return filepath.Base(opts.TemplateFile), string(b), nil
+}
+
+// fileSuffixes returns the suffixes of the files declared by the templates
+// named names, sorted. Each must name a Go file in the output file's
+// directory.
+func fileSuffixes(names []string) ([]string, error) {
+	var suffixes []string
+	for _, name := range names {
+		suffix, ok := strings.CutPrefix(name, filePrefix)
+		if !ok {
+			continue
+		}
+		if !strings.HasSuffix(suffix, ".go") || strings.ContainsAny(suffix, `/\`) {
+			return nil, fmt.Errorf("template %q must declare a suffix ending in .go, such as %q", name, filePrefix+"_mock.go")
+		}
+		suffixes = append(suffixes, suffix)
+	}
+	sort.Strings(suffixes)
+	return suffixes, nil
+}
+
+// declaredFiles returns the suffixes of the files declared by the template
+// and partials opts generates code with, sorted, without executing them,
+// so that the functions they use needn't be loaded.
+func declaredFiles(opts Options) ([]string, error) {
+	_, text, err := readTemplate(opts)
+	if err != nil {
+		return nil, err
+	}
+	texts := []string{text}
+	if opts.PartialsDir != "" {
+		partials, err := filepath.Glob(filepath.Join(opts.PartialsDir, "*.tmpl"))
+		if err != nil {
+			return nil, fmt.Errorf("error reading template partials: %v", err)
+		}
+		for _, file := range partials {
+			b, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("error reading template partials: %v", err)
+			}
+			texts = append(texts, string(b))
+		}
+	}
+
+	trees := make(map[string]*parse.Tree)
+	for _, text := range texts {
+		tree := parse.New("declared")
+		tree.Mode = parse.SkipFuncCheck
+		if _, err := tree.Parse(text, "", "", trees); err != nil {
+			return nil, fmt.Errorf("error parsing template: %v", err)
+		}
+	}
+	var names []string
+	for name := range trees {
+		names = append(names, name)
+	}
+	return fileSuffixes(names)
+}
+
+// checkFileNames returns an error if two of files, generated for one
+// interface, have the same name, as a file declared by the template may
+// have that of another generated file.
+func checkFileNames(files []File) error {
+	names := make(map[string]bool)
+	for _, f := range files {
+		if names[f.Name] {
+			return fmt.Errorf("the template declares file %s, which is already generated", f.Name)
+		}
+		names[f.Name] = true
+	}
+	return nil
+}
+
+// generateTest generates a test of the stub from the template text, into
+// a file named after opts.Output with suffix.
+func generateTest(text string, suffix string, data *templateData, funcMap template.FuncMap, opts Options) (File, error) {
+	tmpl, err := template.New(strings.TrimPrefix(suffix, "_")).Funcs(funcMap).Parse(text)
+	if err != nil {
+		return File{}, fmt.Errorf("error parsing template: %v", err)
+	}
+	code, err := execute(tmpl, data, opts.DisableFormatting)
+	if err != nil {
+		return File{}, err
+	}
+	return File{
+		Name:    strings.TrimSuffix(opts.Output, ".go") + suffix,
+		Content: []byte(code),
+	}, nil
+}
+
+// execute executes tmpl with data, formatting the result unless
+// disableFormatting is set. The imports it doesn't use are dropped either
+// way.
+func execute(tmpl *template.Template, data *templateData, disableFormatting bool) (string, error) {
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("error generating stub: %v", err)
+	}
+
+	if disableFormatting {
+		return dropUnusedImports(buf.String()), nil
+	}
+	return formatCode(buf.String())
+}
+
+// newTemplateData returns the data to execute the templates with to
+// generate code for iface.
+func newTemplateData(iface *model.Interface, opts Options) (*templateData, error) {
+	pkg := iface.Type.Obj().Pkg()
+	data := &templateData{
+		PackageName:   opts.PackageName,
+		RuntimePath:   runtimePath,
+		Style:         opts.Style,
+		InterfaceName: iface.Name,
+		InterfacePath: pkg.Path() + "." + iface.Name,
+		InterfaceHash: iface.Hash,
+		Func:          iface.Func,
+		PackagePath:   pkg.Path(),
+		ModulePath:    iface.Module,
+		SourceFile:    sourceFile(iface),
+		SourceLine:    iface.Pos.Line,
+		ToolVersion:   Version,
+		StubName:      styles[opts.Style].prefix + iface.Name,
+		SplitHelpers:  opts.SplitHelpers,
+
+		ErrorUnconfigured: opts.ErrorUnconfigured,
+		CallChannels:      opts.CallChannels,
+		EmbedInterface:    opts.EmbedInterface,
+		Assertions:        opts.Assertions,
+	}
+	var err error
+	if data.ToolOptions, err = headerOptions(opts); err != nil {
+		return nil, err
+	}
+	if data.PackageName == "" {
+		data.PackageName = pkg.Name()
+	}
+
+	// The interface's own package is only imported when generating into
+	// another package.
+	local := pkg
+	if data.PackageName != pkg.Name() {
+		local = nil
+	}
+	imps := newImportSet(local)
+	imps.reserve("runtime", "errors", "fmt", "io", "sync", "time", "prometheus", "require", "assert",
+		"cmp", "qt", "testing")
+	for _, spec := range opts.Imports {
+		imp, err := parseImport(spec)
+		if err != nil {
+			return nil, err
+		}
+		if err := imps.add(imp); err != nil {
+			return nil, err
+		}
+	}
+
+	replacer, err := newTypeReplacer(opts.ReplaceTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	var typeArgs []string
+	var typeParamDecls []string
+	tparams := iface.Type.TypeParams()
+	for i := 0; i < tparams.Len(); i++ {
+		tp := tparams.At(i)
+		typ, err := replacer.replace(tp.Constraint())
+		if err != nil {
+			return nil, err
+		}
+		constraint := types.TypeString(typ, imps.qualifier)
+		data.TypeParams = append(data.TypeParams, typeParamData{
+			Name:       tp.Obj().Name(),
+			Constraint: constraint,
+		})
+		typeArgs = append(typeArgs, tp.Obj().Name())
+		typeParamDecls = append(typeParamDecls, tp.Obj().Name()+" "+constraint)
+	}
+	if len(typeArgs) > 0 {
+		data.TypeArgs = "[" + strings.Join(typeArgs, ", ") + "]"
+		data.TypeParamsDecl = "[" + strings.Join(typeParamDecls, ", ") + "]"
+	}
+
+	data.InterfaceType = iface.Name + data.TypeArgs
+	if local == nil {
+		data.InterfaceType = imps.qualifier(pkg) + "." + data.InterfaceType
+	}
+	if replacer != nil {
+		if named, ok := replacer.lookup(iface.Type.Obj(), iface.Type.Underlying()); ok {
+			data.InterfaceType = types.TypeString(named, imps.qualifier) + data.TypeArgs
+		}
+	}
+
+	stubbed, err := filterMethods(iface, opts.Methods, opts.ExcludeMethods)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool)
+	for _, m := range iface.Methods {
+		method, err := newMethodData(m, opts.ArgNaming, imps, replacer)
+		if err != nil {
+			return nil, model.Errorf(m.Pos, "%v", err)
+		}
+		if styles[opts.Style].needsErrors && !method.HasError {
+			return nil, model.Errorf(m.Pos, "style %s requires every method to return an error, but %s.%s does not",
+				opts.Style, iface.Name, method.Name)
+		}
+		for _, p := range method.ParamList {
+			names[p.Name] = true
+		}
+		for _, r := range method.ResultList {
+			names[r.Name] = true
+		}
+		if err := setResultDefaults(&method, m, opts.Defaults); err != nil {
+			return nil, err
+		}
+		if opts.FuncAdapter {
+			adapter := method
+			data.FuncAdapter = &adapter
+		}
+		if stubbed[m.Name] {
+			data.HasContext = data.HasContext || method.Context != ""
+			if len(data.TypeParams) > 0 {
+				// The stream stubs aren't generic, so can't refer to the
+				// interface's type parameters.
+				method.Stream = nil
+			}
+			data.HasStreams = data.HasStreams || method.Stream != nil
+			data.HasClock = data.HasClock || method.Clock != ""
+			data.HasFuzz = data.HasFuzz || method.Fuzz
+			data.HasRead = data.HasRead || method.IO && method.Name == "Read"
+			data.HasWrite = data.HasWrite || method.IO && method.Name == "Write"
+			data.Methods = append(data.Methods, method)
+		} else {
+			data.Unstubbed = append(data.Unstubbed, method)
+		}
+	}
+
+	declared := make(map[string]bool)
+	for _, m := range iface.Methods {
+		declared[m.Name] = true
+	}
+	data.Helpers = make(map[string]string)
+	for _, name := range styles[opts.Style].helpers {
+		data.Helpers[name] = name
+		if declared[name] {
+			data.Helpers[name] = styles[opts.Style].prefix + name
+		}
+	}
+	if _, err := memberNames(iface, data); err != nil {
+		return nil, err
+	}
+
+	data.Imports = imps.list()
+	data.Receiver = receiverName(data.StubName, names)
+	for i := range data.Methods {
+		data.Methods[i].receiver = data.Receiver
+	}
+	return data, nil
+}
+
+// memberNames returns the names of the exported methods and fields of the
+// type generated with data, each described by what it's generated for, or
+// an error if two of them would have the same name, such as the stub's
+// OnThing for the method Thing and the interface's own OnThing.
+func memberNames(iface *model.Interface, data *templateData) (map[string]string, error) {
+	members := make(map[string]string)
+	add := func(name, desc string) error {
+		if other, ok := members[name]; ok {
+			return model.Errorf(iface.Pos, "cannot generate %s for %s: %s and %s would both be named %s",
+				data.StubName, iface.Name, other, desc, name)
+		}
+		members[name] = desc
+		return nil
+	}
+	for _, m := range iface.Methods {
+		if err := add(m.Name, "the method "+m.Name); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range styles[data.Style].helpers {
+		if err := add(data.Helpers[name], "the helper "+name); err != nil {
+			return nil, err
+		}
+	}
+	if data.Style != "stub" && data.Style != "spy" {
+		return members, nil
+	}
+	for _, m := range data.Methods {
+		derived := []string{m.Name + "Calls"}
+		if data.Style == "stub" {
+			derived = append(derived, "On"+m.Name, "Expect"+m.Name, "Record"+m.Name)
+			if len(m.ParamList) > 0 {
+				derived = append(derived, "Assert"+m.Name+"CalledWith")
+			}
+			if data.CallChannels {
+				derived = append(derived, m.Name+"CalledCh")
+			}
+		}
+		for _, name := range derived {
+			if err := add(name, name+" for the method "+m.Name); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return members, nil
+}
+
+// sourceFile returns the file declaring iface, relative to the root of its
+// module, or its base name outside a module, with forward slashes so it
+// is the same on every platform.
+func sourceFile(iface *model.Interface) string {
+	if iface.ModuleDir != "" {
+		if rel, err := filepath.Rel(iface.ModuleDir, iface.Pos.Filename); err == nil {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.Base(iface.Pos.Filename)
+}
+
+// filterMethods returns the names of the methods of iface to stub: those
+// matching any of the patterns in include, or every method if include is
+// empty, less those matching any of the patterns in exclude. Patterns are
+// matched with path.Match, and each must match a method.
+func filterMethods(iface *model.Interface, include []string, exclude []string) (map[string]bool, error) {
+	// matching returns the methods matching any of patterns.
+	matching := func(patterns []string) (map[string]bool, error) {
+		matched := make(map[string]bool)
+		for _, pattern := range patterns {
+			found := false
+			for _, m := range iface.Methods {
+				ok, err := path.Match(pattern, m.Name)
+				if err != nil {
+					return nil, fmt.Errorf("invalid method pattern %q", pattern)
+				}
+				if ok {
+					matched[m.Name] = true
+					found = true
+				}
+			}
+			if !found {
+				return nil, model.Errorf(iface.Pos, "no method of %s matches %q", iface.Name, pattern)
+			}
+		}
+		return matched, nil
+	}
+
+	included, err := matching(include)
+	if err != nil {
+		return nil, err
+	}
+	excluded, err := matching(exclude)
+	if err != nil {
+		return nil, err
+	}
+	stubbed := make(map[string]bool)
+	for _, m := range iface.Methods {
+		if (len(include) == 0 || included[m.Name]) && !excluded[m.Name] {
+			stubbed[m.Name] = true
+		}
+	}
+	return stubbed, nil
+}
+
+// isContextType reports whether typ is context.Context.
+func isContextType(typ types.Type) bool {
+	named, ok := typ.(*types.Named)
+	return ok && named.Obj().Pkg() != nil &&
+		named.Obj().Pkg().Path() == "context" && named.Obj().Name() == "Context"
+}
+
+// holdsReferences reports whether values of typ are, or hold in their
+// elements or fields, pointers, maps, funcs, channels or interfaces, which
+// may hold any of them. Type parameters are assumed not to.
+func holdsReferences(typ types.Type) bool {
+	if _, ok := typ.(*types.TypeParam); ok {
+		return false
+	}
+	switch t := typ.Underlying().(type) {
+	case *types.Pointer, *types.Map, *types.Signature, *types.Chan, *types.Interface:
+		return true
+	case *types.Basic:
+		return t.Kind() == types.UnsafePointer
+	case *types.Slice:
+		return holdsReferences(t.Elem())
+	case *types.Array:
+		return holdsReferences(t.Elem())
+	case *types.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			if holdsReferences(t.Field(i).Type()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// receiverName returns a receiver name for the type typeName that is not
+// in names.
+func receiverName(typeName string, names map[string]bool) string {
+	candidates := []string{
+		strings.ToLower(typeName[:1]),
+		strings.ToLower(typeName[:2]),
+		"recv",
+	}
+	for _, c := range candidates {
+		if !names[c] {
+			return c
+		}
+	}
+	for i := 2; ; i++ {
+		if c := fmt.Sprintf("recv%d", i); !names[c] {
+			return c
+		}
+	}
+}
+
+func newMethodData(m *model.Method, naming string, imps *importSet, replacer *typeReplacer) (methodData, error) {
+	typ, err := replacer.replace(m.Func.Type())
+	if err != nil {
+		return methodData{}, err
+	}
+	sig := typ.(*types.Signature)
+	method := methodData{
+		Name:     m.Name,
+		Doc:      m.Doc,
+		Variadic: sig.Variadic(),
+		types:    make(map[string]types.Type),
+	}
+
+	params := sig.Params()
+	names := paramNames(sig, naming, imps)
+	for i := 0; i < params.Len(); i++ {
+		v := params.At(i)
+		p := paramData{
+			Name: names[i],
+			Type: types.TypeString(v.Type(), imps.qualifier),
+		}
+		p.FieldName = export(p.Name)
+		if naming == ArgNamingArg {
+			p.FieldName = fmt.Sprintf("Arg%d", i+1)
+		}
+		p.FieldType = p.Type
+		p.Example, p.ExampleOutput, p.Printable = exampleValue(v.Type(), imps.qualifier)
+		if sig.Variadic() && i == params.Len()-1 {
+			p.Variadic = true
+			p.Type = "..." + types.TypeString(v.Type().(*types.Slice).Elem(), imps.qualifier)
+			p.Example, p.ExampleOutput, p.Printable = exampleValue(v.Type().(*types.Slice).Elem(), imps.qualifier)
+			p.ExampleOutput = "[" + p.ExampleOutput + "]"
+		}
+		if p.FuzzType = fuzzType(v.Type()); p.FuzzType != "" && !p.Variadic {
+			p.FuzzName = fmt.Sprintf("fuzz%d", i)
+			p.FuzzArg = p.FuzzName
+			if p.FuzzType != p.Type {
+				p.FuzzArg = p.Type + "(" + p.FuzzName + ")"
+			}
+			method.Fuzz = true
+		} else {
+			p.FuzzType = ""
+		}
+		if _, ok := v.Type().Underlying().(*types.Pointer); ok {
+			method.OutParams = true
+		}
+		p.Context = isContextType(v.Type())
+		if holdsReferences(v.Type()) && !p.Context {
+			method.ReferenceParams = true
+		}
+		if method.Context == "" && p.Context {
+			method.Context = p.Name
+		}
+		method.ParamList = append(method.ParamList, p)
+		method.Params = append(method.Params, p.Name+" "+p.Type)
+		method.ParamNames = append(method.ParamNames, p.Name)
+		method.ParamTypes = append(method.ParamTypes, p.Type)
+		method.types[p.Type] = v.Type()
+	}
+
+	results := sig.Results()
+	declared := make(map[string]bool)
+	for _, name := range method.ParamNames {
+		declared[name] = true
+	}
+	for i := 0; i < results.Len(); i++ {
+		declared[results.At(i).Name()] = true
+	}
+	prefix := resultVarPrefix(declared, results.Len())
+	for i := 0; i < results.Len(); i++ {
+		v := results.At(i)
+		r := resultData{
+			Name:  v.Name(),
+			Type:  types.TypeString(v.Type(), imps.qualifier),
+			Var:   fmt.Sprintf("%s%d", prefix, i),
+			Named: v.Name() != "" && v.Name() != "_",
+		}
+		if !r.Named {
+			r.Name = fmt.Sprintf("R%d", i)
+		}
+		r.Example, r.ExampleOutput, r.Printable = exampleValue(v.Type(), imps.qualifier)
+		method.ResultList = append(method.ResultList, r)
+		if r.Named {
+			method.Results = append(method.Results, r.Name+" "+r.Type)
+		} else {
+			method.Results = append(method.Results, r.Type)
+		}
+		method.ResultTypes = append(method.ResultTypes, r.Type)
+		method.ResultNames = append(method.ResultNames, r.Name)
+		method.ResultVars = append(method.ResultVars, r.Var)
+		method.types[r.Type] = v.Type()
+	}
+	method.HasError = results.Len() > 0 &&
+		types.Identical(results.At(results.Len()-1).Type(), types.Universe.Lookup("error").Type())
+	method.RoundTrip = m.Name == "RoundTrip" &&
+		signatureString(sig) == "func(*net/http.Request) (*net/http.Response, error)"
+	method.IO = (m.Name == "Read" || m.Name == "Write") &&
+		signatureString(sig) == "func([]byte) (int, error)"
+	method.Rows = results.Len() == 2 && method.HasError &&
+		types.TypeString(results.At(0).Type(), nil) == "database/sql/driver.Rows"
+	if clockSignatures[m.Name] == signatureString(sig) {
+		method.Clock = m.Name
+	}
+	for i := 0; i < results.Len() && method.Stream == nil; i++ {
+		if method.Stream, err = newStreamData(results.At(i).Type(), replacer, imps.qualifier); err != nil {
+			return methodData{}, err
+		}
+	}
+	return method, nil
+}
+
+// resultVarPrefix returns the prefix of the local variable names of n
+// results, numbered from 0, such that none of the names is in declared.
+func resultVarPrefix(declared map[string]bool, n int) string {
+	clashes := func(prefix string) bool {
+		for i := 0; i < n; i++ {
+			if declared[fmt.Sprintf("%s%d", prefix, i)] {
+				return true
+			}
+		}
+		return false
+	}
+	for _, prefix := range []string{"r", "res", "result"} {
+		if !clashes(prefix) {
+			return prefix
+		}
+	}
+	for i := 2; ; i++ {
+		if prefix := fmt.Sprintf("result%d_", i); !clashes(prefix) {
+			return prefix
+		}
+	}
+}
+
+// setResultDefaults sets the defaults of the results of method, whose model
+// is m, from defaults; see Options.Defaults.
+func setResultDefaults(method *methodData, m *model.Method, defaults map[string]string) error {
+	for i := range method.ResultList {
+		r := &method.ResultList[i]
+		expr, ok := defaults[r.Type]
+		if !ok {
+			expr, ok = defaults[m.Results[i].QualifiedType]
+		}
+		if !ok {
+			continue
+		}
+		if _, err := parser.ParseExpr(expr); err != nil {
+			return fmt.Errorf("invalid default %q for %s: %v", expr, r.Type, err)
+		}
+		r.Default = expr
+		method.HasDefaults = true
+	}
+	return nil
+}
+
+// clockSignatures are the signatures of the methods of runtime.Clock that
+// back the methods of clock-like interfaces, by name.
+var clockSignatures = map[string]string{
+	"Now":   "func() time.Time",
+	"Since": "func(time.Time) time.Duration",
+	"Until": "func(time.Time) time.Duration",
+	"After": "func(time.Duration) <-chan time.Time",
+	"Sleep": "func(time.Duration)",
+}
+
+// signatureString returns sig as a function type without parameter and
+// result names, with its types qualified by their package paths.
+func signatureString(sig *types.Signature) string {
+	unnamed := func(tuple *types.Tuple) *types.Tuple {
+		vars := make([]*types.Var, tuple.Len())
+		for i := range vars {
+			vars[i] = types.NewParam(token.NoPos, nil, "", tuple.At(i).Type())
+		}
+		return types.NewTuple(vars...)
+	}
+	return types.TypeString(types.NewSignatureType(nil, nil, nil,
+		unnamed(sig.Params()), unnamed(sig.Results()), sig.Variadic()), nil)
+}
+
+// newStreamData returns the description of the stream typ, or nil if typ
+// isn't a named interface with a Send(T) error, Recv() (T, error) or
+// CloseAndRecv() (T, error) method.
+func newStreamData(typ types.Type, replacer *typeReplacer, qualifier types.Qualifier) (*streamData, error) {
+	switch typ.(type) {
+	case *types.Named, *types.Alias:
+	default:
+		return nil, nil
+	}
+	if !types.IsInterface(typ) {
+		return nil, nil
+	}
+
+	errorType := types.Universe.Lookup("error").Type()
+	// method returns the signature of the stream's method name if it has
+	// params parameters and returns a value and an error, or just an
+	// error if value is false.
+	method := func(name string, params int, value bool) *types.Signature {
+		obj, _, _ := types.LookupFieldOrMethod(typ, false, nil, name)
+		fn, ok := obj.(*types.Func)
+		if !ok {
+			return nil
+		}
+		sig := fn.Type().(*types.Signature)
+		results := 1
+		if value {
+			results = 2
+		}
+		if sig.Params().Len() != params || sig.Results().Len() != results ||
+			!types.Identical(sig.Results().At(results-1).Type(), errorType) {
+			return nil
+		}
+		return sig
+	}
+
+	stream := &streamData{Type: types.TypeString(typ, qualifier)}
+	if sig := method("Send", 1, false); sig != nil {
+		send, err := replacer.replace(sig.Params().At(0).Type())
+		if err != nil {
+			return nil, err
+		}
+		stream.Send = types.TypeString(send, qualifier)
+	}
+	for _, name := range []string{"Recv", "CloseAndRecv"} {
+		sig := method(name, 0, true)
+		if sig == nil {
+			continue
+		}
+		typ, err := replacer.replace(sig.Results().At(0).Type())
+		if err != nil {
+			return nil, err
+		}
+		recv := types.TypeString(typ, qualifier)
+		if stream.Recv == "" || stream.Recv == recv {
+			stream.Recv = recv
+			stream.RecvMethods = append(stream.RecvMethods, name)
+		}
+	}
+	stream.CloseSend = method("CloseSend", 0, false) != nil
+	if stream.Send == "" && stream.Recv == "" {
+		return nil, nil
+	}
+	return stream, nil
+}
+
+// Argument naming schemes, set with Options.ArgNaming. They decide the
+// names of the generated methods' parameters and of the call-record fields
+// holding them.
+const (
+	// ArgNamingParam keeps the parameters' names, naming unnamed
+	// parameters argN, and names fields after the parameters.
+	ArgNamingParam = "param"
+	// ArgNamingArg is ArgNamingParam, but names the fields ArgN.
+	ArgNamingArg = "arg"
+	// ArgNamingCamel camel-cases the parameters' names, naming unnamed
+	// parameters after their type where it can, and names fields after
+	// the parameters.
+	ArgNamingCamel = "camel"
+)
+
+// paramNames returns the names of the generated method's parameters for
+// the signature sig.
+func paramNames(sig *types.Signature, naming string, imps *importSet) []string {
+	params := sig.Params()
+	for i := 0; i < params.Len(); i++ {
+		// Qualify every type first, so that parameters named after types
+		// don't shadow the packages the signature refers to.
+		types.TypeString(params.At(i).Type(), imps.qualifier)
+	}
+
+	var names []string
+	used := make(map[string]bool)
+	for i := 0; i < params.Len(); i++ {
+		name := params.At(i).Name()
+		if naming == ArgNamingCamel {
+			if name == "" || name == "_" {
+				name = typeParamName(params.At(i).Type())
+				if token.IsKeyword(name) || types.Universe.Lookup(name) != nil || imps.byName[name] != "" {
+					name = ""
+				}
+			} else {
+				name = camel(name)
+			}
+			for n := 2; name != "" && used[name]; n++ {
+				name = fmt.Sprintf("%s%d", strings.TrimRight(name, "0123456789"), n)
+			}
+		}
+		if name == "" || name == "_" {
+			name = fmt.Sprintf("arg%d", i+1)
+		}
+		used[name] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+// typeParamName returns a camel-cased parameter name derived from the name
+// of typ, or "" if typ isn't a named type or a pointer to one.
+func typeParamName(typ types.Type) string {
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	switch t := typ.(type) {
+	case *types.Named:
+		return camel(t.Obj().Name())
+	case *types.Alias:
+		return camel(t.Obj().Name())
+	}
+	return ""
+}
+
+// parsePartials adds the templates in the .tmpl files in dir to tmpl,
+// replacing any partials of the same name. Each file defines the partial
+// named after it, and may define others with {{define}}.
+func parsePartials(tmpl *template.Template, dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		return fmt.Errorf("error reading template partials: %v", err)
+	}
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("error reading template partials: %v", err)
+		}
+		name := strings.TrimSuffix(filepath.Base(file), ".tmpl")
+		if _, err := tmpl.New(name).Parse(string(b)); err != nil {
+			return fmt.Errorf("error parsing template partial: %v", err)
+		}
+	}
+	return nil
+}
+
+// formatCode formats generated code, dropping any imports that the
+// generated code doesn't use. Unlike goimports, it doesn't look for the
+// packages of missing imports, so it doesn't depend on GOPATH or the module
+// cache.
+func formatCode(code string) (string, error) {
+	// imports.Process only sorts and groups the imports.
+	formatted, err := imports.Process("", []byte(dropUnusedImports(code)), &imports.Options{
+		FormatOnly: true,
+		Comments:   true,
+		TabIndent:  true,
+		TabWidth:   8,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error formatting generated code: %v", err)
+	}
+	return string(formatted), nil
+}
+
+// dropUnusedImports returns code without the lines of the imports it
+// doesn't use, leaving it otherwise as it is. Code that doesn't parse is
+// returned unchanged.
+func dropUnusedImports(code string) string {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", code, 0)
+	if err != nil {
+		return code
+	}
+	var b strings.Builder
+	last := 0
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := assumedName(path)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		if name == "_" || name == "." || usesName(file, name) {
+			continue
+		}
+		// Only imports on lines of their own are dropped.
+		start := fset.Position(spec.Pos()).Offset
+		end := fset.Position(spec.End()).Offset
+		lineStart := strings.LastIndex(code[:start], "\n") + 1
+		lineEnd := len(code)
+		if i := strings.Index(code[end:], "\n"); i >= 0 {
+			lineEnd = end + i + 1
+		}
+		if strings.TrimSpace(code[lineStart:start]) != "" || strings.TrimSpace(code[end:lineEnd]) != "" {
+			continue
+		}
+		b.WriteString(code[last:lineStart])
+		last = lineEnd
+	}
+	b.WriteString(code[last:])
+	return b.String()
+}
+
+// assumedName returns the name a package imported without one is referred
+// to by, guessed from its path as goimports does: the last element, or the
+// one before a major version suffix, without any "go-" prefix and from the
+// first character that can't be in an identifier. The templates name the
+// packages whose name is otherwise.
+func assumedName(importPath string) string {
+	base := path.Base(importPath)
+	if v := strings.TrimPrefix(base, "v"); v != base {
+		if _, err := strconv.Atoi(v); err == nil && path.Dir(importPath) != "." {
+			base = path.Base(path.Dir(importPath))
+		}
+	}
+	base = strings.TrimPrefix(base, "go-")
+	if i := strings.IndexFunc(base, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}); i >= 0 {
+		base = base[:i]
+	}
+	return base
+}
+
+// usesName reports whether file refers to a package imported as name.
+func usesName(file *ast.File, name string) bool {
+	used := false
+	ast.Inspect(file, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok && id.Name == name && id.Obj == nil {
+				used = true
+			}
+		}
+		return !used
+	})
+	return used
+}
+
+// parseImport parses an import given as "name=path", or as "path" of a
+// package named as assumedName assumes.
+func parseImport(imp string) (importData, error) {
+	name, path, ok := strings.Cut(imp, "=")
+	if !ok {
+		path = name
+		name = assumedName(path)
+	}
+	if name == "" || path == "" || !token.IsIdentifier(name) {
+		return importData{}, fmt.Errorf("invalid import %q", imp)
+	}
+	return importData{Name: name, Path: path}, nil
+}
+
+// importSet records the packages referred to by generated code, choosing a
+// unique name for each.
+type importSet struct {
+	local  *types.Package
+	names  map[string]string // path to name
+	byName map[string]string // name to path
+}
+
+func newImportSet(local *types.Package) *importSet {
+	return &importSet{
+		local:  local,
+		names:  make(map[string]string),
+		byName: make(map[string]string),
+	}
+}
+
+// reserve prevents packages from being imported with the given names, which
+// are used by the templates.
+func (s *importSet) reserve(names ...string) {
+	for _, name := range names {
+		s.byName[name] = "-"
+	}
+}
+
+// add adds imp to the set under its given name.
+func (s *importSet) add(imp importData) error {
+	if name, ok := s.names[imp.Path]; ok {
+		if name != imp.Name {
+			return fmt.Errorf("%s is imported as both %s and %s", imp.Path, name, imp.Name)
+		}
+		return nil
+	}
+	switch s.byName[imp.Name] {
+	case "":
+	case "-":
+		return fmt.Errorf("cannot import %s as %s: the name is reserved for the templates' own imports",
+			imp.Path, imp.Name)
+	default:
+		return fmt.Errorf("cannot import %s as %s: the name is already used", imp.Path, imp.Name)
+	}
+	s.names[imp.Path] = imp.Name
+	s.byName[imp.Name] = imp.Path
+	return nil
+}
+
+// qualifier is a types.Qualifier that adds the packages it is called with to
+// the set.
+func (s *importSet) qualifier(pkg *types.Package) string {
+	if pkg == s.local {
+		return ""
+	}
+	if name, ok := s.names[pkg.Path()]; ok {
+		return name
+	}
+	name := pkg.Name()
+	for i := 2; s.byName[name] != ""; i++ {
+		name = fmt.Sprintf("%s%d", pkg.Name(), i)
+	}
+	s.names[pkg.Path()] = name
+	s.byName[name] = pkg.Path()
+	return name
+}
+
+// list returns the imports in the set, sorted by path.
+func (s *importSet) list() []importData {
+	var imps []importData
+	for path, name := range s.names {
+		imps = append(imps, importData{Name: name, Path: path})
+	}
+	sort.Slice(imps, func(i, j int) bool {
+		return imps[i].Path < imps[j].Path
+	})
+	return imps
+}
+
+// specs returns the import specs for the set, sorted by path.
+func (s *importSet) specs() []string {
+	var specs []string
+	for _, imp := range s.list() {
+		specs = append(specs, imp.String())
+	}
+	return specs
+}
@@ -0,0 +1,504 @@
+// Package generator generates stubs, test doubles and decorators for Go
+// interfaces. It is the library behind the toe command, for tools that
+// want to generate code with toe without running it.
+package generator
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/phildrip/toe/model"
+)
+
+//go:embed stub.go.tmpl
+var stubTemplate string
+
+//go:embed metrics.go.tmpl
+var metricsTemplate string
+
+//go:embed retry.go.tmpl
+var retryTemplate string
+
+//go:embed breaker.go.tmpl
+var breakerTemplate string
+
+//go:embed cache.go.tmpl
+var cacheTemplate string
+
+//go:embed spy.go.tmpl
+var spyTemplate string
+
+//go:embed noop.go.tmpl
+var noopTemplate string
+
+//go:embed funcfields.go.tmpl
+var funcFieldsTemplate string
+
+//go:embed decorator.go.tmpl
+var decoratorTemplate string
+
+//go:embed aggregate.go.tmpl
+var aggregateTemplate string
+
+//go:embed example.go.tmpl
+var exampleTemplate string
+
+//go:embed racetest.go.tmpl
+var raceTestTemplate string
+
+//go:embed fuzz.go.tmpl
+var fuzzTemplate string
+
+//go:embed scaffold.go.tmpl
+var scaffoldTemplate string
+
+// modulePath is the path of the module providing the generator, derived from
+// this package's import path so that it follows the module declared in
+// go.mod.
+var modulePath = path.Dir(reflect.TypeOf(Options{}).PkgPath())
+
+// runtimePath is the import path of the support library used by generated
+// stubs.
+var runtimePath = modulePath + "/runtime"
+
+// styles maps each style to the template used to render it, the prefix
+// given to the generated type, whether every method of the interface must
+// return an error, and the helper methods the type has besides the
+// interface's; see templateData.Helpers.
+var styles = map[string]struct {
+	template    string
+	prefix      string
+	needsErrors bool
+	helpers     []string
+}{
+	"stub": {stubTemplate, "Stub", false, []string{
+		"Sequence", "Verify", "Scope", "DumpInteractions", "RateLimit", "AllowSequence",
+		"SnapshotConfig", "RestoreConfig", "Clone", "SetReadContent", "WrittenBytes",
+		"Advance", "SetNow", "WaitForTimers", "CaptureContextValues", "PropagateContextErrors",
+		"Func",
+	}},
+	"spy":         {spyTemplate, "Spy", false, []string{"Sequence", "CaptureContextValues"}},
+	"noop":        {noopTemplate, "Noop", false, nil},
+	"func-fields": {funcFieldsTemplate, "Func", false, nil},
+	"decorator":   {decoratorTemplate, "Decorator", false, nil},
+	"metrics":     {metricsTemplate, "Metrics", false, nil},
+	"retry":       {retryTemplate, "Retry", true, nil},
+	"breaker":     {breakerTemplate, "Breaker", true, []string{"State"}},
+	"cache":       {cacheTemplate, "Cache", false, []string{"Purge"}},
+}
+
+// Styles returns the names of the styles of code that can be generated,
+// sorted.
+func Styles() []string {
+	var names []string
+	for name := range styles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Model is a loaded package, whose interfaces code can be generated for.
+type Model = model.Package
+
+// Load loads the package in dir.
+func Load(dir string) (*Model, error) {
+	return model.Load(dir)
+}
+
+// LoadFromModule loads the package in dir of the module given as
+// path@version, fetching it if needed; see model.LoadFromModule.
+func LoadFromModule(module string, dir string) (*Model, error) {
+	return model.LoadFromModule(module, dir)
+}
+
+// LoadFile loads a single Go file, resolving its types as far as it can;
+// see model.LoadFile.
+func LoadFile(file string) (*Model, error) {
+	return model.LoadFile(file)
+}
+
+// Options are the options controlling code generation. Their JSON keys
+// match the settings of the toe command's config file.
+type Options struct {
+	// Interface is the name of the interface to generate code for.
+	Interface string `json:"interface,omitempty"`
+	// Style is the style of code to generate; see Styles. It defaults to
+	// "stub".
+	Style string `json:"style,omitempty"`
+	// Output is the name of the file to generate. The names of any
+	// further files are derived from it. It may be empty when generating
+	// a single file. It may be a template, such as
+	// "stubs/{{.Package}}/{{.Interface | lower}}_stub.go"; see OutputName.
+	Output string `json:"output,omitempty"`
+	// PackageName is the package of the generated code. It defaults to the
+	// interface's package.
+	PackageName string `json:"packageName,omitempty"`
+	// TemplateFile replaces the style's built-in template.
+	TemplateFile string `json:"templateFile,omitempty"`
+	// PartialsDir holds partial templates overriding parts of the
+	// template.
+	PartialsDir string `json:"partialsDir,omitempty"`
+	// TemplateModule is a module, as path@version, providing the
+	// templates, fetched if needed: TemplateFile and PartialsDir are
+	// relative to its root, and TemplateFile defaults to the style's name
+	// followed by ".go.tmpl", such as "stub.go.tmpl".
+	TemplateModule string `json:"templateModule,omitempty"`
+	// ArgNaming is the argument naming scheme, ArgNamingParam by
+	// default.
+	ArgNaming string `json:"argNaming,omitempty"`
+	// Imports are imports to add to the generated code, as "path" or
+	// "name=path".
+	Imports []string `json:"imports,omitempty"`
+	// Methods and ExcludeMethods select the methods the stub implements,
+	// as patterns matched against the method names with path.Match, such
+	// as "Get*": Methods those to include, all of them if empty, and
+	// ExcludeMethods those to leave out. The stub panics when any other
+	// method is called.
+	Methods        []string `json:"methods,omitempty"`
+	ExcludeMethods []string `json:"excludeMethods,omitempty"`
+	// Defaults maps types to the Go expressions the stub's methods return
+	// for results of the type when called without a configured result,
+	// rather than zero values, such as "uuid.Nil" for "uuid.UUID". Types
+	// are as written in the generated code, or qualified by their package's
+	// import path, such as "github.com/google/uuid.UUID". The packages the
+	// expressions refer to must be imported, as those of the types in the
+	// method signatures are.
+	Defaults map[string]string `json:"defaults,omitempty"`
+	// ReplaceTypes maps named types to the types that replace them
+	// wherever the generated code refers to them, both qualified by their
+	// package's import path, such as "example.com/m/internal/ids.ID" to
+	// "example.com/m/ids.ID", for stubs generated outside the internal tree
+	// of the interface's module. The replacements must be identical to the
+	// types they replace, such as exported aliases of them, for the stubs
+	// to implement the interface, and the types replaced can't be generic.
+	// The interface itself can be replaced too.
+	ReplaceTypes map[string]string `json:"replaceTypes,omitempty"`
+	// ErrorUnconfigured makes the stub's methods that return an error
+	// return a *runtime.ErrNotConfigured, rather than nil, when called
+	// without a configured result.
+	ErrorUnconfigured bool `json:"errorUnconfigured,omitempty"`
+	// CallChannels gives the stub a <Method>CalledCh channel for each
+	// method, receiving the record of each call.
+	CallChannels bool `json:"callChannels,omitempty"`
+	// EmbedInterface embeds the interface in the stub, so that the stub
+	// still implements it once methods are added to it, until it is
+	// regenerated. The added methods panic when called.
+	EmbedInterface bool `json:"embedInterface,omitempty"`
+	// FuncAdapter also generates a <Interface>Func type for an interface
+	// with a single method, adapting funcs with the method's signature to
+	// the interface as http.HandlerFunc does http.Handler.
+	FuncAdapter bool `json:"funcAdapter,omitempty"`
+	// SplitHelpers generates the template's helpers partial into a
+	// separate file, named after Output with a "_helpers" suffix.
+	SplitHelpers bool `json:"splitHelpers,omitempty"`
+	// WithExample generates an example test using the stub, named after
+	// Output with an "_example_test" suffix.
+	WithExample bool `json:"withExample,omitempty"`
+	// WithRaceTest generates a test calling the stub's methods
+	// concurrently, for the race detector, named after Output with a
+	// "_race_test" suffix.
+	WithRaceTest bool `json:"withRaceTest,omitempty"`
+	// WithFuzz generates a fuzz test calling the stub's methods with
+	// fuzzed arguments and checking the calls it records, named after
+	// Output with a "_fuzz_test" suffix.
+	WithFuzz bool `json:"withFuzz,omitempty"`
+	// FuncsPlugin is a Go plugin adding functions to those available to
+	// the templates.
+	FuncsPlugin string `json:"funcsPlugin,omitempty"`
+	// DisableFormatting leaves the generated code unformatted, other than
+	// dropping the imports it doesn't use.
+	DisableFormatting bool `json:"disableFormatting,omitempty"`
+	// EOL is the line ending of the generated files, EOLLF by default.
+	EOL string `json:"eol,omitempty"`
+	// Assertions is the library the stub's assertion helpers, such as
+	// Verify and Assert<Method>CalledWith, and the generated tests fail
+	// tests with, AssertionsStd by default.
+	Assertions string `json:"assertions,omitempty"`
+}
+
+// Line endings, set with Options.EOL.
+const (
+	EOLLF   = "lf"
+	EOLCRLF = "crlf"
+)
+
+// Assertion libraries, set with Options.Assertions.
+const (
+	// AssertionsStd fails tests with the testing package's methods, so
+	// that the generated code depends on nothing but the runtime.
+	AssertionsStd = "std"
+	// AssertionsTestify fails tests with testify's require and assert
+	// packages.
+	AssertionsTestify = "testify"
+	// AssertionsCmp compares values with go-cmp, reporting diffs.
+	AssertionsCmp = "cmp"
+	// AssertionsQuicktest fails tests with quicktest's checkers, which
+	// report go-cmp diffs.
+	AssertionsQuicktest = "quicktest"
+)
+
+// File is a generated file.
+type File struct {
+	// Name is the file's name, from Options.Output.
+	Name    string
+	Content []byte
+}
+
+// Generate generates code for an interface in m.
+func Generate(m *Model, opts Options) ([]File, error) {
+	if err := setDefaults(&opts); err != nil {
+		return nil, err
+	}
+	if err := resolveTemplates(&opts); err != nil {
+		return nil, err
+	}
+	if opts.SplitHelpers && opts.Output == "" {
+		return nil, fmt.Errorf("splitting helpers requires an output file name")
+	}
+	if opts.WithExample && opts.Output == "" {
+		return nil, fmt.Errorf("generating an example requires an output file name")
+	}
+	if opts.WithRaceTest && opts.Output == "" {
+		return nil, fmt.Errorf("generating a race test requires an output file name")
+	}
+	if opts.WithFuzz && opts.Output == "" {
+		return nil, fmt.Errorf("generating a fuzz test requires an output file name")
+	}
+	if (len(opts.Methods) > 0 || len(opts.ExcludeMethods) > 0) && opts.Style != "stub" && opts.TemplateFile == "" {
+		return nil, fmt.Errorf("methods can only be selected for the stub style")
+	}
+	if len(opts.Defaults) > 0 && opts.Style != "stub" && opts.TemplateFile == "" {
+		return nil, fmt.Errorf("result defaults are only supported by the stub style")
+	}
+	if opts.ErrorUnconfigured && opts.Style != "stub" && opts.TemplateFile == "" {
+		return nil, fmt.Errorf("unconfigured errors are only supported by the stub style")
+	}
+	if opts.CallChannels && opts.Style != "stub" && opts.TemplateFile == "" {
+		return nil, fmt.Errorf("call channels are only supported by the stub style")
+	}
+	if opts.EmbedInterface && opts.Style != "stub" && opts.TemplateFile == "" {
+		return nil, fmt.Errorf("embedding the interface is only supported by the stub style")
+	}
+	if opts.FuncAdapter && opts.Style != "stub" && opts.TemplateFile == "" {
+		return nil, fmt.Errorf("func adapters are only supported by the stub style")
+	}
+	if opts.Assertions != AssertionsStd && opts.Style != "stub" && opts.TemplateFile == "" {
+		return nil, fmt.Errorf("assertion libraries are only supported by the stub style")
+	}
+	if opts.WithExample && (opts.Style != "stub" || opts.TemplateFile != "") {
+		return nil, fmt.Errorf("examples can only be generated for the built-in stub style")
+	}
+	if opts.WithRaceTest && (opts.Style != "stub" || opts.TemplateFile != "") {
+		return nil, fmt.Errorf("race tests can only be generated for the built-in stub style")
+	}
+	if opts.WithFuzz && (opts.Style != "stub" || opts.TemplateFile != "") {
+		return nil, fmt.Errorf("fuzz tests can only be generated for the built-in stub style")
+	}
+
+	iface, err := m.Lookup(opts.Interface)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Output, err = OutputName(m, opts); err != nil {
+		return nil, err
+	}
+	if err := checkImportPaths(iface); err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(iface.Pos.Filename, "_test.go") && opts.Output != "" && !strings.HasSuffix(opts.Output, "_test.go") {
+		return nil, model.Errorf(iface.Pos, "%s is declared in a test file, so its code must be generated into a _test.go file", iface.Name)
+	}
+	if iface.Func && opts.Style != "stub" && opts.TemplateFile == "" {
+		return nil, model.Errorf(iface.Pos, "func type %s can only be generated with the stub style", iface.Name)
+	}
+	if iface.Func && opts.EmbedInterface {
+		return nil, model.Errorf(iface.Pos, "func type %s can't be embedded in its stub", iface.Name)
+	}
+	if opts.FuncAdapter && iface.Func {
+		return nil, model.Errorf(iface.Pos, "%s is already a func type", iface.Name)
+	}
+	if opts.FuncAdapter && len(iface.Methods) != 1 {
+		return nil, model.Errorf(iface.Pos, "a func adapter requires an interface with a single method, but %s has %d",
+			iface.Name, len(iface.Methods))
+	}
+	if (opts.WithExample || opts.WithRaceTest || opts.WithFuzz) && iface.Type.TypeParams().Len() > 0 {
+		// The tests would have to choose type arguments satisfying the
+		// constraints.
+		return nil, model.Errorf(iface.Pos, "tests can't be generated for generic interface %s", iface.Name)
+	}
+	files, err := generateStubCode(iface, opts)
+	if err != nil {
+		return nil, err
+	}
+	setEOL(files, opts.EOL)
+	return files, nil
+}
+
+// outputData is the data Options.Output is executed with.
+type outputData struct {
+	// Package is the name of the interface's package, and PackagePath its
+	// import path.
+	Package     string
+	PackagePath string
+	Interface   string
+	Style       string
+}
+
+// OutputName returns the name of the file generated with opts for an
+// interface in m: opts.Output, executed as a template if it is one, with
+// the functions available to the code templates and the interface's
+// Package, PackagePath, Interface and Style.
+func OutputName(m *Model, opts Options) (string, error) {
+	if !strings.Contains(opts.Output, "{{") {
+		return opts.Output, nil
+	}
+	if opts.Style == "" {
+		opts.Style = "stub"
+	}
+	tmpl, err := template.New("output").Funcs(builtinFuncs()).Parse(opts.Output)
+	if err != nil {
+		return "", fmt.Errorf("error parsing output file name: %v", err)
+	}
+	var name strings.Builder
+	err = tmpl.Execute(&name, outputData{
+		Package:     m.Name,
+		PackagePath: m.Path,
+		Interface:   opts.Interface,
+		Style:       opts.Style,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error expanding output file name: %v", err)
+	}
+	return name.String(), nil
+}
+
+// OutputFiles returns the names of the files Generate would generate with
+// opts for an interface in m, and the name of the type it would declare in
+// them, without generating them, so that the outputs of several
+// generations can be checked for conflicts.
+func OutputFiles(m *Model, opts Options) ([]string, string, error) {
+	if err := setDefaults(&opts); err != nil {
+		return nil, "", err
+	}
+	if err := resolveTemplates(&opts); err != nil {
+		return nil, "", err
+	}
+	output, err := OutputName(m, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	files := []string{output}
+	base := strings.TrimSuffix(output, ".go")
+	if opts.SplitHelpers {
+		files = append(files, base+"_helpers.go")
+	}
+	declared, err := declaredFiles(opts)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, suffix := range declared {
+		files = append(files, base+suffix)
+	}
+	if opts.WithExample {
+		files = append(files, base+"_example_test.go")
+	}
+	if opts.WithRaceTest {
+		files = append(files, base+"_race_test.go")
+	}
+	if opts.WithFuzz {
+		files = append(files, base+"_fuzz_test.go")
+	}
+	return files, styles[opts.Style].prefix + opts.Interface, nil
+}
+
+// resolveTemplates sets the paths of the templates of opts.TemplateModule,
+// if any, in the module's directory, fetching it if needed.
+func resolveTemplates(opts *Options) error {
+	if opts.TemplateModule == "" {
+		return nil
+	}
+	dir, err := model.DownloadModule(opts.TemplateModule)
+	if err != nil {
+		return fmt.Errorf("error fetching templates: %v", err)
+	}
+	if opts.TemplateFile == "" {
+		opts.TemplateFile = opts.Style + ".go.tmpl"
+	}
+	opts.TemplateFile = filepath.Join(dir, filepath.FromSlash(opts.TemplateFile))
+	if opts.PartialsDir != "" {
+		opts.PartialsDir = filepath.Join(dir, filepath.FromSlash(opts.PartialsDir))
+	}
+	return nil
+}
+
+// setEOL converts the line endings of files to eol: CRLF for EOLCRLF,
+// and otherwise LF, whatever those of the templates.
+func setEOL(files []File, eol string) {
+	for i := range files {
+		content := bytes.ReplaceAll(files[i].Content, []byte("\r\n"), []byte("\n"))
+		if eol == EOLCRLF {
+			content = bytes.ReplaceAll(content, []byte("\n"), []byte("\r\n"))
+		}
+		files[i].Content = content
+	}
+}
+
+// checkImportPaths returns an error if the import path of iface's package,
+// or of a package its methods refer to, is derived from the package's
+// directory, as for packages outside a module or GOPATH: the path would be
+// recorded in the header and imports of the generated code, which would
+// then depend on where it was generated.
+func checkImportPaths(iface *model.Interface) error {
+	paths := []string{iface.Package}
+	for _, imp := range iface.Imports {
+		paths = append(paths, imp.Path)
+	}
+	for _, path := range paths {
+		if strings.HasPrefix(path, "_/") || path == "command-line-arguments" {
+			return model.Errorf(iface.Pos,
+				"the import path of %s depends on its directory; generate from within a module", iface.Name)
+		}
+	}
+	return nil
+}
+
+// setDefaults sets the style and argument naming scheme of opts to the
+// defaults if they are empty, returning an error if they are unknown.
+func setDefaults(opts *Options) error {
+	if opts.Style == "" {
+		opts.Style = "stub"
+	}
+	if _, ok := styles[opts.Style]; !ok {
+		return fmt.Errorf("unknown style %q", opts.Style)
+	}
+	switch opts.ArgNaming {
+	case "":
+		opts.ArgNaming = ArgNamingParam
+	case ArgNamingParam, ArgNamingArg, ArgNamingCamel:
+	default:
+		return fmt.Errorf("unknown argument naming scheme %q", opts.ArgNaming)
+	}
+	switch opts.EOL {
+	case "":
+		opts.EOL = EOLLF
+	case EOLLF, EOLCRLF:
+	default:
+		return fmt.Errorf("unknown line ending %q", opts.EOL)
+	}
+	switch opts.Assertions {
+	case "":
+		opts.Assertions = AssertionsStd
+	case AssertionsStd, AssertionsTestify, AssertionsCmp, AssertionsQuicktest:
+	default:
+		return fmt.Errorf("unknown assertion library %q", opts.Assertions)
+	}
+	return nil
+}
@@ -0,0 +1,1558 @@
+package generator_test
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/phildrip/toe/generator"
+	"github.com/phildrip/toe/model"
+)
+
+func TestGenerate(t *testing.T) {
+	model, err := generator.Load("../ref")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := generator.Generate(model, generator.Options{
+		Interface:    "Thinger",
+		Output:       "stubs/stubthinger.go",
+		PackageName:  "ref_stubs",
+		WithExample:  true,
+		WithRaceTest: true,
+		WithFuzz:     true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 4 {
+		t.Fatalf("expected %v, got %v", 4, len(files))
+	}
+
+	// The checked-in stub is generated with the same options.
+	want, err := os.ReadFile("../ref/stubs/stubthinger.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(files[0].Content) != string(want) {
+		t.Errorf("generated stub differs from ../ref/stubs/stubthinger.go:\n%s", files[0].Content)
+	}
+}
+
+func TestGenerateSplitHelpers(t *testing.T) {
+	model, err := generator.Load("../ref")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		style   string
+		output  string
+		code    []string
+		helpers []string
+	}{
+		{
+			style:   "stub",
+			output:  "stub_thinger.go",
+			code:    []string{"type StubThinger struct {", "func (s *StubThinger) ThingWithParams(arg1 int, arg2 string) (string, error) {"},
+			helpers: []string{"type StubThingerThingWithParamsParams struct {", "type StubThingerThingWithParamsRet struct {", "type StubThingerThingWithParamsThen struct {"},
+		},
+		{
+			style:   "spy",
+			output:  "spy_thinger.go",
+			code:    []string{"type SpyThinger struct {", "func (s *SpyThinger) ThingWithParams(arg1 int, arg2 string) (string, error) {"},
+			helpers: []string{"type SpyThingerThingWithParamsParams struct {"},
+		},
+	}
+	for _, test := range tests {
+		files, err := generator.Generate(model, generator.Options{
+			Interface:    "Thinger",
+			Style:        test.style,
+			Output:       test.output,
+			SplitHelpers: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != 2 {
+			t.Fatalf("expected %v, got %v", 2, len(files))
+		}
+		if want := strings.TrimSuffix(test.output, ".go") + "_helpers.go"; files[1].Name != want {
+			t.Errorf("expected %v, got %v", want, files[1].Name)
+		}
+		code, helpers := string(files[0].Content), string(files[1].Content)
+		for _, want := range test.code {
+			if !strings.Contains(code, want) || strings.Contains(helpers, want) {
+				t.Errorf("%s: expected %q in the code only", test.style, want)
+			}
+		}
+		for _, want := range test.helpers {
+			if !strings.Contains(helpers, want) || strings.Contains(code, want) {
+				t.Errorf("%s: expected %q in the helpers only", test.style, want)
+			}
+		}
+		// The helpers file has the same header as the code.
+		if want := "//toe:interface github.com/phildrip/toe/ref.Thinger\n"; !strings.Contains(helpers, want) {
+			t.Errorf("expected %q in:\n%s", want, helpers)
+		}
+		typeCheck(t, "../ref/thinger.go", files)
+	}
+}
+
+func TestGenerateDocComments(t *testing.T) {
+	tests := []struct {
+		dir          string
+		iface        string
+		splitHelpers bool
+	}{
+		{dir: "../ref", iface: "Thinger"},
+		{dir: "../ref", iface: "Thinger", splitHelpers: true},
+		{dir: "testdata/shapes", iface: "Synthetic"},
+		{dir: "testdata/shapes", iface: "Generic"},
+	}
+	for _, test := range tests {
+		model, err := generator.Load(test.dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files, err := generator.Generate(model, generator.Options{
+			Interface:    test.iface,
+			Output:       "stub.go",
+			SplitHelpers: test.splitHelpers,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Every exported declaration of the stub is documented.
+		for _, file := range files {
+			f, err := parser.ParseFile(token.NewFileSet(), file.Name, file.Content, parser.ParseComments)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, decl := range f.Decls {
+				switch decl := decl.(type) {
+				case *ast.FuncDecl:
+					if decl.Name.IsExported() && decl.Doc == nil {
+						t.Errorf("%s: expected %v, got %v", test.iface, "a doc comment on "+decl.Name.Name, nil)
+					}
+				case *ast.GenDecl:
+					for _, spec := range decl.Specs {
+						if spec, ok := spec.(*ast.TypeSpec); ok && spec.Name.IsExported() && decl.Doc == nil && spec.Doc == nil {
+							t.Errorf("%s: expected %v, got %v", test.iface, "a doc comment on "+spec.Name.Name, nil)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestGenerateMethods(t *testing.T) {
+	model, err := generator.Load("../ref")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := generator.Generate(model, generator.Options{
+		Interface:      "Thinger",
+		Methods:        []string{"ThingWith*"},
+		ExcludeMethods: []string{"ThingWithParams"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := string(files[0].Content)
+	for _, want := range []string{
+		"func (s *StubThinger) OnThingWithParam(args ...any) *StubThingerThingWithParamThen {",
+		`panic("toe: StubThinger.Thing is not stubbed")`,
+		`panic("toe: StubThinger.ThingWithParams is not stubbed")`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("expected %q in:\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, "OnThingWithParams") {
+		t.Errorf("expected no OnThingWithParams in:\n%s", code)
+	}
+}
+
+func TestGenerateErrorUnconfigured(t *testing.T) {
+	model, err := generator.Load("../ref")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := generator.Generate(model, generator.Options{
+		Interface:         "Thinger",
+		ErrorUnconfigured: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `ret.R1 = runtime.NotConfigured("StubThinger", "ThingWithParams", StubThingerThingWithParamsParams{`
+	if !strings.Contains(string(files[0].Content), want) {
+		t.Errorf("expected %q in:\n%s", want, files[0].Content)
+	}
+}
+
+func TestGenerateEmbedInterface(t *testing.T) {
+	model, err := generator.Load("../ref")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := generator.Generate(model, generator.Options{
+		Interface:      "Thinger",
+		PackageName:    "ref_stubs",
+		EmbedInterface: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "type StubThinger struct {\n\t// Thinger provides"
+	if !strings.Contains(string(files[0].Content), want) {
+		t.Errorf("expected %q in:\n%s", want, files[0].Content)
+	}
+	if !strings.Contains(string(files[0].Content), "\tref.Thinger\n") {
+		t.Errorf("expected %q in:\n%s", "ref.Thinger", files[0].Content)
+	}
+}
+
+func TestGenerateDefaults(t *testing.T) {
+	model, err := generator.Load("../ref")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := generator.Generate(model, generator.Options{
+		Interface:   "Thinger",
+		PackageName: "ref_stubs",
+		Defaults:    map[string]string{"string": `"none"`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"runtime.InvokeDefault[StubThingerThingWithParamsRet]", "\t\tret.R0 = \"none\"\n"} {
+		if !strings.Contains(string(files[0].Content), want) {
+			t.Errorf("expected %q in:\n%s", want, files[0].Content)
+		}
+	}
+
+	_, err = generator.Generate(model, generator.Options{
+		Interface: "Thinger",
+		Defaults:  map[string]string{"string": `"none`},
+	})
+	if err == nil {
+		t.Errorf("expected an error for an invalid default, got nil")
+	}
+}
+
+func TestGenerateReplaceTypes(t *testing.T) {
+	model, err := generator.Load("testdata/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := generator.Generate(model, generator.Options{
+		Interface:      "FeedClient",
+		PackageName:    "stubs",
+		EmbedInterface: true,
+		ReplaceTypes: map[string]string{
+			"github.com/phildrip/toe/generator/testdata/stream.Request":    "example.com/api.Request",
+			"github.com/phildrip/toe/generator/testdata/stream.FeedClient": "example.com/api.FeedClient",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(files[0].Content)
+	for _, want := range []string{"\"example.com/api\"", "in *api.Request", "\tapi.FeedClient\n", "*stream.Response"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("expected %q in:\n%s", want, content)
+		}
+	}
+	if strings.Contains(content, "*stream.Request") {
+		t.Errorf("expected no *stream.Request in:\n%s", content)
+	}
+
+	_, err = generator.Generate(model, generator.Options{
+		Interface:    "FeedClient",
+		ReplaceTypes: map[string]string{"Request": "example.com/api.Request"},
+	})
+	if err == nil {
+		t.Errorf("expected an error for an unqualified type, got nil")
+	}
+}
+
+func TestGenerateFuncType(t *testing.T) {
+	model, err := generator.Load("../ref/results")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := generator.Generate(model, generator.Options{
+		Interface:   "Handler",
+		PackageName: "ref_stubs",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "func (s *StubHandler) Func() results.Handler {\n\treturn s.Call\n}"
+	if !strings.Contains(string(files[0].Content), want) {
+		t.Errorf("expected %q in:\n%s", want, files[0].Content)
+	}
+
+	_, err = generator.Generate(model, generator.Options{Interface: "Handler", Style: "spy"})
+	if err == nil {
+		t.Errorf("expected an error for a func type with the spy style, got nil")
+	}
+}
+
+func TestGenerateFuncAdapter(t *testing.T) {
+	model, err := generator.Load("testdata/svc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := generator.Generate(model, generator.Options{
+		Interface:   "Clock",
+		FuncAdapter: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"type ClockFunc func() time2.Time\n",
+		"func (s ClockFunc) Now() time2.Time {\n\treturn s()\n}",
+	} {
+		if !strings.Contains(string(files[0].Content), want) {
+			t.Errorf("expected %q in:\n%s", want, files[0].Content)
+		}
+	}
+
+	_, err = generator.Generate(model, generator.Options{Interface: "Repo", FuncAdapter: true})
+	if err == nil {
+		t.Errorf("expected an error for an interface with two methods, got nil")
+	}
+}
+
+func TestGenerateHelperNames(t *testing.T) {
+	model, err := generator.Load("testdata/doc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := generator.Generate(model, generator.Options{
+		Interface: "Doc",
+		Output:    "stub_doc.go",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"func (s *StubDoc) Clone() Doc {",
+		"func (s *StubDoc) StubClone() *StubDoc {",
+		"func (s *StubDoc) StubVerify(t runtime.TB) {",
+		"func (s *StubDoc) StubSequence() []runtime.Call {",
+		"func (s *StubDoc) Scope(t runtime.TB) {",
+	} {
+		if !strings.Contains(string(files[0].Content), want) {
+			t.Errorf("expected %q in:\n%s", want, files[0].Content)
+		}
+	}
+	typeCheck(t, "testdata/doc/doc.go", files)
+
+	_, err = generator.Generate(model, generator.Options{Interface: "Switch"})
+	if want := "the method OnOn and OnOn for the method On would both be named OnOn"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("expected %v, got %v", want, err)
+	}
+}
+
+func TestGenerateSharedMethodNames(t *testing.T) {
+	model, err := generator.Load("testdata/doc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The stubs of interfaces sharing a method name can share a package.
+	var files []generator.File
+	for _, name := range []string{"Source", "Sink"} {
+		generated, err := generator.Generate(model, generator.Options{
+			Interface: name,
+			Output:    "stub_" + strings.ToLower(name) + ".go",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, generated...)
+	}
+	typeCheck(t, "testdata/doc/doc.go", files)
+}
+
+// typeCheck type-checks files, generated into the package of the interfaces
+// declared in src, along with src.
+func typeCheck(t *testing.T, src string, files []generator.File) {
+	t.Helper()
+	content, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources := map[string][]byte{filepath.Base(src): content}
+	for _, f := range files {
+		sources[f.Name] = f.Content
+	}
+	if err := model.TypeCheck(".", sources); err != nil {
+		t.Errorf("expected %v, got %v", nil, err)
+	}
+}
+
+func TestGenerateTemplateFile(t *testing.T) {
+	model, err := generator.Load("../ref")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The template imports os without using it, which is dropped.
+	text := "package {{.PackageName}}\n\nimport (\n\"fmt\"\n\"os\"\n)\n\n" +
+		"var {{.StubName}}Methods = fmt.Sprint( {{range .Methods}}\"{{.Name}}\", {{end}} )\n"
+	tests := []struct {
+		name              string
+		text              string
+		disableFormatting bool
+		want              string
+		err               string
+	}{
+		{
+			name: "formatted",
+			text: text,
+			want: "package ref\n\nimport (\n\t\"fmt\"\n)\n\n" +
+				"var StubThingerMethods = fmt.Sprint(\"Thing\", \"ThingWithParam\", \"ThingWithParams\")\n",
+		},
+		{
+			name:              "unformatted",
+			text:              "package {{.PackageName}}\n\nimport (\n\"fmt\"\n\"os\"\n)\n\nvar {{.StubName}}  =  fmt.Sprint()\n",
+			disableFormatting: true,
+			want:              "package ref\n\nimport (\n\"fmt\"\n)\n\nvar StubThinger  =  fmt.Sprint()\n",
+		},
+		{name: "invalid", text: "{{range .Methods}}", err: "error parsing template"},
+		{name: "missing", err: "error reading template"},
+	}
+	for _, test := range tests {
+		tmpl := filepath.Join(t.TempDir(), test.name+".tmpl")
+		if test.text != "" {
+			if err := os.WriteFile(tmpl, []byte(test.text), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		files, err := generator.Generate(model, generator.Options{
+			Interface:         "Thinger",
+			Output:            "thinger_names.go",
+			TemplateFile:      tmpl,
+			DisableFormatting: test.disableFormatting,
+		})
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s: expected %v, got %v", test.name, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(files[0].Content) != test.want {
+			t.Errorf("%s: expected %q, got %q", test.name, test.want, files[0].Content)
+		}
+	}
+}
+
+func TestGenerateMethodData(t *testing.T) {
+	model, err := generator.Load("testdata/shapes")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := filepath.Join(t.TempDir(), "methods.tmpl")
+	text := "{{range .Methods}}{{.Name}}" +
+		"({{range .ParamList}} {{.Name}} {{.Type}} {{.FieldName}} {{.FieldType}} {{.Variadic}}{{end}} )" +
+		"({{range .ResultList}} {{.Name}} {{.Type}} {{.Var}} {{.Named}}{{end}} )" +
+		" error={{.HasError}} variadic={{.Variadic}}\n{{end}}"
+	if err := os.WriteFile(tmpl, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	files, err := generator.Generate(model, generator.Options{
+		Interface:         "Synthetic",
+		TemplateFile:      tmpl,
+		DisableFormatting: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := string(files[0].Content)
+
+	tests := []struct {
+		method string
+		want   string
+	}{
+		{"Ping", "Ping( )( ) error=false variadic=false"},
+		{"Tag", "Tag( arg1 context.Context Arg1 context.Context false arg2 *Item Arg2 *Item false arg3 ...string Arg3 []string true )( R0 error r0 false ) error=true variadic=true"},
+		{"List", "List( ctx context.Context Ctx context.Context false filter func(Item) bool Filter func(Item) bool false limit int Limit int false )" +
+			"( items []Item r0 true next string r1 true err error r2 true ) error=true variadic=false"},
+		{"Swap", "Swap( r0 string R0 string false r1 string R1 string false )( R0 string res0 false R1 string res1 false ) error=false variadic=false"},
+	}
+	for _, test := range tests {
+		if !strings.Contains(code, test.want+"\n") {
+			t.Errorf("expected %q for %s in:\n%s", test.want, test.method, code)
+		}
+	}
+}
+
+func TestGenerateSourceData(t *testing.T) {
+	model, err := generator.Load("../ref")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := filepath.Join(t.TempDir(), "source.tmpl")
+	err = os.WriteFile(tmpl, []byte("// {{.ModulePath}} {{.PackagePath}} {{.SourceFile}}:{{.SourceLine}}\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := generator.Generate(model, generator.Options{
+		Interface:         "Thinger",
+		TemplateFile:      tmpl,
+		DisableFormatting: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "// github.com/phildrip/toe github.com/phildrip/toe/ref ref/thinger.go:7\n"; string(files[0].Content) != want {
+		t.Errorf("expected %q, got %q", want, files[0].Content)
+	}
+}
+
+func TestGenerateTemplateFuncs(t *testing.T) {
+	model, err := generator.Load("../ref")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := filepath.Join(t.TempDir(), "funcs.tmpl")
+	text := "{{range .Methods}}{{snake .Name}} {{plural .Name}} {{kebab $.StubName}}({{join .ParamTypes \", \"}}){{if isError (last .ResultTypes)}} error{{end}}\n{{end}}"
+	if err := os.WriteFile(tmpl, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	files, err := generator.Generate(model, generator.Options{
+		Interface:         "Thinger",
+		TemplateFile:      tmpl,
+		DisableFormatting: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "thing Things stub-thinger() error\n" +
+		"thing_with_param ThingWithParams stub-thinger(int) error\n" +
+		"thing_with_params ThingWithParamses stub-thinger(int, string) error\n"
+	if string(files[0].Content) != want {
+		t.Errorf("expected %q, got %q", want, files[0].Content)
+	}
+}
+
+func TestGenerateTypePredicates(t *testing.T) {
+	model, err := generator.Load("testdata/shapes")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := filepath.Join(t.TempDir(), "kinds.tmpl")
+	text := `{{define "kind"}} {{if isMap .}}map{{else if isSlice .}}slice{{else if isPointer .}}pointer` +
+		`{{else if isChan .}}chan{{else if isFunc .}}func{{else if isContext .}}context{{else if isError .}}error{{else}}other{{end}}{{end}}` +
+		`{{range .Methods}}{{.Name}}({{range .ParamTypes}}{{template "kind" .}}{{end}} ){{range .ResultTypes}}{{template "kind" .}}{{end}}` + "\n{{end}}"
+	if err := os.WriteFile(tmpl, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	files, err := generator.Generate(model, generator.Options{
+		Interface:         "Synthetic",
+		TemplateFile:      tmpl,
+		DisableFormatting: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := string(files[0].Content)
+
+	tests := []struct {
+		method string
+		kinds  string
+	}{
+		{"Annotate", "Annotate( map slice ) map"},
+		{"Lookup", "Lookup( map ) map other"},
+		{"Put", "Put( context slice ) error"},
+		{"List", "List( context func other ) slice other error"},
+		{"Send", "Send( chan other )"},
+		{"Get", "Get( context other ) pointer error"},
+	}
+	for _, test := range tests {
+		if !strings.Contains(code, test.kinds+"\n") {
+			t.Errorf("expected %q for %s in:\n%s", test.kinds, test.method, code)
+		}
+	}
+}
+
+func TestGeneratePartials(t *testing.T) {
+	model, err := generator.Load("../ref")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	header := `// Copyright Example Corp. Code generated by toe. DO NOT EDIT.
+
+package {{.PackageName}}
+
+import (
+	{{- range .Imports}}
+	{{.}}
+	{{- end}}
+)
+`
+	if err := os.WriteFile(filepath.Join(dir, "header.tmpl"), []byte(header), 0644); err != nil {
+		t.Fatal(err)
+	}
+	files, err := generator.Generate(model, generator.Options{
+		Interface:   "Thinger",
+		Style:       "noop",
+		Output:      "noop_thinger.go",
+		PartialsDir: dir,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "// Copyright Example Corp."; !strings.HasPrefix(string(files[0].Content), want) {
+		t.Errorf("expected %q, got %q", want, files[0].Content)
+	}
+	typeCheck(t, "../ref/thinger.go", files)
+}
+
+func TestGenerateArgNaming(t *testing.T) {
+	model, err := generator.Load("testdata/shapes")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		naming string
+		method string
+		fields string
+	}{
+		{"param", "Tag(arg1 context.Context, arg2 *Item, arg3 ...string) error", "Arg1 context.Context\n\tArg2 *Item\n\tArg3 []string\n"},
+		{"arg", "Tag(arg1 context.Context, arg2 *Item, arg3 ...string) error", "Arg1 context.Context\n\tArg2 *Item\n\tArg3 []string\n"},
+		// context.Context's parameter would shadow the package.
+		{"camel", "Tag(arg1 context.Context, item *Item, arg3 ...string) error", "Arg1 context.Context\n\tItem *Item\n\tArg3 []string\n"},
+	}
+	for _, test := range tests {
+		files, err := generator.Generate(model, generator.Options{
+			Interface: "Synthetic",
+			Output:    "stub_synthetic.go",
+			ArgNaming: test.naming,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		code := string(files[0].Content)
+		if want := "func (s *StubSynthetic) " + test.method + " {"; !strings.Contains(code, want) {
+			t.Errorf("expected %q in:\n%s", want, code)
+		}
+		if want := "type StubSyntheticTagParams struct {\n\t" + test.fields + "}"; !strings.Contains(code, want) {
+			t.Errorf("expected %q in:\n%s", want, code)
+		}
+		typeCheck(t, "testdata/shapes/shapes.go", files)
+	}
+}
+
+func TestGenerateImports(t *testing.T) {
+	model, err := generator.Load("../ref")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		imp  string
+		use  string
+		want string
+	}{
+		{"str=strings", `str.ToUpper("{{.InterfaceName}}")`, `str "strings"`},
+		{"strings", `strings.ToUpper("{{.InterfaceName}}")`, `"strings"`},
+		// The package of a major version suffix is named after the
+		// element before it.
+		{"math/rand/v2", `{{range .Imports}}{{if eq .Path "math/rand/v2"}}{{.Name}}{{end}}{{end}}.IntN(2)`, `"math/rand/v2"`},
+		{"rnd=math/rand/v2", `rnd.IntN(2)`, `rnd "math/rand/v2"`},
+	}
+	for _, test := range tests {
+		tmpl := filepath.Join(t.TempDir(), "imports.tmpl")
+		text := `package {{.PackageName}}
+
+import (
+	{{- range .Imports}}
+	{{.}}
+	{{- end}}
+)
+
+var {{.StubName}}Value = ` + test.use + "\n"
+		if err := os.WriteFile(tmpl, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+		files, err := generator.Generate(model, generator.Options{
+			Interface:    "Thinger",
+			Output:       "thinger_value.go",
+			TemplateFile: tmpl,
+			Imports:      []string{test.imp},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "\t" + test.want + "\n"; !strings.Contains(string(files[0].Content), want) {
+			t.Errorf("expected %q in:\n%s", want, files[0].Content)
+		}
+		typeCheck(t, "../ref/thinger.go", files)
+	}
+}
+
+func TestGenerateFormatImports(t *testing.T) {
+	model, err := generator.Load("../ref")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := filepath.Join(t.TempDir(), "imports.tmpl")
+	text := `package {{.PackageName}}
+
+import (
+	"os"
+	"strings"
+	"github.com/google/go-cmp/cmp"
+	"github.com/phildrip/toe/runtime"
+	yaml "gopkg.in/yaml.v3"
+	"math/rand/v2"
+	_ "embed"
+)
+
+var _ = strings.ToUpper
+var _ = rand.Int
+var _ runtime.TB
+var _ = bytes.NewReader
+`
+	if err := os.WriteFile(tmpl, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Only the imports that aren't used are changed, without looking for
+	// packages, such as the missing bytes.
+	files, err := generator.Generate(model, generator.Options{Interface: "Thinger", TemplateFile: tmpl})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `package ref
+
+import (
+	_ "embed"
+	"math/rand/v2"
+	"strings"
+
+	"github.com/phildrip/toe/runtime"
+)
+
+var _ = strings.ToUpper
+var _ = rand.Int
+var _ runtime.TB
+var _ = bytes.NewReader
+`
+	if string(files[0].Content) != want {
+		t.Errorf("expected %s, got %s", want, files[0].Content)
+	}
+
+	files, err = generator.Generate(model, generator.Options{Interface: "Thinger", TemplateFile: tmpl, DisableFormatting: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = strings.NewReplacer("{{.PackageName}}", "ref", "\t\"os\"\n", "", "\t\"github.com/google/go-cmp/cmp\"\n", "", "\tyaml \"gopkg.in/yaml.v3\"\n", "").Replace(text)
+	if string(files[0].Content) != want {
+		t.Errorf("expected %s, got %s", want, files[0].Content)
+	}
+}
+
+func TestGenerateDeclaredFiles(t *testing.T) {
+	model, err := generator.Load("../ref")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := filepath.Join(t.TempDir(), "files.tmpl")
+	text := `package {{.PackageName}}
+{{define "file:_names.go"}}package {{.PackageName}}
+
+var {{.StubName}}Names = []string{ {{range .Methods}}"{{.Name}}",{{end}} }
+{{end}}`
+	if err := os.WriteFile(tmpl, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	opts := generator.Options{Interface: "Thinger", Output: "thinger_stub.go", TemplateFile: tmpl}
+	names, _, err := generator.OutputFiles(model, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"thinger_stub.go", "thinger_stub_names.go"}; fmt.Sprint(names) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, names)
+	}
+	files, err := generator.Generate(model, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[1].Name != "thinger_stub_names.go" {
+		t.Fatalf("expected %v, got %v", "thinger_stub_names.go", files)
+	}
+	want := "var StubThingerNames = []string{\"Thing\", \"ThingWithParam\", \"ThingWithParams\"}\n"
+	if !strings.Contains(string(files[1].Content), want) {
+		t.Errorf("expected %q in:\n%s", want, files[1].Content)
+	}
+
+	if err := os.WriteFile(tmpl, []byte(`{{define "file:names.txt"}}{{end}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := generator.Generate(model, opts); err == nil {
+		t.Errorf("expected an error declaring a file that isn't Go code")
+	}
+}
+
+func TestGenerateTemplateModule(t *testing.T) {
+	model, err := generator.Load("../ref")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The module, a dependency of toe, is in the module cache. Its licence
+	// is a template without actions.
+	t.Setenv("GOPROXY", "off")
+	files, err := generator.Generate(model, generator.Options{
+		Interface:         "Thinger",
+		TemplateModule:    "golang.org/x/tools@v0.26.0",
+		TemplateFile:      "LICENSE",
+		DisableFormatting: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Copyright 2009 The Go Authors."; !strings.HasPrefix(string(files[0].Content), want) {
+		t.Errorf("expected %q, got %q", want, files[0].Content)
+	}
+}
+
+func TestGenerateOutsideModule(t *testing.T) {
+	model, err := generator.Load("../ref")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := generator.Generate(model, generator.Options{
+		Interface:   "Thinger",
+		Output:      "stub_thinger.go",
+		PackageName: "stubs",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The stub is built in a module of its own, which requires toe by its
+	// module path like any other user of it.
+	root, err := filepath.Abs("..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, err := os.ReadFile(filepath.Join(root, "go.sum"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	mod := "module example.com/m\n\ngo 1.22.0\n\nrequire github.com/phildrip/toe v0.0.0\n\nreplace github.com/phildrip/toe => " + root + "\n"
+	for name, content := range map[string][]byte{
+		"go.mod":          []byte(mod),
+		"go.sum":          sum,
+		"stub_thinger.go": files[0].Content,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmd := exec.Command("go", "build", "./...")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("expected %v, got %v: %s", nil, err, out)
+	}
+}
+
+func TestCheckTemplate(t *testing.T) {
+	if err := generator.CheckTemplate(generator.Options{}, "."); err != nil {
+		t.Errorf("expected %v, got %v", nil, err)
+	}
+
+	tmpl := filepath.Join(t.TempDir(), "bad.tmpl")
+	text := "package {{.PackageName}}\n\nvar _ {{.InterfaceName}} = (*{{.StubName}})(nil)\n"
+	if err := os.WriteFile(tmpl, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := generator.CheckTemplate(generator.Options{TemplateFile: tmpl}, ".")
+	if err == nil || !strings.Contains(err.Error(), "undefined: StubSynthetic") {
+		t.Errorf("expected %v, got %v", "undefined: StubSynthetic", err)
+	}
+}
+
+func TestGenerateStreams(t *testing.T) {
+	model, err := generator.Load("testdata/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := generator.Generate(model, generator.Options{Interface: "FeedClient"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := string(files[0].Content)
+	for _, want := range []string{
+		"func (s *StubFeedClientListStream) Recv() (*Response, error) {",
+		"func (s *StubFeedClientUploadStream) Send(msg *Request) error {",
+		"func (s *StubFeedClientUploadStream) CloseAndRecv() (*Response, error) {",
+		"func (s *StubFeedClientChatStream) Sent() []*Request {",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("expected %q in:\n%s", want, code)
+		}
+	}
+	if strings.Contains(code, "StubFeedClientGetStream") {
+		t.Errorf("expected no StubFeedClientGetStream in:\n%s", code)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	model, err := generator.Load("../ref")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, opts := range []generator.Options{
+		{Interface: "Thinger", Style: "nope"},
+		{Interface: "Nope"},
+		{Interface: "Thinger", ArgNaming: "nope"},
+		{Interface: "Thinger", SplitHelpers: true},
+		{Interface: "Thinger", Methods: []string{"Nope"}},
+		{Interface: "Thinger", Style: "spy", Methods: []string{"Thing"}},
+		{Interface: "Thinger", Style: "spy", ErrorUnconfigured: true},
+		{Interface: "Thinger", Style: "spy", CallChannels: true},
+		{Interface: "Thinger", Style: "spy", EmbedInterface: true},
+		{Interface: "Thinger", WithRaceTest: true},
+		{Interface: "Thinger", WithFuzz: true},
+		{Interface: "Thinger", Style: "spy", Output: "spy_thinger.go", WithFuzz: true},
+	} {
+		if _, err := generator.Generate(model, opts); err == nil {
+			t.Errorf("expected an error generating with %+v", opts)
+		}
+	}
+}
+
+func TestGenerateReproducible(t *testing.T) {
+	m, err := generator.Load("testdata/shapes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, style := range generator.Styles() {
+		interfaces := []string{"Synthetic", "Generic", "Document"}
+		if style == "retry" || style == "breaker" {
+			interfaces = []string{"Fallible", "FallibleGeneric"}
+		}
+		for _, name := range interfaces {
+			// Output is absolute, as in code actions, but only names the files.
+			output := filepath.Join(m.Dir, style+"_"+strings.ToLower(name)+".go")
+			opts := generator.Options{Interface: name, Style: style, Output: output}
+			if style == "stub" && !strings.Contains(name, "Generic") {
+				opts.SplitHelpers, opts.WithExample, opts.WithRaceTest, opts.WithFuzz = true, true, true, true
+			}
+			files, err := generator.Generate(m, opts)
+			if err != nil {
+				t.Errorf("expected %v, got %v", nil, err)
+				continue
+			}
+			var checked []generator.File
+			for _, file := range files {
+				for _, dir := range []string{m.Dir, home} {
+					if strings.Contains(string(file.Content), dir) {
+						t.Errorf("expected %v, got %v", "no "+dir, string(file.Content))
+					}
+				}
+				if !strings.Contains(string(file.Content), "package shapes_test") {
+					checked = append(checked, generator.File{Name: filepath.Base(file.Name), Content: file.Content})
+				}
+			}
+			// The code of the metrics style needs the Prometheus client,
+			// which this module doesn't require.
+			if style != "metrics" {
+				typeCheck(t, "testdata/shapes/shapes.go", checked)
+			}
+		}
+	}
+}
+func TestGenerateStylesBuild(t *testing.T) {
+	m, err := generator.Load("testdata/shapes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	shapes, err := os.ReadFile("testdata/shapes/shapes.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := filepath.Abs("..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, err := os.ReadFile(filepath.Join(root, "go.sum"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The code of every style is built, with its tests, in a module of its
+	// own, which requires the Prometheus client for the metrics style.
+	dir := t.TempDir()
+	mod := "module example.com/m\n\ngo 1.22.0\n\n" +
+		"require (\n\tgithub.com/phildrip/toe v0.0.0\n\tgithub.com/prometheus/client_golang v1.20.5\n)\n\n" +
+		"replace github.com/phildrip/toe => " + root + "\n"
+	for name, content := range map[string][]byte{"go.mod": []byte(mod), "go.sum": sum} {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	goCmd := func(args ...string) *exec.Cmd {
+		cmd := exec.Command("go", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off", "GONOSUMDB=*")
+		return cmd
+	}
+	if out, err := goCmd("list", "-m", "github.com/prometheus/client_golang").CombinedOutput(); err != nil {
+		t.Skipf("the Prometheus client can't be loaded offline: %s", out)
+	}
+
+	for _, style := range generator.Styles() {
+		pkg := filepath.Join(dir, style)
+		if err := os.Mkdir(pkg, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(pkg, "shapes.go"), shapes, 0644); err != nil {
+			t.Fatal(err)
+		}
+		interfaces := []string{"Synthetic", "Generic", "Document"}
+		if style == "retry" || style == "breaker" {
+			interfaces = []string{"Fallible", "FallibleGeneric"}
+		}
+		for _, name := range interfaces {
+			opts := generator.Options{Interface: name, Style: style, Output: strings.ToLower(name) + ".go"}
+			if style == "stub" && !strings.Contains(name, "Generic") {
+				opts.SplitHelpers, opts.WithExample, opts.WithRaceTest, opts.WithFuzz = true, true, true, true
+			}
+			files, err := generator.Generate(m, opts)
+			if err != nil {
+				t.Fatalf("expected %v, got %v", nil, err)
+			}
+			for _, file := range files {
+				if err := os.WriteFile(filepath.Join(pkg, file.Name), file.Content, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+	if out, err := goCmd("vet", "./...").CombinedOutput(); err != nil {
+		t.Errorf("expected %v, got %v: %s", nil, err, out)
+	}
+}
+
+func TestGenerateCacheKey(t *testing.T) {
+	model, err := generator.Load("testdata/shapes")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Search takes a *Item, whose address the default key would hold.
+	panics := `panic("NewCache%[1]s: Opts.Key is required, as %[1]s has cached methods taking pointers, maps, funcs, channels or interfaces")`
+	tests := []struct {
+		iface string
+		want  string
+	}{
+		{"Synthetic", fmt.Sprintf(panics, "Synthetic")},
+		// Format takes a fmt.Stringer, which may hold a pointer.
+		{"Formatter", fmt.Sprintf(panics, "Formatter")},
+		// Load's context isn't part of the key.
+		{"Generic", `return method + fmt.Sprintf("%#v", args)`},
+	}
+	for _, test := range tests {
+		files, err := generator.Generate(model, generator.Options{
+			Interface: test.iface,
+			Style:     "cache",
+			Output:    "cache.go",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(files[0].Content), test.want) {
+			t.Errorf("expected %q in:\n%s", test.want, files[0].Content)
+		}
+	}
+}
+
+func TestGenerateBuiltVersion(t *testing.T) {
+	// go build stamps toe with a pseudo-version from version control, unlike
+	// go run, which the checked-in code is generated with; both record
+	// the same version.
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "toe")
+	if out, err := exec.Command("go", "build", "-o", bin, "..").CombinedOutput(); err != nil {
+		t.Fatalf("expected %v, got %v: %s", nil, err, out)
+	}
+	output := filepath.Join(dir, "retry_thinger.go")
+	cmd := exec.Command(bin, "-style", "retry", "-o", output, ".", "Thinger")
+	cmd.Dir = "../ref"
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("expected %v, got %v: %s", nil, err, out)
+	}
+	got, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile("../ref/retry_thinger.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+func TestGenerateEOL(t *testing.T) {
+	model, err := generator.Load("../ref")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := generator.Generate(model, generator.Options{Interface: "Thinger", EOL: generator.EOLCRLF})
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := string(files[0].Content)
+	if strings.Count(code, "\n") != strings.Count(code, "\r\n") {
+		t.Errorf("expected %v, got %q", "CRLF line endings", code)
+	}
+
+	files, err = generator.Generate(model, generator.Options{Interface: "Thinger"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lf := string(files[0].Content); lf != strings.ReplaceAll(code, "\r\n", "\n") {
+		t.Errorf("expected %v, got %q", "the same code with LF line endings", lf)
+	}
+}
+
+func TestGenerateAssertions(t *testing.T) {
+	model, err := generator.Load("../ref")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := generator.Generate(model, generator.Options{
+		Interface:    "Thinger",
+		Output:       "stub_thinger.go",
+		WithRaceTest: true,
+		Assertions:   generator.AssertionsTestify,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, race := string(files[0].Content), string(files[1].Content)
+	if !strings.Contains(code, "func (s *StubThinger) AssertThingWithParamCalledWith(t require.TestingT, ") ||
+		!strings.Contains(code, "require.NoError(t, s.runtimeStub().Verify())") {
+		t.Errorf("expected %v, got %v", "helpers failing with require", code)
+	}
+	if !strings.Contains(race, `assert.Equal(t, goroutines*calls, stub.ThingCalls.Len(), "calls to Thing")`) {
+		t.Errorf("expected %v, got %v", "a race test asserting with assert", race)
+	}
+
+	files, err = generator.Generate(model, generator.Options{Interface: "Thinger", Assertions: generator.AssertionsQuicktest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := string(files[0].Content); !strings.Contains(code, "qt.Assert(t, s.runtimeStub().Verify(), qt.IsNil)") {
+		t.Errorf("expected %v, got %v", "Verify failing with quicktest", code)
+	}
+
+	_, err = generator.Generate(model, generator.Options{Interface: "Thinger", Style: "spy", Assertions: generator.AssertionsTestify})
+	if err == nil {
+		t.Errorf("expected %v, got %v", "an error", err)
+	}
+}
+
+func TestOutputName(t *testing.T) {
+	model, err := generator.Load("../ref")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := generator.Generate(model, generator.Options{
+		Interface: "Thinger",
+		Style:     "noop",
+		Output:    "stubs/{{.Package}}/{{.Interface | lower}}_{{.Style}}.go",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files[0].Name != "stubs/ref/thinger_noop.go" {
+		t.Errorf("expected %v, got %v", "stubs/ref/thinger_noop.go", files[0].Name)
+	}
+
+	if _, err := generator.OutputName(model, generator.Options{Output: "{{.Nope}}.go"}); err == nil {
+		t.Errorf("expected %v, got %v", "an error", err)
+	}
+}
+
+func TestKeep(t *testing.T) {
+	model, err := generator.Load("../ref")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := generator.Generate(model, generator.Options{Interface: "Thinger", Style: "noop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	region := "// toe:keep\nfunc (n *NoopThinger) Name() string {\n\treturn strings.ToUpper(\"noop\")\n}\n\n// toe:end\n"
+	existing := "package ref\n\nimport \"strings\"\n\n// Old code.\n\n" + region
+	kept, err := generator.Keep("noop_thinger.go", []byte(existing), files[0].Content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(kept), "\n\n"+region) || !strings.Contains(string(kept), "\"strings\"") {
+		t.Errorf("expected %v, got %v", "the region and its import", string(kept))
+	}
+	if strings.Contains(string(kept), "Old code") {
+		t.Errorf("expected %v, got %v", "only the region", string(kept))
+	}
+
+	// Regenerating keeps the region as it is.
+	again, err := generator.Keep("noop_thinger.go", kept, files[0].Content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(kept) {
+		t.Errorf("expected %v, got %v", string(kept), string(again))
+	}
+
+	if _, err := generator.Keep("noop_thinger.go", []byte("// toe:keep\n"), files[0].Content); err == nil {
+		t.Errorf("expected %v, got %v", "an error", err)
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "../ref/stubs/stubthinger.go", nil, parser.PackageClauseOnly|parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, ok := generator.ParseHeader(f)
+	if !ok {
+		t.Fatalf("expected %v, got %v", "a header", ok)
+	}
+	if h.Path != "github.com/phildrip/toe/ref" || h.Name != "Thinger" || h.Style != "stub" {
+		t.Errorf("expected %v, got %v", "github.com/phildrip/toe/ref.Thinger in style stub", h)
+	}
+	if h.Version != generator.Version {
+		t.Errorf("expected %v, got %v", generator.Version, h.Version)
+	}
+	if h.Options.PackageName != "ref_stubs" || !h.Options.WithExample || !h.Options.WithRaceTest {
+		t.Errorf("expected %v, got %v", "the options of the go:generate line", h.Options)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	model, err := generator.Load("../ref")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile("../ref/stubs/stubthinger.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diffs, err := generator.Diff(model, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 0 {
+		t.Errorf("expected %v, got %v", "no differences", diffs)
+	}
+
+	renamed := strings.ReplaceAll(string(content), "ThingWithParams", "ThingWithArgs")
+	diffs, err = generator.Diff(model, []byte(renamed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []generator.MethodDiff{
+		{Name: "ThingWithArgs", Old: "ThingWithArgs(int, string) (string, error)"},
+		{Name: "ThingWithParams", New: "ThingWithParams(int, string) (string, error)"},
+	}
+	if fmt.Sprint(diffs) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, diffs)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	model, err := generator.Load("../ref")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	explanation, err := generator.Explain(model, generator.Options{
+		Interface: "Thinger",
+		Methods:   []string{"Thing"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"thinger.go:7:6: github.com/phildrip/toe/ref.Thinger, 3 methods\n",
+		"thinger.go:8:2: \tThing() error\n",
+		"thinger.go:10:2: \tThingWithParams(arg1 int, arg2 string) (string, error) (not stubbed)\n",
+	} {
+		if !strings.Contains(explanation, want) {
+			t.Errorf("expected %q in:\n%s", want, explanation)
+		}
+	}
+}
+
+func TestGenerateAggregate(t *testing.T) {
+	m, err := generator.Load("testdata/doc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Source and Sink share Close, which the aggregate forwards to
+	// StubSource along with its configurators, and Doc's Verify method is
+	// forwarded rather than ambiguous with the stubs' Verify helpers.
+	interfaces := []string{"Source", "Sink", "Doc"}
+	var files []generator.File
+	for _, name := range interfaces {
+		generated, err := generator.Generate(m, generator.Options{
+			Interface:   name,
+			Output:      "stub_" + strings.ToLower(name) + ".go",
+			PackageName: "stubs",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, generated...)
+	}
+	aggregate, err := generator.GenerateAggregate(m, "Deps", interfaces, generator.Options{
+		Output:      "stub_deps.go",
+		PackageName: "stubs",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := string(aggregate[0].Content)
+	for _, want := range []string{
+		"package stubs\n",
+		"func (s *StubDeps) Close() error {\n\treturn s.StubSource.Close()\n}",
+		"func (s *StubDeps) OnClose(args ...any) *StubSourceCloseThen {",
+		"func (s *StubDeps) Verify(sig []byte) error {\n\treturn s.StubDoc.Verify(sig)\n}",
+		"func (s *StubDeps) StubVerify(t runtime.TB) {",
+		"_ doc.Source = (*StubDeps)(nil)",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("expected %q in:\n%s", want, code)
+		}
+	}
+	sources := map[string][]byte{aggregate[0].Name: aggregate[0].Content}
+	for _, f := range files {
+		sources[f.Name] = f.Content
+	}
+	if err := model.TypeCheck(".", sources); err != nil {
+		t.Errorf("expected %v, got %v", nil, err)
+	}
+
+	_, err = generator.GenerateAggregate(m, "Deps", []string{"Source", "Closer"}, generator.Options{})
+	if want := "interfaces Source and Closer both have a method Close, with different signatures"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("expected %v, got %v", want, err)
+	}
+}
+
+func TestGenerateAggregateHeader(t *testing.T) {
+	m, err := generator.Load("testdata/doc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	aggregate, err := generator.GenerateAggregate(m, "Deps", []string{"Source", "Sink"}, generator.Options{
+		Output:      "stub_deps.go",
+		PackageName: "stubs",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := parser.ParseFile(token.NewFileSet(), "stub_deps.go", aggregate[0].Content, parser.ImportsOnly|parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, imp := range f.Imports {
+		if imp.Path.Value == `"testing"` {
+			t.Errorf("expected %v, got %v", "no import of testing", imp.Path.Value)
+		}
+	}
+
+	h, ok := generator.ParseHeader(f)
+	if !ok {
+		t.Fatalf("expected %v, got %v", "a header", ok)
+	}
+	if h.Style != "aggregate" || h.Version != generator.Version || h.Options.PackageName != "stubs" {
+		t.Errorf("expected %v, got %v", "the aggregate style, version and options", h)
+	}
+	recorded := h.Interfaces()
+	if len(recorded) != 2 {
+		t.Fatalf("expected %v, got %v", 2, len(recorded))
+	}
+	for i, name := range []string{"Source", "Sink"} {
+		iface, err := m.Lookup(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := generator.Header{Path: "github.com/phildrip/toe/generator/testdata/doc", Name: name, Hash: iface.Hash}
+		if got := recorded[i]; got.Path != want.Path || got.Name != want.Name || got.Hash != want.Hash {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestGenerateAggregateGeneric(t *testing.T) {
+	m, err := generator.Load("testdata/doc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	interfaces := []string{"Cache", "Index", "Source"}
+	var files []generator.File
+	for _, name := range interfaces {
+		generated, err := generator.Generate(m, generator.Options{
+			Interface: name,
+			Output:    "stub_" + strings.ToLower(name) + ".go",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, generated...)
+	}
+	aggregate, err := generator.GenerateAggregate(m, "Deps", interfaces, generator.Options{Output: "stub_deps.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := string(aggregate[0].Content)
+	for _, want := range []string{
+		"type StubDeps[K comparable, V any] struct {\n\t*StubCache[K, V]\n\t*StubIndex[K]\n\t*StubSource\n}",
+		"func (s *StubDeps[K, V]) OnClose(args ...any) *StubCacheCloseThen[K, V] {",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("expected %q in:\n%s", want, code)
+		}
+	}
+	typeCheck(t, "testdata/doc/doc.go", append(files, aggregate...))
+
+	_, err = generator.GenerateAggregate(m, "Deps", []string{"Cache", "Set"}, generator.Options{})
+	if want := "the type parameter K of Set is constrained by any, but by comparable"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("expected %v, got %v", want, err)
+	}
+}
+
+func TestScaffold(t *testing.T) {
+	model, err := generator.Load("testdata/svc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := generator.Scaffold(model, "OrderService", []string{"Repo", "Clock"},
+		generator.Options{Output: "order_service_test.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected %v, got %v", 1, len(files))
+	}
+	code := string(files[0].Content)
+	for _, want := range []string{
+		"func TestOrderService_Lookup(t *testing.T) {",
+		"func TestOrderService_Place(t *testing.T) {",
+		"svc := NewOrderService(d.repo, d.clock)",
+		"r0, r1 := svc.Lookup(tt.ctx, tt.ids...)",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("expected %q in:\n%s", want, code)
+		}
+	}
+
+	files, err = generator.Scaffold(model, "OrderService", []string{"Repo", "Clock"},
+		generator.Options{Output: "order_service_test.go", Assertions: generator.AssertionsCmp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := string(files[0].Content); !strings.Contains(code, "if diff := cmp.Diff(tt.wantR0, r0); diff != \"\" {") {
+		t.Errorf("expected %v, got %v", "results compared with cmp.Diff", code)
+	}
+
+	if _, err := generator.Scaffold(model, "Repo", nil, generator.Options{}); err == nil {
+		t.Errorf("expected error scaffolding an interface")
+	}
+}
+
+func TestExtract(t *testing.T) {
+	model, err := generator.Load("testdata/extract")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := generator.Extract(model, "Client", generator.Options{Output: "client_api.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := string(files[0].Content)
+	want := "type ClientInterface interface {\n\tDeadline() time.Time\n\tFetch(ctx context.Context, id string) ([]byte, error)\n}"
+	if !strings.Contains(code, want) {
+		t.Errorf("expected %q in:\n%s", want, code)
+	}
+	if strings.Contains(code, "DO NOT EDIT") {
+		t.Errorf("expected no generated-code header in:\n%s", code)
+	}
+	typeCheck(t, "testdata/extract/extract.go", files)
+
+	files, err = generator.Extract(model, "Box", generator.Options{Interface: "Container", Output: "container.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "type Container[K comparable, V any] interface {\n\tGet(key K) (V, bool)\n\tPut(key K, value V)\n}"
+	if !strings.Contains(string(files[0].Content), want) {
+		t.Errorf("expected %q in:\n%s", want, files[0].Content)
+	}
+	typeCheck(t, "testdata/extract/extract.go", files)
+
+	for _, typeName := range []string{"Nope", "Empty"} {
+		if _, err := generator.Extract(model, typeName, generator.Options{}); err == nil {
+			t.Errorf("expected an error extracting %s, got nil", typeName)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package generator
+
+import (
+	"encoding/json"
+	"go/ast"
+	"runtime/debug"
+	"strings"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// Version is the version of toe recorded in the header of generated files:
+// the version of the module providing this package, as recorded in the
+// running binary, or "(devel)" when it isn't known, such as when toe is
+// built from a checkout. Programs embedding the generator may set it.
+var Version = moduleVersion()
+
+// moduleVersion returns the version of the module providing the generator
+// from the running binary's build information. When toe is built as the
+// main module, only release versions are used: the pseudo-versions, marked
+// +dirty for modified checkouts, that go build stamps from version control
+// differ from the "(devel)" of go run and from commit to commit, so the
+// same stub would be generated differently.
+func moduleVersion() string {
+	version := ""
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if info.Main.Path == modulePath && isRelease(info.Main.Version) {
+			version = info.Main.Version
+		}
+		for _, dep := range info.Deps {
+			if dep.Path == modulePath {
+				version = dep.Version
+				if dep.Replace != nil && dep.Replace.Version != "" {
+					version = dep.Replace.Version
+				}
+			}
+		}
+	}
+	if version == "" {
+		return "(devel)"
+	}
+	return version
+}
+
+// isRelease reports whether version is a release version, rather than a
+// pseudo-version or one with build metadata such as +dirty.
+func isRelease(version string) bool {
+	return semver.IsValid(version) && semver.Build(version) == "" && !module.IsPseudoVersion(version)
+}
+
+// headerOptions returns the options recorded in the header of the files
+// generated with opts, as JSON: those shaping the generated code, other
+// than the interface and style, which are recorded separately. Names of
+// files, which depend on where toe runs, are left out, as are line
+// endings, so that files differing only in those are otherwise the same.
+func headerOptions(opts Options) (string, error) {
+	opts.Interface = ""
+	opts.Style = ""
+	opts.EOL = ""
+	opts.Output = ""
+	opts.TemplateFile = ""
+	opts.PartialsDir = ""
+	opts.FuncsPlugin = ""
+	b, err := json.Marshal(opts)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// Header is the information recorded in the header of a file generated
+// from an interface, in lines such as:
+//
+//	//toe:style stub
+//	//toe:interface github.com/phildrip/toe/ref.Thinger
+//	//toe:hash 5f8dd1eebf87fa13
+//	//toe:version v1.2.0
+//	//toe:options {"packageName":"ref_stubs","withExample":true}
+type Header struct {
+	Style string
+	// Path is the import path of the interface's package, and Name the
+	// interface's name.
+	Path string
+	Name string
+	// Hash is the hash of the interface's method set when the file was
+	// generated; see model.Hash.
+	Hash string
+	// Version is the version of toe that generated the file, and Options
+	// the options it was generated with, other than the interface, style
+	// and names of files. Both are empty for files generated by versions
+	// of toe that didn't record them.
+	Version string
+	Options Options
+	// Aggregated are the interfaces after the first of an aggregate, which
+	// records each interface it embeds the stub of in its own
+	// //toe:interface and //toe:hash lines. Only their Path, Name and Hash
+	// are set.
+	Aggregated []Header
+}
+
+// Interfaces returns the interfaces recorded in h: the interface the file
+// was generated from, followed by the others of an aggregate.
+func (h Header) Interfaces() []Header {
+	return append([]Header{{Path: h.Path, Name: h.Name, Hash: h.Hash}}, h.Aggregated...)
+}
+
+// ParseHeader returns the header of file, which must have been parsed with
+// comments, if it was generated from an interface.
+func ParseHeader(file *ast.File) (Header, bool) {
+	var h Header
+	for _, group := range file.Comments {
+		if group.Pos() > file.Package {
+			break
+		}
+		for _, c := range group.List {
+			if style, ok := strings.CutPrefix(c.Text, "//toe:style "); ok {
+				h.Style = strings.TrimSpace(style)
+			}
+			if iface, ok := strings.CutPrefix(c.Text, "//toe:interface "); ok {
+				dot := strings.LastIndex(iface, ".")
+				if dot < 0 {
+					return Header{}, false
+				}
+				path, name := iface[:dot], strings.TrimSpace(iface[dot+1:])
+				if h.Name == "" {
+					h.Path, h.Name = path, name
+				} else {
+					h.Aggregated = append(h.Aggregated, Header{Path: path, Name: name})
+				}
+			}
+			if hash, ok := strings.CutPrefix(c.Text, "//toe:hash "); ok {
+				if n := len(h.Aggregated); n > 0 {
+					h.Aggregated[n-1].Hash = strings.TrimSpace(hash)
+				} else {
+					h.Hash = strings.TrimSpace(hash)
+				}
+			}
+			if version, ok := strings.CutPrefix(c.Text, "//toe:version "); ok {
+				h.Version = strings.TrimSpace(version)
+			}
+			if options, ok := strings.CutPrefix(c.Text, "//toe:options "); ok {
+				// Options that can't be read, such as those of a newer
+				// version of toe, are left out.
+				json.Unmarshal([]byte(options), &h.Options)
+			}
+		}
+	}
+	return h, h.Name != "" && h.Hash != ""
+}
@@ -0,0 +1,118 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/imports"
+)
+
+// The lines fencing a region of a generated file, such as helper methods
+// added to a stub, that Keep carries over when the file is regenerated.
+const (
+	KeepBegin = "// toe:keep"
+	KeepEnd   = "// toe:end"
+)
+
+// Keep returns generated, the regenerated content of the file name, with
+// the regions of its existing content fenced by KeepBegin and KeepEnd
+// lines appended verbatim, fences included. The imports of existing are
+// added to those of generated where the regions use them, and those they
+// use that neither imports are added as by goimports.
+func Keep(name string, existing, generated []byte) ([]byte, error) {
+	regions, err := keptRegions(existing)
+	if err != nil || len(regions) == 0 {
+		return generated, err
+	}
+
+	crlf := bytes.Contains(generated, []byte("\r\n"))
+	code := string(bytes.ReplaceAll(generated, []byte("\r\n"), []byte("\n")))
+	code = strings.TrimRight(code, "\n") + "\n\n" + strings.Join(regions, "\n")
+
+	code, err = addImports(code, existing)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := imports.Process(name, []byte(code), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error formatting kept regions of %s: %v", name, err)
+	}
+	if crlf {
+		merged = bytes.ReplaceAll(merged, []byte("\n"), []byte("\r\n"))
+	}
+	return merged, nil
+}
+
+// keptRegions returns the regions of content fenced by KeepBegin and
+// KeepEnd lines, each ending with a newline.
+func keptRegions(content []byte) ([]string, error) {
+	lines := strings.SplitAfter(string(bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))), "\n")
+	var regions []string
+	var region strings.Builder
+	begin := 0
+	for i, line := range lines {
+		switch strings.TrimSpace(line) {
+		case KeepBegin:
+			if begin > 0 {
+				return nil, fmt.Errorf("line %d: %s inside the region kept from line %d", i+1, KeepBegin, begin)
+			}
+			begin = i + 1
+		case KeepEnd:
+			if begin == 0 {
+				return nil, fmt.Errorf("line %d: %s outside a kept region", i+1, KeepEnd)
+			}
+			region.WriteString(strings.TrimSuffix(line, "\n") + "\n")
+			regions = append(regions, region.String())
+			region.Reset()
+			begin = 0
+			continue
+		}
+		if begin > 0 {
+			region.WriteString(line)
+		}
+	}
+	if begin > 0 {
+		return nil, fmt.Errorf("line %d: %s without %s", begin, KeepBegin, KeepEnd)
+	}
+	return regions, nil
+}
+
+// addImports adds the imports of existing to code, for imports.Process to
+// remove those that aren't used.
+func addImports(code string, existing []byte) (string, error) {
+	fset := token.NewFileSet()
+	old, err := parser.ParseFile(fset, "", existing, parser.ImportsOnly)
+	if err != nil {
+		// The existing file's imports are only a hint.
+		return code, nil
+	}
+	file, err := parser.ParseFile(fset, "", code, parser.ParseComments)
+	if err != nil {
+		return "", fmt.Errorf("error parsing kept regions: %v", err)
+	}
+	for _, spec := range old.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		var name string
+		if spec.Name != nil {
+			if spec.Name.Name == "_" || spec.Name.Name == "." {
+				continue
+			}
+			name = spec.Name.Name
+		}
+		astutil.AddNamedImport(fset, file, name, path)
+	}
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, file); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
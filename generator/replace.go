@@ -0,0 +1,205 @@
+package generator
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// typeReplacer replaces named types in the types of the generated code; see
+// Options.ReplaceTypes.
+type typeReplacer struct {
+	names    map[string]qualifiedName // replaced type to replacement
+	replaced map[string]*types.Named  // replaced type to replacement type
+}
+
+// qualifiedName is a type name qualified by its package's import path.
+type qualifiedName struct {
+	Path, Name string
+}
+
+func (n qualifiedName) String() string {
+	return n.Path + "." + n.Name
+}
+
+// parseQualifiedName parses a type name qualified by its package's import
+// path, such as "example.com/m/ids.ID".
+func parseQualifiedName(s string) (qualifiedName, error) {
+	i := strings.LastIndex(s, ".")
+	if i <= strings.LastIndex(s, "/") || !token.IsIdentifier(s[i+1:]) {
+		return qualifiedName{}, fmt.Errorf("invalid type %q: it must be qualified by its package's import path, such as example.com/m/ids.ID", s)
+	}
+	return qualifiedName{Path: s[:i], Name: s[i+1:]}, nil
+}
+
+// newTypeReplacer returns a typeReplacer for the replacements, from
+// replaced types to their replacements, or nil if there are none.
+func newTypeReplacer(replacements map[string]string) (*typeReplacer, error) {
+	if len(replacements) == 0 {
+		return nil, nil
+	}
+	r := &typeReplacer{
+		names:    make(map[string]qualifiedName),
+		replaced: make(map[string]*types.Named),
+	}
+	for from, to := range replacements {
+		fromName, err := parseQualifiedName(from)
+		if err != nil {
+			return nil, err
+		}
+		toName, err := parseQualifiedName(to)
+		if err != nil {
+			return nil, err
+		}
+		r.names[fromName.String()] = toName
+	}
+	return r, nil
+}
+
+// lookup returns the replacement of the type named obj with the underlying
+// type underlying, if it is replaced.
+func (r *typeReplacer) lookup(obj *types.TypeName, underlying types.Type) (*types.Named, bool) {
+	if obj.Pkg() == nil {
+		return nil, false
+	}
+	key := qualifiedName{Path: obj.Pkg().Path(), Name: obj.Name()}.String()
+	if named, ok := r.replaced[key]; ok {
+		return named, true
+	}
+	to, ok := r.names[key]
+	if !ok {
+		return nil, false
+	}
+	name := to.Path[strings.LastIndex(to.Path, "/")+1:]
+	pkg := types.NewPackage(to.Path, name)
+	// The replacement keeps the replaced type's underlying type, which
+	// examples and fuzz targets are generated from.
+	named := types.NewNamed(types.NewTypeName(token.NoPos, pkg, to.Name, nil), underlying, nil)
+	r.replaced[key] = named
+	return named, true
+}
+
+// replace returns typ with the types it refers to replaced.
+func (r *typeReplacer) replace(typ types.Type) (types.Type, error) {
+	if r == nil {
+		return typ, nil
+	}
+	switch t := typ.(type) {
+	case *types.Alias:
+		if named, ok := r.lookup(t.Obj(), t.Underlying()); ok {
+			return named, nil
+		}
+	case *types.Named:
+		if t.TypeArgs().Len() > 0 {
+			if _, ok := r.names[qualifiedName{Path: t.Obj().Pkg().Path(), Name: t.Obj().Name()}.String()]; ok {
+				return nil, fmt.Errorf("cannot replace %s: generic types can't be replaced", types.TypeString(t, nil))
+			}
+			args := make([]types.Type, t.TypeArgs().Len())
+			changed := false
+			for i := range args {
+				arg, err := r.replace(t.TypeArgs().At(i))
+				if err != nil {
+					return nil, err
+				}
+				args[i] = arg
+				changed = changed || arg != t.TypeArgs().At(i)
+			}
+			if !changed {
+				return t, nil
+			}
+			return types.Instantiate(nil, t.Origin(), args, false)
+		}
+		if named, ok := r.lookup(t.Obj(), t.Underlying()); ok {
+			return named, nil
+		}
+	case *types.Pointer:
+		elem, err := r.replace(t.Elem())
+		if err != nil || elem == t.Elem() {
+			return t, err
+		}
+		return types.NewPointer(elem), nil
+	case *types.Slice:
+		elem, err := r.replace(t.Elem())
+		if err != nil || elem == t.Elem() {
+			return t, err
+		}
+		return types.NewSlice(elem), nil
+	case *types.Array:
+		elem, err := r.replace(t.Elem())
+		if err != nil || elem == t.Elem() {
+			return t, err
+		}
+		return types.NewArray(elem, t.Len()), nil
+	case *types.Chan:
+		elem, err := r.replace(t.Elem())
+		if err != nil || elem == t.Elem() {
+			return t, err
+		}
+		return types.NewChan(t.Dir(), elem), nil
+	case *types.Map:
+		key, err := r.replace(t.Key())
+		if err != nil {
+			return nil, err
+		}
+		elem, err := r.replace(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		if key == t.Key() && elem == t.Elem() {
+			return t, nil
+		}
+		return types.NewMap(key, elem), nil
+	case *types.Signature:
+		params, err := r.replaceTuple(t.Params())
+		if err != nil {
+			return nil, err
+		}
+		results, err := r.replaceTuple(t.Results())
+		if err != nil {
+			return nil, err
+		}
+		if params == t.Params() && results == t.Results() {
+			return t, nil
+		}
+		return types.NewSignatureType(t.Recv(), nil, nil, params, results, t.Variadic()), nil
+	case *types.Struct:
+		fields := make([]*types.Var, t.NumFields())
+		tags := make([]string, t.NumFields())
+		changed := false
+		for i := range fields {
+			f := t.Field(i)
+			typ, err := r.replace(f.Type())
+			if err != nil {
+				return nil, err
+			}
+			fields[i] = types.NewField(f.Pos(), f.Pkg(), f.Name(), typ, f.Embedded())
+			tags[i] = t.Tag(i)
+			changed = changed || typ != f.Type()
+		}
+		if !changed {
+			return t, nil
+		}
+		return types.NewStruct(fields, tags), nil
+	}
+	return typ, nil
+}
+
+// replaceTuple returns tuple with the types of its variables replaced.
+func (r *typeReplacer) replaceTuple(tuple *types.Tuple) (*types.Tuple, error) {
+	vars := make([]*types.Var, tuple.Len())
+	changed := false
+	for i := range vars {
+		v := tuple.At(i)
+		typ, err := r.replace(v.Type())
+		if err != nil {
+			return nil, err
+		}
+		vars[i] = types.NewParam(v.Pos(), v.Pkg(), v.Name(), typ)
+		changed = changed || typ != v.Type()
+	}
+	if !changed {
+		return tuple, nil
+	}
+	return types.NewTuple(vars...), nil
+}
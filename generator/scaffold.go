@@ -0,0 +1,184 @@
+package generator
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+	"text/template"
+
+	"github.com/phildrip/toe/model"
+)
+
+// scaffoldData is the data the scaffold template is executed with.
+type scaffoldData struct {
+	PackageName string
+	Imports     []importData
+	Service     string
+	// DepsType is the name of the struct holding the stubbed
+	// dependencies.
+	DepsType string
+	Deps     []scaffoldDep
+	// Construct is an expression creating the service from the
+	// dependencies, held in d. When ConstructErr is true it also returns
+	// an error.
+	Construct    string
+	ConstructErr bool
+	Methods      []methodData
+	// Assertions is the library the tests check results with; see
+	// Options.Assertions.
+	Assertions string
+}
+
+type scaffoldDep struct {
+	// Name is the dependency's interface, and Var the field holding its
+	// stub.
+	Name string
+	Var  string
+}
+
+// Scaffold generates a test file for the exported methods of the type
+// service in m, with a table-driven test for each method. The service is
+// created with the stubs of the interfaces deps, which must be generated
+// separately into the package, passed to its New<service> constructor or
+// set in the matching fields. Only opts.Output, opts.DisableFormatting and
+// opts.Assertions are used.
+func Scaffold(m *Model, service string, deps []string, opts Options) ([]File, error) {
+	obj, ok := m.Types.Scope().Lookup(service).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("type %s not found", service)
+	}
+	if types.IsInterface(obj.Type()) {
+		return nil, model.Errorf(m.Fset.Position(obj.Pos()), "%s is an interface, not a service implementation", service)
+	}
+
+	imps := newImportSet(m.Types)
+	imps.reserve("testing", "reflect", "assert", "require", "cmp", "qt")
+	data := &scaffoldData{
+		PackageName: m.Name,
+		Service:     service,
+		DepsType:    strings.ToLower(service[:1]) + service[1:] + "Deps",
+		Assertions:  opts.Assertions,
+	}
+	switch data.Assertions {
+	case "":
+		data.Assertions = AssertionsStd
+	case AssertionsStd, AssertionsTestify, AssertionsCmp, AssertionsQuicktest:
+	default:
+		return nil, fmt.Errorf("unknown assertion library %q", data.Assertions)
+	}
+
+	depTypes := make(map[string]*scaffoldDep)
+	for _, name := range deps {
+		if _, err := m.Lookup(name); err != nil {
+			return nil, err
+		}
+		data.Deps = append(data.Deps, scaffoldDep{Name: name, Var: camel(name)})
+	}
+	for i := range data.Deps {
+		depTypes[data.Deps[i].Name] = &data.Deps[i]
+	}
+	// depFor returns the dependency of type typ, if any.
+	depFor := func(typ types.Type) *scaffoldDep {
+		if named, ok := typ.(*types.Named); ok && named.Obj().Pkg() == m.Types {
+			return depTypes[named.Obj().Name()]
+		}
+		return nil
+	}
+
+	var err error
+	data.Construct, data.ConstructErr, err = construct(m.Types, obj, depFor, imps)
+	if err != nil {
+		return nil, err
+	}
+
+	mset := types.NewMethodSet(types.NewPointer(obj.Type()))
+	for i := 0; i < mset.Len(); i++ {
+		fn := mset.At(i).Obj().(*types.Func)
+		if !fn.Exported() {
+			continue
+		}
+		method, err := newMethodData(&model.Method{Name: fn.Name(), Func: fn}, ArgNamingParam, imps, nil)
+		if err != nil {
+			return nil, err
+		}
+		data.Methods = append(data.Methods, method)
+	}
+	if len(data.Methods) == 0 {
+		return nil, model.Errorf(m.Fset.Position(obj.Pos()), "%s has no exported methods", service)
+	}
+	data.Imports = imps.list()
+
+	tmpl, err := template.New("scaffold").Funcs(builtinFuncs()).Parse(scaffoldTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing template: %v", err)
+	}
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("error generating test: %v", err)
+	}
+	code := buf.String()
+	if !opts.DisableFormatting {
+		code, err = formatCode(code)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return []File{{Name: opts.Output, Content: []byte(code)}}, nil
+}
+
+// construct returns an expression creating the service obj from the
+// dependencies in d: a call to its New<service> constructor if it has one,
+// or else a composite literal setting its fields. The bool reports
+// whether the constructor also returns an error.
+func construct(pkg *types.Package, obj *types.TypeName, depFor func(types.Type) *scaffoldDep,
+	imps *importSet) (string, bool, error) {
+	if ctor, ok := pkg.Scope().Lookup("New" + obj.Name()).(*types.Func); ok {
+		sig := ctor.Type().(*types.Signature)
+		var args []string
+		for i := 0; i < sig.Params().Len(); i++ {
+			param := sig.Params().At(i)
+			if dep := depFor(param.Type()); dep != nil {
+				args = append(args, "d."+dep.Var)
+			} else {
+				args = append(args, zeroValue(param.Type(), imps.qualifier))
+			}
+		}
+		returnsErr := sig.Results().Len() == 2 &&
+			types.Identical(sig.Results().At(1).Type(), types.Universe.Lookup("error").Type())
+		return ctor.Name() + "(" + strings.Join(args, ", ") + ")", returnsErr, nil
+	}
+
+	st, ok := obj.Type().Underlying().(*types.Struct)
+	if !ok {
+		return "", false, fmt.Errorf("%s has no New%s constructor and is not a struct", obj.Name(), obj.Name())
+	}
+	var fields []string
+	for i := 0; i < st.NumFields(); i++ {
+		if dep := depFor(st.Field(i).Type()); dep != nil {
+			fields = append(fields, st.Field(i).Name()+": d."+dep.Var)
+		}
+	}
+	return "&" + obj.Name() + "{" + strings.Join(fields, ", ") + "}", false, nil
+}
+
+// zeroValue returns an expression for the zero value of typ.
+func zeroValue(typ types.Type, qualifier types.Qualifier) string {
+	switch u := typ.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsBoolean != 0:
+			return "false"
+		case u.Info()&types.IsNumeric != 0:
+			return "0"
+		case u.Info()&types.IsString != 0:
+			return `""`
+		}
+	case *types.Pointer, *types.Signature, *types.Chan, *types.Interface, *types.Slice, *types.Map:
+		if _, ok := typ.(*types.TypeParam); !ok {
+			return "nil"
+		}
+	case *types.Struct, *types.Array:
+		return types.TypeString(typ, qualifier) + "{}"
+	}
+	return "*new(" + types.TypeString(typ, qualifier) + ")"
+}
@@ -0,0 +1,46 @@
+package doc
+
+// Doc has methods with the names of the stub's helpers.
+type Doc interface {
+	Clone() Doc
+	Verify(sig []byte) error
+	Sequence() int
+}
+
+// Switch has a method with the name of the stub's configurator for another.
+type Switch interface {
+	On() error
+	OnOn() error
+}
+
+// Source and Sink share their Close method.
+type Source interface {
+	Next() (string, error)
+	Close() error
+}
+
+type Sink interface {
+	Put(s string) error
+	Close() error
+}
+
+// Closer's Close differs from Source's.
+type Closer interface {
+	Close()
+}
+
+// Cache and Index are generic, sharing the type parameter K.
+type Cache[K comparable, V any] interface {
+	Get(key K) (V, bool)
+	Close() error
+}
+
+type Index[K comparable] interface {
+	Keys() []K
+	Close() error
+}
+
+// Set's K is constrained differently from Cache's.
+type Set[K any] interface {
+	Add(key K)
+}
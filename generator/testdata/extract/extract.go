@@ -0,0 +1,26 @@
+package extract
+
+import (
+	"context"
+	"time"
+)
+
+// Client is a concrete type to extract an interface from.
+type Client struct{}
+
+func (c *Client) Fetch(ctx context.Context, id string) ([]byte, error) { return nil, nil }
+func (c Client) Deadline() time.Time                                   { return time.Time{} }
+func (c *Client) reset()                                               {}
+
+// Box is a generic type to extract an interface from.
+type Box[K comparable, V any] struct {
+	items map[K]V
+}
+
+func (b *Box[K, V]) Get(key K) (V, bool) { v, ok := b.items[key]; return v, ok }
+func (b *Box[K, V]) Put(key K, value V)  { b.items[key] = value }
+
+// Empty has no exported methods.
+type Empty struct{}
+
+func (Empty) hidden() {}
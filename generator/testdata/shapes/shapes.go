@@ -0,0 +1,83 @@
+// Package shapes has interfaces with methods of the shapes generated code
+// meets, as the synthetic package of CheckTemplate does.
+package shapes
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Item is a type declared alongside the interfaces.
+type Item struct {
+	ID   int
+	Name string
+}
+
+// Headers is a named map type, and IDs a named slice type.
+type Headers map[string]string
+
+type IDs []int
+
+// Closer is embedded in Synthetic.
+type Closer interface {
+	Close() error
+}
+
+// Synthetic has methods of many shapes, and embeds interfaces.
+type Synthetic interface {
+	Closer
+	io.Reader
+	Get(ctx context.Context, id int) (*Item, error)
+	Put(ctx context.Context, items ...Item) error
+	List(ctx context.Context, filter func(Item) bool, limit int) (items []Item, next string, err error)
+	Search(ctx context.Context, query *Item) ([]Item, error)
+	Watch(ctx context.Context) (<-chan Item, error)
+	Send(ch chan<- Item, timeout time.Duration)
+	Lookup(ids map[string][]*Item) (map[string]Item, bool)
+	Annotate(headers Headers, ids IDs) Headers
+	Tag(context.Context, *Item, ...string) error
+	Swap(r0, r1 string) (string, string)
+	Logf(format string, args ...any)
+	Elapsed(start time.Time) (time.Duration, error)
+	Fetch(ctx context.Context, cacheKey string, v int, ok bool, r0 string, err error) (*Item, error)
+	Ping()
+}
+
+// Formatter takes an interface, whose values may hold pointers.
+type Formatter interface {
+	Format(s fmt.Stringer) (string, error)
+}
+
+// Generic is a generic interface.
+type Generic[K comparable, V any] interface {
+	Load(ctx context.Context, key K) (V, error)
+	Store(key K, values ...V) error
+	Keys() []K
+}
+
+// Document has methods with the names of the stubs' helpers.
+type Document interface {
+	Clone() Document
+	Verify(sig []byte) error
+	Sequence() int
+}
+
+// Fallible's methods all return an error, for the styles requiring it, and
+// have the names of the stubs' helpers.
+type Fallible interface {
+	Closer
+	Get(ctx context.Context, id int) (*Item, error)
+	Put(ctx context.Context, items ...Item) error
+	Clone() (Fallible, error)
+	Verify(sig []byte) error
+	Attempt(ctx context.Context, r0 int) (int, error)
+	Trip(generation uint64, err error) (int, error)
+}
+
+// FallibleGeneric is a generic interface whose methods all return an error.
+type FallibleGeneric[K comparable, V any] interface {
+	Load(ctx context.Context, key K) (V, error)
+	Store(key K, values ...V) error
+}
@@ -0,0 +1,39 @@
+// Package stream declares a client interface shaped like those generated
+// for gRPC services with streaming methods.
+package stream
+
+import "context"
+
+type Request struct{ ID string }
+
+type Response struct{ Value string }
+
+// ClientStream stands in for grpc.ClientStream.
+type ClientStream interface {
+	Context() context.Context
+	CloseSend() error
+}
+
+type Feed_ListClient interface {
+	Recv() (*Response, error)
+	ClientStream
+}
+
+type Feed_UploadClient interface {
+	Send(*Request) error
+	CloseAndRecv() (*Response, error)
+	ClientStream
+}
+
+type Feed_ChatClient interface {
+	Send(*Request) error
+	Recv() (*Response, error)
+	ClientStream
+}
+
+type FeedClient interface {
+	Get(ctx context.Context, in *Request) (*Response, error)
+	List(ctx context.Context, in *Request) (Feed_ListClient, error)
+	Upload(ctx context.Context) (Feed_UploadClient, error)
+	Chat(ctx context.Context) (Feed_ChatClient, error)
+}
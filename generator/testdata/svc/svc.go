@@ -0,0 +1,48 @@
+package svc
+
+import (
+	"context"
+	"time"
+)
+
+type Order struct {
+	ID    string
+	Total int
+}
+
+type Repo interface {
+	Save(ctx context.Context, o Order) error
+	Find(ctx context.Context, id string) (Order, error)
+}
+
+type Clock interface {
+	Now() time.Time
+}
+
+type OrderService struct {
+	repo  Repo
+	clock Clock
+}
+
+func NewOrderService(repo Repo, clock Clock) *OrderService {
+	return &OrderService{repo: repo, clock: clock}
+}
+
+func (s *OrderService) Place(ctx context.Context, o Order) (time.Time, error) {
+	if err := s.repo.Save(ctx, o); err != nil {
+		return time.Time{}, err
+	}
+	return s.clock.Now(), nil
+}
+
+func (s *OrderService) Lookup(ctx context.Context, ids ...string) ([]Order, error) {
+	var orders []Order
+	for _, id := range ids {
+		o, err := s.repo.Find(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, o)
+	}
+	return orders, nil
+}
@@ -1,10 +1,11 @@
-module toe
+module github.com/phildrip/toe
 
-go 1.19
-
-require golang.org/x/tools v0.23.0
+go 1.22.0
 
 require (
-	golang.org/x/mod v0.19.0 // indirect
-	golang.org/x/sync v0.7.0 // indirect
+	github.com/google/go-cmp v0.6.0
+	golang.org/x/mod v0.21.0
+	golang.org/x/tools v0.26.0
 )
+
+require golang.org/x/sync v0.8.0 // indirect
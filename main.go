@@ -1,293 +1,458 @@
 package main
 
 import (
-	_ "embed"
-	"encoding/json"
 	"flag"
 	"fmt"
-	"go/ast"
-	"go/format"
-	"go/parser"
-	"go/token"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 	"text/template"
 
-	"golang.org/x/tools/go/packages"
+	"github.com/phildrip/toe/generator"
+	"github.com/phildrip/toe/model"
 )
 
-//go:embed stub.go.tmpl
-var stubTemplate string
-
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "extract":
+			runExtract(os.Args[2:])
+			return
+		case "describe":
+			runDescribe(os.Args[2:])
+			return
+		case "deps":
+			runDeps(os.Args[2:])
+			return
+		case "diff":
+			runDiff(os.Args[2:])
+			return
+		case "stats":
+			runStats(os.Args[2:])
+			return
+		case "usage":
+			runUsage(os.Args[2:])
+			return
+		case "bazel":
+			runBazel(os.Args[2:])
+			return
+		case "serve":
+			runServe(os.Args[2:])
+			return
+		case "code-action":
+			runCodeAction(os.Args[2:])
+			return
+		case "scaffold-test":
+			runScaffold(os.Args[2:])
+			return
+		case "template":
+			runTemplate(os.Args[2:])
+			return
+		}
+	}
+
 	var outputFile string
 	var disableFormatting bool
+	var style string
+	var aggregateName string
+	var outputPackage string
+	var templateFile string
+	var partialsDir string
+	var templateModule string
+	var funcsPlugin string
+	var postCmd string
+	var configFile string
+	var extraImports stringList
+	defaults := make(typeMap)
+	replaceTypes := make(typeMap)
+	var splitHelpers bool
+	var withExample bool
+	var withRaceTest bool
+	var withFuzz bool
+	var methods string
+	var excludeMethods string
+	var errorUnconfigured bool
+	var callChannels bool
+	var embedInterface bool
+	var funcAdapter bool
+	var explain bool
+	var argNaming string
+	var module string
+	var eol string
+	var assertions string
+	var prune bool
+	var inputFile string
+	var inputDirs stringList
 	flag.BoolVar(&disableFormatting, "no-fmt", false, "disable formatting of the output")
+	flag.BoolVar(&splitHelpers, "split-helpers", false,
+		"generate the call records and expectation types into <output>_helpers.go")
+	flag.BoolVar(&withExample, "with-example", false,
+		"also generate an example test using the stub into <output>_example_test.go")
+	flag.BoolVar(&withRaceTest, "with-race-test", false,
+		"also generate a test calling the stub concurrently into <output>_race_test.go")
+	flag.BoolVar(&withFuzz, "with-fuzz", false,
+		"also generate a fuzz test calling the stub with fuzzed arguments into <output>_fuzz_test.go")
+	flag.StringVar(&methods, "methods", "",
+		"comma-separated patterns of the methods the stub implements; the others panic")
+	flag.StringVar(&excludeMethods, "exclude-methods", "",
+		"comma-separated patterns of methods the stub leaves out, panicking when they are called")
+	flag.BoolVar(&errorUnconfigured, "error-unconfigured", false,
+		"make stub methods return an error when called without a configured result")
+	flag.BoolVar(&callChannels, "call-channels", false,
+		"give the stub a <Method>CalledCh channel receiving each call to the method")
+	flag.BoolVar(&embedInterface, "embed-interface", false,
+		"embed the interface in the stub, so it still compiles once methods are added, which then panic")
+	flag.BoolVar(&funcAdapter, "func-adapter", false,
+		"also generate a <Interface>Func type adapting funcs to the interface, which must have a single method")
+	flag.BoolVar(&explain, "explain", false,
+		"print the interface's resolved method set, with source positions, to stderr before generating")
+	flag.StringVar(&argNaming, "arg-naming", "",
+		"argument naming scheme, param, arg or camel, overriding the config's")
+	flag.StringVar(&module, "module", "",
+		"module, as path@version, to load the input directory from, relative to its root, fetching it if needed")
+	flag.StringVar(&inputFile, "file", "",
+		"Go file to load the interfaces from on its own, instead of an input directory")
+	flag.Var(&inputDirs, "dir",
+		"input directory to look the interfaces up in, instead of the first argument; may be repeated")
+	flag.StringVar(&assertions, "assertions", "",
+		"library the stub's assertion helpers fail tests with: std (the default), testify, cmp or quicktest")
+	flag.StringVar(&eol, "eol", "",
+		"line ending of the generated files: lf (the default) or crlf")
+	flag.BoolVar(&prune, "prune", false,
+		"in batch mode, delete files generated by earlier runs whose interface no longer exists")
+	flag.StringVar(&style, "style", "stub",
+		"kind of code to generate: "+strings.Join(generator.Styles(), ", "))
 
 	flag.StringVar(&outputFile, "o", "", "output file name")
-	flag.Parse()
-
-	if flag.NArg() != 2 {
-		fmt.Fprintf(os.Stderr,
-			"Usage: %s [-no-fmt] -o <output.go> <input_directory> <interface>\n",
-			os.Args[0])
-
-		os.Exit(1)
+	flag.StringVar(&configFile, "config", "", "JSON file of generation settings")
+	flag.StringVar(&templateFile, "template", "",
+		"template file to generate the code with, instead of the style's built-in template")
+	flag.StringVar(&partialsDir, "template-dir", "",
+		"directory of partial templates overriding parts of the template")
+	flag.StringVar(&templateModule, "template-module", "",
+		"module, as path@version, providing the templates, fetching it if needed; -template and -template-dir are relative to its root")
+	flag.Var(&extraImports, "import",
+		"import added to the generated code, as path or name=path; may be repeated")
+	flag.Var(defaults, "default",
+		"expression stub methods return for results of a type when unconfigured, as type=expr; may be repeated")
+	flag.Var(replaceTypes, "replace-type",
+		"type replacing another in the generated code, as path.Type=path.Type; may be repeated")
+	flag.StringVar(&funcsPlugin, "funcs", "",
+		"Go plugin adding functions to those available to templates")
+	flag.StringVar(&outputPackage, "pkg", "",
+		"package name of the generated code (default the interface's package)")
+	flag.StringVar(&postCmd, "post-cmd", "",
+		"command run with sh after the output file is written; {{.Output}} expands to its name")
+	flag.StringVar(&aggregateName, "aggregate", "",
+		"generate Stub<name> embedding the stubs of several interfaces")
+	addBuildFlags(flag.CommandLine)
+	args := parseInterspersed(flag.CommandLine, os.Args[1:])
+
+	if aggregateName != "" {
+		runAggregate(aggregateName, args, outputFile, outputPackage, postCmd, disableFormatting, eol)
+		return
 	}
 
-	inputDir := flag.Arg(0)
-	interfaceName := flag.Arg(1)
+	if len(args) == 0 && configFile != "" {
+		cfg, err := loadConfig(configFile)
+		if err != nil {
+			fatal("loading config", err)
+		}
+		if len(cfg.Stubs) > 0 {
+			runBatch(cfg, filepath.Dir(configFile), postCmd, prune)
+			return
+		}
+	}
 
-	interfaceMethods, packageName, err := findInterface(inputDir, interfaceName)
+	named := inputFile != "" || len(inputDirs) > 0
+	if len(args) < 2 && (!named || len(args) < 1) {
+		fmt.Fprintf(os.Stderr,
+			"Usage: %s [-no-fmt] [-style <style>] [-template <file>] -o <output.go> <input_directory> <interface>...\n"+
+				"       %s [flags] -dir <input_directory>... <interface>...\n"+
+				"       %s [flags] -file <input.go> <interface>...\n",
+			os.Args[0], os.Args[0], os.Args[0])
 
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error finding interface: %v\n", err)
 		os.Exit(1)
 	}
 
-	if len(interfaceMethods) == 0 {
-		fmt.Fprintf(os.Stderr, "Interface %s not found\n", interfaceName)
-		os.Exit(1)
+	interfaceNames := args
+	switch {
+	case inputFile != "" && (module != "" || len(inputDirs) > 0):
+		fatal("finding interface", fmt.Errorf("-file can't be used with -module or -dir"))
+	case !named:
+		inputDirs, interfaceNames = stringList{args[0]}, args[1:]
 	}
-
-	stubCode, err := generateStubCode(interfaceName,
-		interfaceMethods,
-		packageName,
-		disableFormatting)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error generating stub: %v\n", err)
-		os.Exit(1)
+	if len(interfaceNames) > 1 && !strings.Contains(outputFile, "{{") {
+		fatal("generating stubs", fmt.Errorf("-o must be a template, such as {{.Interface | lower}}_stub.go, "+
+			"to generate code for several interfaces"))
 	}
 
-	if outputFile == "" {
-		fmt.Println(stubCode)
-	} else {
-		err := os.WriteFile(outputFile, []byte(stubCode), 0644)
+	var cfg config
+	if configFile != "" {
+		loaded, err := loadConfig(configFile)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error writing output file: %v\n", err)
-			os.Exit(1)
+			fatal("loading config", err)
 		}
-		fmt.Printf("Stub generated in %s\n", outputFile)
+		cfg = *loaded
 	}
-}
 
-func findInterface(inputDir string, interfaceName string) ([]*ast.Field, string, error) {
-	cfg := &packages.Config{
-		Mode: packages.NeedName |
-			packages.NeedFiles |
-			packages.NeedSyntax |
-			packages.NeedTypes |
-			packages.NeedTypesInfo,
-		Dir: inputDir,
+	var loaded []*generator.Model
+	if inputFile != "" {
+		model, err := generator.LoadFile(inputFile)
+		if err != nil {
+			fatal("finding interface", err)
+		}
+		loaded = append(loaded, model)
 	}
-	pkgs, err := packages.Load(cfg, ".")
+	for _, inputDir := range inputDirs {
+		var model *generator.Model
+		var err error
+		if module != "" {
+			model, err = generator.LoadFromModule(module, inputDir)
+		} else {
+			model, err = generator.Load(inputDir)
+		}
+		if err != nil {
+			fatal("finding interface", err)
+		}
+		loaded = append(loaded, model)
+	}
+	models, err := modelsByInterface(loaded, interfaceNames)
 	if err != nil {
-		return nil, "", fmt.Errorf("load: %v", err)
+		fatal("finding interface", err)
+	}
+
+	if argNaming != "" {
+		cfg.ArgNaming = argNaming
+	}
+	if assertions != "" {
+		cfg.Assertions = assertions
+	}
+	opts := generator.Options{
+		Style:             style,
+		Output:            outputFile,
+		PackageName:       outputPackage,
+		TemplateFile:      templateFile,
+		PartialsDir:       partialsDir,
+		TemplateModule:    templateModule,
+		FuncsPlugin:       funcsPlugin,
+		ArgNaming:         cfg.ArgNaming,
+		Imports:           append(cfg.Imports, extraImports...),
+		Defaults:          mergeTypeMaps(cfg.Defaults, defaults),
+		ReplaceTypes:      mergeTypeMaps(cfg.ReplaceTypes, replaceTypes),
+		Methods:           splitList(methods),
+		ExcludeMethods:    splitList(excludeMethods),
+		ErrorUnconfigured: errorUnconfigured,
+		CallChannels:      callChannels,
+		EmbedInterface:    embedInterface,
+		FuncAdapter:       funcAdapter,
+		SplitHelpers:      splitHelpers,
+		WithExample:       withExample,
+		WithRaceTest:      withRaceTest,
+		WithFuzz:          withFuzz,
+		DisableFormatting: disableFormatting,
+		EOL:               eol,
+		Assertions:        cfg.Assertions,
 	}
-	if packages.PrintErrors(pkgs) > 0 {
-		return nil, "", fmt.Errorf("packages contain errors")
+	// A templated -o may give several interfaces the same file.
+	outputs := make(map[string]string)
+	for _, interfaceName := range interfaceNames {
+		opts.Interface = interfaceName
+		names, _, err := generator.OutputFiles(models[interfaceName], opts)
+		if err != nil {
+			fatal("generating stub", err)
+		}
+		for _, name := range names {
+			if other, ok := outputs[name]; ok {
+				fatal("generating stubs", fmt.Errorf("%s and %s both write %s", other, interfaceName, name))
+			}
+			outputs[name] = interfaceName
+		}
 	}
 
-	var interfaceMethods []*ast.Field
-	var packageName string
+	for _, interfaceName := range interfaceNames {
+		opts.Interface = interfaceName
+		if explain {
+			explanation, err := generator.Explain(models[interfaceName], opts)
+			if err != nil {
+				fatal("explaining interface", err)
+			}
+			fmt.Fprint(os.Stderr, explanation)
+		}
 
-	for _, pkg := range pkgs {
-		packageName = pkg.Name
-		for _, file := range pkg.Syntax {
-			ast.Inspect(
-				file, func(n ast.Node) bool {
-					if ts, ok := n.(*ast.TypeSpec); ok && ts.Name.Name == interfaceName {
-						if ift, ok := ts.Type.(*ast.InterfaceType); ok {
-							interfaceMethods = ift.Methods.List
-						}
-					}
-					return true
-				})
+		files, err := generator.Generate(models[interfaceName], opts)
+		if err != nil {
+			fatal("generating stub", err)
 		}
+		writeFiles(files, postCmd)
 	}
-	return interfaceMethods, packageName, nil
-}
-
-type methodData struct {
-	Name        string
-	Params      []string
-	ParamNames  []string
-	Results     []string
-	ResultNames []string
 }
 
-func zip(a []string, b []string, fmtStr string) []string {
-	if len(a) != len(b) {
-		panic("unequal length")
-	}
-	var zipped []string
-	for i := range a {
-		zipped = append(zipped, fmt.Sprintf(fmtStr, a[i], b[i]))
+// modelsByInterface returns the package among loaded declaring each of the
+// named interfaces. With a single package, the interfaces are looked up in
+// it when generating, which reports those it doesn't declare.
+func modelsByInterface(loaded []*generator.Model, names []string) (map[string]*generator.Model, error) {
+	models := make(map[string]*generator.Model)
+	for _, name := range names {
+		if len(loaded) == 1 {
+			models[name] = loaded[0]
+			continue
+		}
+		for _, model := range loaded {
+			if _, err := model.Lookup(name); err != nil {
+				continue
+			}
+			if other, ok := models[name]; ok {
+				return nil, fmt.Errorf("interface %s is declared in both %s and %s", name, other.Path, model.Path)
+			}
+			models[name] = model
+		}
+		if _, ok := models[name]; !ok {
+			return nil, fmt.Errorf("interface %s not found in any of the input directories", name)
+		}
 	}
-	return zipped
+	return models, nil
 }
 
-// joinl joins a list of strings with a separator, with arguments reversed
-// compared to strings.Join.
-func joinl(sep string, a []string) string {
-	return strings.Join(a, sep)
+// addBuildFlags adds the -mod and -overlay flags to fs, which are passed to
+// the go command when loading packages, as to go build, and the -lang flag
+// setting the language version packages are type-checked against.
+func addBuildFlags(fs *flag.FlagSet) {
+	fs.Func("mod", "module download mode passed to the go command: readonly, vendor or mod",
+		func(mode string) error {
+			model.BuildFlags = append(model.BuildFlags, "-mod="+mode)
+			return nil
+		})
+	fs.Func("overlay", "JSON file replacing source files, passed to the go command",
+		func(file string) error {
+			// Packages are loaded from other directories.
+			abs, err := filepath.Abs(file)
+			if err != nil {
+				return err
+			}
+			model.BuildFlags = append(model.BuildFlags, "-overlay="+abs)
+			return nil
+		})
+	fs.StringVar(&model.Lang, "lang", "",
+		"Go language version, such as go1.21, to type-check the input package against")
+	fs.IntVar(&model.LoadRetries, "load-retries", model.LoadRetries,
+		"times to retry loading packages when downloading modules fails with a network error")
+	fs.BoolVar(&model.Tests, "tests", false,
+		"load the package's _test.go files too, to generate code for interfaces declared in them")
 }
 
-func generateStubCode(interfaceName string,
-	methods []*ast.Field,
-	packageName string,
-	disableFormatting bool) (string, error) {
-	stubName := "Stub" + interfaceName
-
-	funcMap := template.FuncMap{
-		"join":  strings.Join,
-		"zip":   zip,
-		"joinl": joinl,
+// splitList splits a comma-separated flag value, returning nil for an
+// empty one.
+func splitList(s string) []string {
+	if s == "" {
+		return nil
 	}
+	return strings.Split(s, ",")
+}
 
-	tmpl := template.Must(
-		template.New("stub").
-			Funcs(funcMap).
-			Parse(stubTemplate))
-
-	var methodsData []methodData
-
-	for _, method := range methods {
-		if len(method.Names) == 0 {
-			continue
-		}
-		methodName := method.Names[0].Name
-		funcType := method.Type.(*ast.FuncType)
-
-		params := getFieldList(funcType.Params)
-		paramNames := getFieldNames(funcType.Params)
-		results := getFieldList(funcType.Results)
-		resultNames := getResultNames(funcType.Results)
-
-		methodsData = append(
-			methodsData, methodData{
-				Name:        methodName,
-				Params:      params,
-				ParamNames:  paramNames,
-				Results:     results,
-				ResultNames: resultNames,
-			})
+// runAggregate generates a stub embedding the stubs of several interfaces.
+// args are the input directory followed by the interface names.
+func runAggregate(name string, args []string, outputFile string, outputPackage string, postCmd string, disableFormatting bool, eol string) {
+	if len(args) < 3 {
+		fmt.Fprintf(os.Stderr,
+			"Usage: %s -aggregate <name> -o <output.go> <input_directory> <interface> <interface>...\n",
+			os.Args[0])
+		os.Exit(1)
 	}
 
-	var buf strings.Builder
-	fmt.Println(prettyPrint(methodsData))
-	err := tmpl.Execute(
-		&buf, struct {
-			PackageName   string
-			InterfaceName string
-			StubName      string
-			Methods       []methodData
-		}{
-			PackageName:   packageName,
-			InterfaceName: interfaceName,
-			StubName:      stubName,
-			Methods:       methodsData,
-		})
+	inputDir := args[0]
+	interfaceNames := args[1:]
 
+	model, err := generator.Load(inputDir)
 	if err != nil {
-		return "", fmt.Errorf("error generating stub: %v", err)
+		fatal("finding interface", err)
 	}
 
-	if !disableFormatting {
-		// Format the generated code
-		fset := token.NewFileSet()
-		node, err := parser.ParseFile(fset, "", buf.String(), parser.ParseComments)
-		if err != nil {
-			return "", fmt.Errorf("error parsing generated code: %v", err)
-		}
+	files, err := generator.GenerateAggregate(model, name, interfaceNames, generator.Options{
+		Output:            outputFile,
+		PackageName:       outputPackage,
+		DisableFormatting: disableFormatting,
+		EOL:               eol,
+	})
+	if err != nil {
+		fatal("generating stub", err)
+	}
+	writeFiles(files, postCmd)
+}
 
-		var formattedBuf strings.Builder
-		err = format.Node(&formattedBuf, fset, node)
+// writeFiles writes each of files with writeOutput, keeping the regions of
+// the files they replace fenced with // toe:keep.
+func writeFiles(files []generator.File, postCmd string) {
+	for _, file := range files {
+		content, err := keepRegions(file.Name, file.Content)
 		if err != nil {
-			return "", fmt.Errorf("error formatting generated code: %v", err)
+			fatal("writing output file", err)
 		}
-
-		return formattedBuf.String(), nil
-	} else {
-		return buf.String(), nil
+		writeOutput(file.Name, string(content), postCmd)
 	}
 }
 
-func prettyPrint(i interface{}) string {
-	s, _ := json.MarshalIndent(i, "", "\t")
-	return string(s)
-}
-
-func getFieldList(fields *ast.FieldList) []string {
-	if fields == nil {
-		return nil
+// keepRegions returns content, the new content of the file at path, with
+// the regions of the existing file kept; see generator.Keep.
+func keepRegions(path string, content []byte) ([]byte, error) {
+	if path == "" {
+		return content, nil
 	}
-	var params []string
-	for _, field := range fields.List {
-		paramType := getTypeString(field.Type)
-		if len(field.Names) > 0 {
-			for _, name := range field.Names {
-				params = append(params, fmt.Sprintf("%s %s", name.Name, paramType))
-			}
-		} else {
-			params = append(params, paramType)
-		}
+	existing, err := os.ReadFile(path)
+	if err != nil {
+		// There is nothing to keep in a new file.
+		return content, nil
 	}
-	return params
+	return generator.Keep(path, existing, content)
 }
 
-func getFieldNames(fields *ast.FieldList) []string {
-	if fields == nil {
-		return nil
+// writeOutput writes code to outputFile, or to stdout if outputFile is
+// empty, then runs postCmd on it.
+func writeOutput(outputFile string, code string, postCmd string) {
+	if outputFile == "" {
+		if postCmd != "" {
+			fmt.Fprintf(os.Stderr, "-post-cmd requires an output file\n")
+			os.Exit(1)
+		}
+		fmt.Println(code)
+	} else {
+		// The directory may come from a templated -o.
+		if err := os.MkdirAll(filepath.Dir(outputFile), 0755); err != nil {
+			fatal("writing output file", err)
+		}
+		err := os.WriteFile(outputFile, []byte(code), 0644)
+		if err != nil {
+			fatal("writing output file", err)
+		}
+		fmt.Printf("Generated %s\n", outputFile)
 	}
-	var names []string
-	for _, field := range fields.List {
-		if len(field.Names) > 0 {
-			for _, name := range field.Names {
-				names = append(names, name.Name)
-			}
-		} else {
-			names = append(names, "_")
+
+	if postCmd != "" {
+		if err := runPostCmd(postCmd, outputFile); err != nil {
+			fatal("running post-generation command", err)
 		}
 	}
-	return names
 }
 
-func getResultNames(fields *ast.FieldList) []string {
-	if fields == nil {
-		return nil
+// runPostCmd runs the shell command cmdTemplate, a template in which
+// {{.Output}} expands to the name of the generated file, quoted for the
+// shell.
+func runPostCmd(cmdTemplate string, outputFile string) error {
+	tmpl, err := template.New("post-cmd").Parse(cmdTemplate)
+	if err != nil {
+		return err
 	}
-	var names []string
-	for i, field := range fields.List {
-		if len(field.Names) > 0 {
-			for _, name := range field.Names {
-				names = append(names, name.Name)
-			}
-		} else {
-			names = append(names, fmt.Sprintf("R%d", i))
-		}
+	var cmdLine strings.Builder
+	if err := tmpl.Execute(&cmdLine, struct{ Output string }{quoteWord(outputFile, "")}); err != nil {
+		return err
 	}
-	return names
-}
 
-func getTypeString(expr ast.Expr) string {
-	switch t := expr.(type) {
-	case *ast.Ident:
-		return t.Name
-	case *ast.SelectorExpr:
-		return fmt.Sprintf("%s.%s", getTypeString(t.X), t.Sel.Name)
-	case *ast.StarExpr:
-		return "*" + getTypeString(t.X)
-	case *ast.ArrayType:
-		return "[]" + getTypeString(t.Elt)
-	case *ast.MapType:
-		return fmt.Sprintf("map[%s]%s", getTypeString(t.Key), getTypeString(t.Value))
-	case *ast.InterfaceType:
-		return "interface{}"
-	case *ast.FuncType:
-		return "func(" + strings.Join(getFieldList(t.Params), ", "+
-			"") + ") " + strings.Join(getFieldList(t.Results), ", ")
-	default:
-		return fmt.Sprintf("%T", expr)
+	cmd := exec.Command("sh", "-c", cmdLine.String())
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %v", cmdLine.String(), err)
 	}
+	return nil
 }
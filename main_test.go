@@ -0,0 +1,515 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/phildrip/toe/generator"
+	"github.com/phildrip/toe/model"
+)
+
+func TestRunPostCmd(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "stub_thinger.go")
+	if err := os.WriteFile(output, []byte("package ref\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runPostCmd("cp {{.Output}} {{.Output}}.bak", output); err != nil {
+		t.Fatalf("expected %v, got %v", nil, err)
+	}
+	if b, err := os.ReadFile(output + ".bak"); err != nil || string(b) != "package ref\n" {
+		t.Errorf("expected %q, got %q (%v)", "package ref\n", b, err)
+	}
+
+	// The name is quoted, so the shell neither splits it nor runs what
+	// follows the semicolon.
+	dir := filepath.Join(t.TempDir(), "my stubs")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	output = filepath.Join(dir, "stub_thinger;exit 7's.go")
+	if err := os.WriteFile(output, []byte("package ref\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := runPostCmd("cp {{.Output}} {{.Output}}.bak", output); err != nil {
+		t.Fatalf("expected %v, got %v", nil, err)
+	}
+	if b, err := os.ReadFile(output + ".bak"); err != nil || string(b) != "package ref\n" {
+		t.Errorf("expected %q, got %q (%v)", "package ref\n", b, err)
+	}
+
+	err := runPostCmd("exit 3", output)
+	if err == nil || !strings.HasPrefix(err.Error(), "exit 3: ") {
+		t.Errorf("expected %v, got %v", "exit 3: exit status 3", err)
+	}
+	if err := runPostCmd("{{.Missing}}", output); err == nil {
+		t.Errorf("expected an error executing the template")
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    *config
+		err     string
+	}{
+		{
+			name: "stubs",
+			content: `{
+				"argNaming": "camel",
+				"imports": ["uuid=github.com/google/uuid"],
+				"defaults": {"uuid.UUID": "uuid.Nil"},
+				"manifest": "gen.json",
+				"stubs": [
+					{"dir": "ref", "interface": "Thinger", "output": "stub_thinger.go"},
+					{"dir": "ref", "interface": "Thinger", "output": "retry_thinger.go", "style": "retry", "argNaming": "arg"}
+				]
+			}`,
+			want: &config{
+				ArgNaming: "camel",
+				Imports:   []string{"uuid=github.com/google/uuid"},
+				Defaults:  map[string]string{"uuid.UUID": "uuid.Nil"},
+				Manifest:  "gen.json",
+				Stubs: []stubConfig{
+					{Dir: "ref", Options: generator.Options{Interface: "Thinger", Output: "stub_thinger.go"}},
+					{Dir: "ref", Options: generator.Options{Interface: "Thinger", Output: "retry_thinger.go", Style: "retry", ArgNaming: "arg"}},
+				},
+			},
+		},
+		{name: "empty", content: `{}`, want: &config{}},
+		{name: "invalid", content: `{"stubs": {}}`, err: "error parsing config"},
+		{name: "missing", err: "error reading config"},
+	}
+	for _, test := range tests {
+		path := filepath.Join(t.TempDir(), "toe.json")
+		if test.content != "" {
+			if err := os.WriteFile(path, []byte(test.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		cfg, err := loadConfig(path)
+		if test.err != "" {
+			if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+				t.Errorf("%s: expected %v, got %v", test.name, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: expected %v, got %v", test.name, nil, err)
+		}
+		if diff := cmp.Diff(test.want, cfg, cmp.AllowUnexported(config{})); diff != "" {
+			t.Errorf("%s: expected %v, got %v: %s", test.name, test.want, cfg, diff)
+		}
+	}
+}
+
+func TestStubOptions(t *testing.T) {
+	cfg := &config{
+		ArgNaming:    "camel",
+		Assertions:   "testify",
+		Imports:      []string{"uuid=github.com/google/uuid"},
+		Defaults:     map[string]string{"uuid.UUID": "uuid.Nil", "time.Duration": "time.Second"},
+		ReplaceTypes: map[string]string{"example.com/a.T": "example.com/b.T"},
+	}
+	tests := []struct {
+		stub generator.Options
+		want generator.Options
+	}{
+		{
+			stub: generator.Options{Interface: "Thinger"},
+			want: generator.Options{
+				Interface:    "Thinger",
+				ArgNaming:    "camel",
+				Assertions:   "testify",
+				Imports:      []string{"uuid=github.com/google/uuid"},
+				Defaults:     map[string]string{"uuid.UUID": "uuid.Nil", "time.Duration": "time.Second"},
+				ReplaceTypes: map[string]string{"example.com/a.T": "example.com/b.T"},
+			},
+		},
+		{
+			stub: generator.Options{
+				Interface:  "Thinger",
+				ArgNaming:  "arg",
+				Assertions: "cmp",
+				Imports:    []string{"strings"},
+				Defaults:   map[string]string{"time.Duration": "time.Minute"},
+			},
+			want: generator.Options{
+				Interface:    "Thinger",
+				ArgNaming:    "arg",
+				Assertions:   "cmp",
+				Imports:      []string{"uuid=github.com/google/uuid", "strings"},
+				Defaults:     map[string]string{"uuid.UUID": "uuid.Nil", "time.Duration": "time.Minute"},
+				ReplaceTypes: map[string]string{"example.com/a.T": "example.com/b.T"},
+			},
+		},
+	}
+	for _, test := range tests {
+		got := stubOptions(stubConfig{Dir: "ref", Options: test.stub}, cfg)
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("expected %v, got %v: %s", test.want, got, diff)
+		}
+	}
+}
+
+func TestTypeMap(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+		err   bool
+	}{
+		{value: "uuid.UUID=uuid.Nil", want: "uuid.UUID=uuid.Nil"},
+		{value: "a=b=c", want: "a=b=c"},
+		{value: "uuid.UUID", err: true},
+		{value: "=uuid.Nil", err: true},
+		{value: "uuid.UUID=", err: true},
+	}
+	for _, test := range tests {
+		m := make(typeMap)
+		err := m.Set(test.value)
+		if (err != nil) != test.err {
+			t.Errorf("%s: expected %v, got %v", test.value, test.err, err)
+		}
+		if err == nil && m.String() != test.want {
+			t.Errorf("%s: expected %v, got %v", test.value, test.want, m.String())
+		}
+	}
+}
+
+func TestCheckOutputs(t *testing.T) {
+	model, err := generator.Load("ref")
+	if err != nil {
+		t.Fatal(err)
+	}
+	models := map[string]*generator.Model{"ref": model}
+
+	stub := func(dir, style, output string) stubConfig {
+		return stubConfig{Dir: dir, Options: generator.Options{Interface: "Thinger", Style: style, Output: output}}
+	}
+	split := stub("ref", "", "a.go")
+	split.SplitHelpers = true
+	tests := []struct {
+		name    string
+		stubs   []stubConfig
+		outputs []string
+		err     string
+	}{
+		{
+			name:    "distinct",
+			stubs:   []stubConfig{stub("ref", "", "a.go"), stub("ref", "retry", "b.go"), stub("ref", "", "sub/a.go")},
+			outputs: []string{"ref/a.go", "ref/b.go", "ref/sub/a.go"},
+		},
+		{
+			name:    "same file",
+			stubs:   []stubConfig{stub("ref", "", "a.go"), stub("ref", "noop", "a.go")},
+			outputs: []string{"ref/a.go"},
+			err:     "Thinger in ref (output a.go) and Thinger in ref (output a.go) both write ref/a.go",
+		},
+		{
+			name:    "same type",
+			stubs:   []stubConfig{stub("ref", "", "a.go"), stub("ref", "", "b.go")},
+			outputs: []string{"ref/a.go", "ref/b.go"},
+			err:     "Thinger in ref (output a.go) and Thinger in ref (output b.go) both declare StubThinger in ref",
+		},
+		{
+			name:    "split helpers",
+			stubs:   []stubConfig{split, stub("ref", "noop", "a_helpers.go")},
+			outputs: []string{"ref/a.go", "ref/a_helpers.go"},
+			err:     "Thinger in ref (output a.go) and Thinger in ref (output a_helpers.go) both write ref/a_helpers.go",
+		},
+		{
+			name:    "unloaded package",
+			stubs:   []stubConfig{stub("gone", "", "a.go"), stub("gone", "", "b.go")},
+			outputs: []string{"gone/a.go", "gone/b.go"},
+		},
+	}
+	for _, test := range tests {
+		outputs, err := checkOutputs(&config{Stubs: test.stubs}, ".", models)
+		if test.err == "" && err != nil || test.err != "" && (err == nil || err.Error() != test.err) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.err, err)
+		}
+		var got []string
+		for path := range outputs {
+			got = append(got, filepath.ToSlash(path))
+		}
+		sort.Strings(got)
+		if fmt.Sprint(got) != fmt.Sprint(test.outputs) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.outputs, got)
+		}
+	}
+}
+
+func TestManifest(t *testing.T) {
+	model, err := generator.Load("ref")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, defaultManifest)
+
+	m, err := readManifest(path)
+	if err != nil || len(m.Stubs) != 0 {
+		t.Errorf("expected %v, got %v (%v)", manifest{}, m, err)
+	}
+
+	stub := stubConfig{Dir: "ref", Options: generator.Options{Interface: "Thinger", Output: "stub_thinger.go"}}
+	files := []generator.File{{Name: "stub_thinger.go", Content: []byte("package ref\n")}}
+	want := manifest{Stubs: []manifestEntry{newManifestEntry(model, dir, filepath.Join(dir, "ref"), stub, files)}}
+	entry := manifestEntry{
+		Interface: "github.com/phildrip/toe/ref.Thinger",
+		Style:     "stub",
+		Files: []manifestFile{{
+			Path:   "ref/stub_thinger.go",
+			SHA256: "134035d2203d04fdd6a2aebe3c5bff17f0ebdcb869f581f3aefcd084c15f60b2",
+		}},
+	}
+	if diff := cmp.Diff(entry, want.Stubs[0]); diff != "" {
+		t.Errorf("expected %v, got %v: %s", entry, want.Stubs[0], diff)
+	}
+
+	if err := writeManifest(path, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("expected %v, got %v: %s", want, got, diff)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"stubs": {}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readManifest(path); err == nil || !strings.HasPrefix(err.Error(), "error parsing manifest") {
+		t.Errorf("expected %v, got %v", "error parsing manifest", err)
+	}
+}
+
+func TestFindOrphans(t *testing.T) {
+	model, err := generator.Load("ref")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := generator.Generate(model, generator.Options{Interface: "Thinger", Style: "noop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := string(files[0].Content)
+
+	// The files are outside the module, but are recorded relative to the
+	// config file in it, whose module the interfaces are looked up in.
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents := map[string]string{
+		"current.go":   code,
+		"generated.go": code,
+		"gone.go":      strings.Replace(code, "ref.Thinger", "ref.Gone", 1),
+		"gonepkg.go":   strings.Replace(code, "toe/ref.Thinger", "toe/gone.Thinger", 1),
+		"replaced.go":  "package ref\n",
+	}
+	var previous manifest
+	for _, name := range []string{"current.go", "generated.go", "gone.go", "gonepkg.go", "replaced.go", "deleted.go"} {
+		path := filepath.Join(dir, name)
+		if content, ok := contents[name]; ok {
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		rel, err := filepath.Rel(wd, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		previous.Stubs = append(previous.Stubs, manifestEntry{
+			Interface: "github.com/phildrip/toe/ref.Thinger",
+			Style:     "noop",
+			Files:     []manifestFile{{Path: filepath.ToSlash(rel)}},
+		})
+	}
+	outputs := map[string]bool{filepath.Join(dir, "generated.go"): true}
+
+	var got []string
+	for _, o := range findOrphans(previous, outputs, ".") {
+		got = append(got, filepath.Base(o.path))
+	}
+	if want := []string{"gone.go", "gonepkg.go"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestCollectStats(t *testing.T) {
+	s, err := collectStats("ref")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Stale) != 0 {
+		t.Errorf("expected %v, got %v", "no stale files", s.Stale)
+	}
+	// ref wraps Thinger in a retry and a breaker, and ref/results wraps
+	// Fetcher in a retry.
+	if s.Styles["retry"] != 2 || s.Styles["breaker"] != 1 {
+		t.Errorf("expected %v, got %v", "two retries and a breaker", s.Styles)
+	}
+	files := 0
+	for _, pkg := range s.Packages {
+		files += pkg.Files
+		if want := (packageStats{Dir: "ref", Interfaces: 1, Files: 2}); pkg.Dir == "ref" && pkg != want {
+			t.Errorf("expected %v, got %v", want, pkg)
+		}
+	}
+	if files != s.Files {
+		t.Errorf("expected %v, got %v", s.Files, files)
+	}
+}
+
+func TestBazelLabel(t *testing.T) {
+	tests := []struct {
+		file  string
+		label string
+		ok    bool
+	}{
+		{file: "/ws/ref/stub.go", label: "stub.go", ok: true},
+		{file: "/ws/ref/stubs/stub.go", label: "//ref/stubs:stub.go", ok: true},
+		{file: "/ws/stub.go", label: "//:stub.go", ok: true},
+		{file: "/elsewhere/stub.go", ok: false},
+	}
+	for _, test := range tests {
+		label, ok := bazelLabel("/ws", "ref", filepath.FromSlash(test.file))
+		if label != test.label || ok != test.ok {
+			t.Errorf("%s: expected %v %v, got %v %v", test.file, test.label, test.ok, label, ok)
+		}
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]string{
+		"stub_thinger.go": "stub_thinger.go",
+		"-style=retry":    "-style=retry",
+		"":                "''",
+		"a b":             "'a b'",
+		"it's":            `'it'\''s'`,
+	}
+	for s, want := range tests {
+		if got := shellQuote(s); got != want {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	}
+}
+
+// captureStdout returns what f prints to stdout.
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	out := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		out <- string(b)
+	}()
+	f()
+	w.Close()
+	return <-out
+}
+
+func TestDescribe(t *testing.T) {
+	path, err := filepath.Abs("ref/thinger.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := captureStdout(t, func() { runDescribe([]string{"ref", "Thinger"}) })
+	want := "github.com/phildrip/toe/ref.Thinger (" + path + ":7:6)\n" +
+		"\tThing() error\n" +
+		"\tThingWithParam(arg1 int) error\n" +
+		"\tThingWithParams(arg1 int, arg2 string) (string, error)\n"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	var iface struct {
+		Name    string `json:"name"`
+		Package string `json:"package"`
+		Methods []struct {
+			Name    string `json:"name"`
+			Results []struct {
+				Type string `json:"type"`
+			} `json:"results"`
+		} `json:"methods"`
+	}
+	out := captureStdout(t, func() { runDescribe([]string{"-json", "ref", "Thinger"}) })
+	if err := json.Unmarshal([]byte(out), &iface); err != nil {
+		t.Fatalf("expected %v, got %v: %s", nil, err, out)
+	}
+	if iface.Name != "Thinger" || iface.Package != "github.com/phildrip/toe/ref" || len(iface.Methods) != 3 {
+		t.Fatalf("expected %v, got %+v", "Thinger and its 3 methods", iface)
+	}
+	if m := iface.Methods[2]; m.Name != "ThingWithParams" || len(m.Results) != 2 || m.Results[0].Type != "string" {
+		t.Errorf("expected %v, got %+v", "ThingWithParams returning a string and an error", m)
+	}
+}
+
+func TestDescribeSignature(t *testing.T) {
+	ctx := &model.Param{Name: "ctx", TypeString: "context.Context"}
+	ids := &model.Param{Name: "ids", TypeString: "[]int"}
+	unnamed := &model.Param{TypeString: "error"}
+	n := &model.Param{Name: "n", TypeString: "int"}
+	err := &model.Param{Name: "err", TypeString: "error"}
+	tests := []struct {
+		params   []*model.Param
+		variadic bool
+		results  []*model.Param
+		want     string
+	}{
+		{want: "()"},
+		{params: []*model.Param{ctx, ids}, variadic: true, results: []*model.Param{unnamed}, want: "(ctx context.Context, ids ...int) error"},
+		{params: []*model.Param{ids}, results: []*model.Param{n, err}, want: "(ids []int) (n int, err error)"},
+		{params: []*model.Param{{TypeString: "string"}}, results: []*model.Param{{TypeString: "int"}, unnamed}, want: "(string) (int, error)"},
+	}
+	for _, test := range tests {
+		got := "(" + describeParams(test.params, test.variadic) + ")" + describeResults(test.results)
+		if got != test.want {
+			t.Errorf("expected %v, got %v", test.want, got)
+		}
+	}
+}
+
+func TestMultipleDirs(t *testing.T) {
+	args, commandLine := os.Args, flag.CommandLine
+	defer func() { os.Args, flag.CommandLine = args, commandLine }()
+	flag.CommandLine = flag.NewFlagSet("toe", flag.ExitOnError)
+
+	dir := t.TempDir()
+	os.Args = []string{"toe", "-dir", "ref", "-dir", "ref/results", "-pkg", "stubs",
+		"-o", filepath.Join(dir, "{{.Interface | lower}}_stub.go"), "Thinger", "Fetcher"}
+	main()
+
+	for file, want := range map[string]string{
+		"thinger_stub.go": "type StubThinger struct",
+		"fetcher_stub.go": "type StubFetcher struct",
+	} {
+		content, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(content), want) {
+			t.Errorf("expected %q in:\n%s", want, content)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package model
+
+import (
+	"sort"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Dependencies are the inputs of loading a package: the Go source files of
+// the package and of the packages it imports, directly or indirectly, and
+// the modules providing them. The standard library, which comes with the
+// Go toolchain, is left out.
+type Dependencies struct {
+	// Files are the Go source files, sorted.
+	Files []string `json:"files"`
+	// Modules are the modules, sorted by path.
+	Modules []Module `json:"modules"`
+}
+
+// Module is a module providing packages.
+type Module struct {
+	Path string `json:"path"`
+	// Version is empty for the main module and for modules replaced with
+	// a directory.
+	Version string `json:"version,omitempty"`
+	// Replace is the module replacing this one, if any.
+	Replace *Module `json:"replace,omitempty"`
+}
+
+// String formats m as path@version, followed by its replacement.
+func (m Module) String() string {
+	s := m.Path
+	if m.Version != "" {
+		s += "@" + m.Version
+	}
+	if m.Replace != nil {
+		s += " => " + m.Replace.String()
+	}
+	return s
+}
+
+// LoadDependencies returns the dependencies of the package in dir.
+func LoadDependencies(dir string) (*Dependencies, error) {
+	cfg, err := newConfig(dir, packages.NeedName|
+		packages.NeedFiles|
+		packages.NeedImports|
+		packages.NeedDeps|
+		packages.NeedModule, BuildFlags)
+	if err != nil {
+		return nil, err
+	}
+	pkgs, err := loadPackages(cfg, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	deps := &Dependencies{}
+	modules := make(map[string]bool)
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		if pkg.Module == nil {
+			// A standard library package.
+			return
+		}
+		deps.Files = append(deps.Files, pkg.GoFiles...)
+		deps.Files = append(deps.Files, pkg.OtherFiles...)
+		deps.Files = append(deps.Files, pkg.EmbedFiles...)
+		if !modules[pkg.Module.Path] {
+			modules[pkg.Module.Path] = true
+			deps.Modules = append(deps.Modules, newModule(pkg.Module))
+		}
+	})
+	sort.Strings(deps.Files)
+	sort.Slice(deps.Modules, func(i, j int) bool {
+		return deps.Modules[i].Path < deps.Modules[j].Path
+	})
+	return deps, nil
+}
+
+func newModule(m *packages.Module) Module {
+	module := Module{Path: m.Path, Version: m.Version}
+	if m.Replace != nil {
+		replace := newModule(m.Replace)
+		module.Replace = &replace
+	}
+	return module
+}
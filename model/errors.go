@@ -0,0 +1,152 @@
+package model
+
+import (
+	"fmt"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Error is an error about a declaration in a loaded package, such as an
+// interface or one of its methods, at Pos.
+type Error struct {
+	Pos token.Position
+	Msg string
+}
+
+// Errorf returns an *Error at pos, with a message formatted as by
+// fmt.Sprintf.
+func Errorf(pos token.Position, format string, args ...any) error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (e *Error) Error() string {
+	return e.Pos.String() + ": " + e.Msg
+}
+
+// RelativePosition formats pos with its file name relative to the working
+// directory, if it is within it.
+func RelativePosition(pos token.Position) string {
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, pos.Filename); err == nil && !strings.HasPrefix(rel, "..") {
+			pos.Filename = rel
+		}
+	}
+	return pos.String()
+}
+
+// LoadError is an error loading packages, reported by the go command or
+// the type checker. Errors are the messages of the errors, and Hints
+// suggestions for fixing their usual causes. Network reports whether
+// downloading modules failed with a network error, a problem with the
+// network or the module proxy rather than with the code, and Retries how
+// many times loading was retried; see LoadRetries.
+type LoadError struct {
+	Errors  []string
+	Hints   []string
+	Network bool
+	Retries int
+}
+
+func (e *LoadError) Error() string {
+	var b strings.Builder
+	if e.Network {
+		fmt.Fprintf(&b, "loading packages failed downloading modules, a network or module proxy problem rather than one in the code, after %d retries:", e.Retries)
+	} else {
+		b.WriteString("loading packages failed:")
+	}
+	for _, msg := range e.Errors {
+		b.WriteString("\n\t" + strings.ReplaceAll(msg, "\n", "\n\t"))
+	}
+	for _, hint := range e.Hints {
+		b.WriteString("\nhint: " + hint)
+	}
+	return b.String()
+}
+
+// loadHints are the hints of LoadError, by a substring of the messages of
+// the errors they apply to.
+var loadHints = []struct {
+	match, hint string
+}{
+	{"go.mod file not found", "run toe in a module's directory, or create a module with go mod init"},
+	{"cannot find main module", "run toe in a module's directory, or create a module with go mod init"},
+	{"no required module provides package", "add the missing dependencies with go get, or go mod tidy"},
+	{"cannot find module providing package", "add the missing dependencies with go get, or go mod tidy"},
+	{": reading https://", "check the module path, and GOPROXY, or GOPRIVATE for private modules"},
+	{"missing go.sum entry", "add the missing go.sum entries with go mod tidy"},
+	{"updates to go.mod needed", "update go.mod with go mod tidy"},
+	{"inconsistent vendoring", "update the vendor directory with go mod vendor, or load packages with -mod=mod"},
+	{"-mod may only be set", "-mod conflicts with GOFLAGS or the vendor directory; check go env GOFLAGS"},
+	{"-mod=", "check -mod, and the flags in GOFLAGS, shown by go env GOFLAGS"},
+	{"GOFLAGS", "fix the flags in GOFLAGS, shown by go env GOFLAGS, which toe passes to the go command"},
+	{"build constraints exclude all Go files", "check the build tags, GOOS and GOARCH the package is loaded with"},
+	{"no Go files in", "check the package directory, or load its _test.go files with -tests"},
+	{"no such file or directory", "check the package directory exists"},
+	{"requires go >=", "upgrade the Go toolchain, or load packages with a toolchain new enough with GOTOOLCHAIN"},
+}
+
+// newLoadError returns a *LoadError for the error messages msgs, with the
+// hints that apply to them.
+func newLoadError(msgs []string) *LoadError {
+	e := &LoadError{}
+	for _, msg := range msgs {
+		e.Errors = append(e.Errors, goCommandMessage(msg))
+		e.Network = e.Network || isNetworkError(msg)
+	}
+	if e.Network {
+		// The other errors usually follow from the failed downloads.
+		e.Hints = append(e.Hints, "check the network and GOPROXY, then run toe again")
+		return e
+	}
+	seen := make(map[string]bool)
+	for _, h := range loadHints {
+		for _, msg := range e.Errors {
+			if strings.Contains(msg, h.match) && !seen[h.hint] {
+				seen[h.hint] = true
+				e.Hints = append(e.Hints, h.hint)
+			}
+		}
+	}
+	return e
+}
+
+// goCommandMessage returns the message of the go command's error msg, as
+// go/packages reports it, wrapped as "err: <error>: stderr: <output>": its
+// output if any, or else the error.
+func goCommandMessage(msg string) string {
+	rest, ok := strings.CutPrefix(msg, "err: ")
+	if !ok {
+		return msg
+	}
+	err, stderr, ok := strings.Cut(rest, ": stderr: ")
+	if !ok {
+		return msg
+	}
+	if stderr = strings.TrimSpace(stderr); stderr != "" {
+		return stderr
+	}
+	return err
+}
+
+// packageErrors returns a *LoadError for the errors of pkgs and their
+// dependencies, or nil if there are none.
+func packageErrors(pkgs []*packages.Package) error {
+	var msgs []string
+	seen := make(map[string]bool)
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		for _, err := range pkg.Errors {
+			if msg := err.Error(); !seen[msg] {
+				seen[msg] = true
+				msgs = append(msgs, msg)
+			}
+		}
+	})
+	if len(msgs) == 0 {
+		return nil
+	}
+	return newLoadError(msgs)
+}
@@ -0,0 +1,190 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// LoadFile loads a single Go file as if it were the whole of its package,
+// for scaffolding in directories that aren't valid packages yet. Types are
+// resolved as far as they can be: the packages the file imports are loaded
+// when the go command can find them, and references to other files of the
+// package or to packages that can't be loaded are left unresolved. The
+// interfaces whose methods refer to unresolved types are left out, Lookup
+// reporting why.
+//
+// The package's import path is that of the file's directory if it is in a
+// module, and otherwise the package's name.
+func LoadFile(file string) (*Package, error) {
+	file, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(file)
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, file, nil, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	imports := loadImports(dir, f)
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Implicits:  make(map[ast.Node]types.Object),
+		Instances:  make(map[*ast.Ident]types.Instance),
+		Scopes:     make(map[ast.Node]*types.Scope),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+	}
+	conf := types.Config{
+		GoVersion: Lang,
+		Importer: importerFunc(func(path string) (*types.Package, error) {
+			if path == "unsafe" {
+				return types.Unsafe, nil
+			}
+			if imp, ok := imports[path]; ok {
+				return imp, nil
+			}
+			return nil, fmt.Errorf("package %s can't be loaded", path)
+		}),
+		// Errors are expected, and leave the types involved invalid.
+		Error: func(error) {},
+	}
+	path := filePackagePath(dir, f.Name.Name)
+	checked, _ := conf.Check(path, fset, []*ast.File{f}, info)
+
+	pkg := newPackage(&packages.Package{
+		Name:      f.Name.Name,
+		PkgPath:   path,
+		GoFiles:   []string{file},
+		Fset:      fset,
+		Syntax:    []*ast.File{f},
+		Types:     checked,
+		TypesInfo: info,
+	})
+	resolved := func(ifaces []*Interface) []*Interface {
+		var resolved []*Interface
+		for _, iface := range ifaces {
+			if strings.Contains(types.TypeString(iface.Type.Underlying(), nil), "invalid type") {
+				if pkg.unresolved == nil {
+					pkg.unresolved = make(map[string]error)
+				}
+				pkg.unresolved[iface.Name] = Errorf(iface.Pos,
+					"%s refers to types that can't be resolved from %s alone", iface.Name, filepath.Base(file))
+				continue
+			}
+			resolved = append(resolved, iface)
+		}
+		return resolved
+	}
+	pkg.Interfaces = resolved(pkg.Interfaces)
+	pkg.Funcs = resolved(pkg.Funcs)
+	return pkg, nil
+}
+
+// loadImports loads the packages imported by files, resolved from dir,
+// leaving out those that can't be loaded.
+func loadImports(dir string, files ...*ast.File) map[string]*types.Package {
+	imports := make(map[string]*types.Package)
+	var paths []string
+	for _, f := range files {
+		for _, spec := range f.Imports {
+			if path, err := strconv.Unquote(spec.Path.Value); err == nil && path != "unsafe" && path != "C" &&
+				!slices.Contains(paths, path) {
+				paths = append(paths, path)
+			}
+		}
+	}
+	if len(paths) == 0 {
+		return imports
+	}
+	cfg, err := newConfig(dir, packages.NeedName|packages.NeedTypes|packages.NeedImports|packages.NeedDeps, BuildFlags)
+	if err != nil {
+		return imports
+	}
+	pkgs, err := packages.Load(cfg, paths...)
+	if err != nil {
+		return imports
+	}
+	for _, pkg := range pkgs {
+		if len(pkg.Errors) == 0 && pkg.Types != nil {
+			imports[pkg.PkgPath] = pkg.Types
+		}
+	}
+	return imports
+}
+
+// filePackagePath returns the import path of the package in dir if the go
+// command can tell, and otherwise name.
+func filePackagePath(dir string, name string) string {
+	cfg, err := newConfig(dir, packages.NeedName, BuildFlags)
+	if err != nil {
+		return name
+	}
+	pkgs, err := packages.Load(cfg, ".")
+	if err != nil || len(pkgs) != 1 {
+		return name
+	}
+	path := pkgs[0].PkgPath
+	if path == "" || strings.HasPrefix(path, "_/") || path == "command-line-arguments" {
+		return name
+	}
+	return path
+}
+
+// TypeCheck type-checks sources, Go files by name, as a package, resolving
+// the packages they import from dir. It returns an error listing the type
+// errors, with their positions, or the packages that can't be loaded.
+func TypeCheck(dir string, sources map[string][]byte) error {
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range names {
+		f, err := parser.ParseFile(fset, name, sources[name], 0)
+		if err != nil {
+			return err
+		}
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	imports := loadImports(dir, files...)
+
+	var errs []string
+	conf := types.Config{
+		GoVersion: Lang,
+		Importer: importerFunc(func(path string) (*types.Package, error) {
+			if path == "unsafe" {
+				return types.Unsafe, nil
+			}
+			if imp, ok := imports[path]; ok {
+				return imp, nil
+			}
+			return nil, fmt.Errorf("package %s can't be loaded from %s", path, dir)
+		}),
+		Error: func(err error) { errs = append(errs, err.Error()) },
+	}
+	conf.Check(files[0].Name.Name, fset, files, nil)
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"go/version"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Lang is the Go language version, such as "go1.21", that loaded packages
+// are type-checked against, rather than the version declared by their
+// module's go directive. The packages they import are checked as usual.
+var Lang string
+
+// checkLang type-checks pkg again against the language version lang,
+// replacing its types, so that code generated with a newer toolchain is
+// still validated against an older version of the language.
+func checkLang(pkg *packages.Package, lang string) error {
+	if !version.IsValid(lang) || version.Lang(lang) != lang {
+		return fmt.Errorf("invalid language version %q: want a version such as go1.21", lang)
+	}
+
+	info := &types.Info{
+		Types:        make(map[ast.Expr]types.TypeAndValue),
+		Defs:         make(map[*ast.Ident]types.Object),
+		Uses:         make(map[*ast.Ident]types.Object),
+		Implicits:    make(map[ast.Node]types.Object),
+		Instances:    make(map[*ast.Ident]types.Instance),
+		Scopes:       make(map[ast.Node]*types.Scope),
+		Selections:   make(map[*ast.SelectorExpr]*types.Selection),
+		FileVersions: make(map[*ast.File]string),
+	}
+	conf := types.Config{
+		GoVersion: lang,
+		Importer: importerFunc(func(path string) (*types.Package, error) {
+			if path == "unsafe" {
+				return types.Unsafe, nil
+			}
+			imp, ok := pkg.Imports[path]
+			if !ok || imp.Types == nil {
+				return nil, fmt.Errorf("package %s not loaded", path)
+			}
+			return imp.Types, nil
+		}),
+		Sizes: pkg.TypesSizes,
+	}
+	checked, err := conf.Check(pkg.PkgPath, pkg.Fset, pkg.Syntax, info)
+	if err != nil {
+		var typeErr types.Error
+		if errors.As(err, &typeErr) {
+			return Errorf(typeErr.Fset.Position(typeErr.Pos), "%s", typeErr.Msg)
+		}
+		return err
+	}
+	pkg.Types, pkg.TypesInfo = checked, info
+	return nil
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) {
+	return f(path)
+}
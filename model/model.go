@@ -0,0 +1,455 @@
+// Package model loads the interfaces declared in a Go package into a
+// representation independent of code generation, for toe's generator and
+// for other tools, such as linters and documentation generators, that want
+// to reuse its interface discovery.
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Package is a loaded Go package.
+//
+// The model marshals to JSON, leaving out the go/types objects.
+type Package struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	// Dir is the package's directory, and Files its Go source files.
+	Dir   string   `json:"dir"`
+	Files []string `json:"files"`
+	// Interfaces are the named interfaces declared at the package's top
+	// level, sorted by name.
+	Interfaces []*Interface `json:"interfaces"`
+	// Funcs are the named func types declared at the package's top level,
+	// sorted by name, modelled as interfaces; see Interface.Func.
+	Funcs []*Interface `json:"funcs,omitempty"`
+	// Types is the type-checked package, and Fset the file set of its
+	// positions.
+	Types *types.Package `json:"-"`
+	Fset  *token.FileSet `json:"-"`
+
+	// unresolved holds the errors of the interfaces left out of a package
+	// loaded by LoadFile as their methods refer to types that couldn't be
+	// resolved, by name.
+	unresolved map[string]error
+}
+
+// Interface is a named interface type.
+type Interface struct {
+	Name string `json:"name"`
+	// Package is the import path of the interface's package.
+	Package string `json:"package"`
+	// Module is the path of the module providing the package, and
+	// ModuleDir its root directory; both are empty for packages loaded
+	// outside a module, such as by LoadFile.
+	Module    string `json:"module,omitempty"`
+	ModuleDir string `json:"moduleDir,omitempty"`
+	// Doc is the interface's doc comment.
+	Doc string `json:"doc,omitempty"`
+	// Pos is the position of the interface's name in its declaration.
+	Pos        token.Position `json:"pos"`
+	TypeParams []*TypeParam   `json:"typeParams,omitempty"`
+	// Methods is the interface's method set, including the methods of
+	// embedded interfaces: its explicitly declared methods in source order,
+	// followed by those of each embedded interface in turn.
+	Methods []*Method `json:"methods"`
+	// Imports are the packages referred to by the methods' signatures,
+	// other than the interface's own package, sorted by path.
+	Imports []Import `json:"imports,omitempty"`
+	// Hash identifies the interface's method set; see Hash.
+	Hash string `json:"hash"`
+	// Func reports whether the type is a named func type rather than an
+	// interface. It is modelled as an interface with a single method,
+	// FuncMethod, with the func type's signature.
+	Func bool `json:"func,omitempty"`
+	// Type is the interface's type.
+	Type *types.Named `json:"-"`
+}
+
+// FuncMethod is the name of the method of the interfaces modelling named
+// func types.
+const FuncMethod = "Call"
+
+// TypeParam is a type parameter of a generic interface.
+type TypeParam struct {
+	Name       string     `json:"name"`
+	Constraint types.Type `json:"-"`
+	// ConstraintString is the constraint as written in the interface's
+	// package.
+	ConstraintString string `json:"constraint"`
+}
+
+// Method is a method of an interface.
+type Method struct {
+	Name string `json:"name"`
+	// Doc is the method's doc comment.
+	Doc string `json:"doc,omitempty"`
+	// Pos is the position of the method's name in its declaration, which
+	// is in an embedded interface for promoted methods.
+	Pos      token.Position `json:"pos"`
+	Params   []*Param       `json:"params"`
+	Results  []*Param       `json:"results"`
+	Variadic bool           `json:"variadic,omitempty"`
+	// Func is the method's object.
+	Func *types.Func `json:"-"`
+}
+
+// Param is a parameter or result of a method.
+type Param struct {
+	// Name is the parameter's name, which is empty for unnamed parameters.
+	Name string     `json:"name,omitempty"`
+	Type types.Type `json:"-"`
+	// TypeString is the parameter's type as written in the interface's
+	// package, and QualifiedType the type with every package named by its
+	// import path. The type of a variadic parameter is a slice.
+	TypeString    string `json:"type"`
+	QualifiedType string `json:"qualifiedType"`
+}
+
+// Import is a package referred to by an interface.
+type Import struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// Load loads the package in dir.
+func Load(dir string) (*Package, error) {
+	return load(dir, ".", BuildFlags)
+}
+
+// LoadImport loads the package with the given import path, resolved from
+// dir.
+func LoadImport(dir string, path string) (*Package, error) {
+	return load(dir, path, BuildFlags)
+}
+
+func load(dir string, pattern string, buildFlags []string) (*Package, error) {
+	pkg, err := loadPackage(dir, pattern, buildFlags)
+	if err != nil {
+		return nil, err
+	}
+	return newPackage(pkg), nil
+}
+
+// newPackage returns the model of the type-checked pkg.
+func newPackage(pkg *packages.Package) *Package {
+	docs := methodDocs(pkg)
+	result := &Package{
+		Name:  pkg.Name,
+		Path:  pkg.PkgPath,
+		Files: pkg.GoFiles,
+		Types: pkg.Types,
+		Fset:  pkg.Fset,
+	}
+	if len(pkg.GoFiles) > 0 {
+		result.Dir = filepath.Dir(pkg.GoFiles[0])
+	}
+	scope := pkg.Types.Scope()
+	for _, name := range scope.Names() {
+		obj, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || obj.IsAlias() {
+			continue
+		}
+		if types.IsInterface(obj.Type()) {
+			result.Interfaces = append(result.Interfaces, newInterface(pkg, obj, docs))
+		} else if _, ok := obj.Type().Underlying().(*types.Signature); ok {
+			result.Funcs = append(result.Funcs, newInterface(pkg, obj, docs))
+		}
+	}
+	return result
+}
+
+// Lookup returns the named interface or func type declared in p.
+func (p *Package) Lookup(name string) (*Interface, error) {
+	if err, ok := p.unresolved[name]; ok {
+		return nil, err
+	}
+	for _, ifaces := range [][]*Interface{p.Interfaces, p.Funcs} {
+		for _, iface := range ifaces {
+			if iface.Name == name {
+				return iface, nil
+			}
+		}
+	}
+	obj, ok := p.Types.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("interface %s not found", name)
+	}
+	if _, ok := obj.Type().(*types.Named); !ok {
+		return nil, Errorf(p.Fset.Position(obj.Pos()), "%s is not a named type", name)
+	}
+	return nil, Errorf(p.Fset.Position(obj.Pos()), "%s is not an interface or func type", name)
+}
+
+// BuildFlags are passed to the go command when loading packages, such as
+// "-mod=vendor" or "-overlay=overlay.json", as to go build. The go command
+// also reads the GOFLAGS environment variable.
+var BuildFlags []string
+
+// Tests makes Load and LoadImport load the test variant of packages, with
+// the declarations of their _test.go files, such as test-only interfaces.
+// Those of external _test packages are left out.
+var Tests bool
+
+// loadPackage loads the package matching pattern in dir, with its syntax
+// and types, passing buildFlags to the go command. It is type-checked
+// against Lang, if set.
+func loadPackage(dir string, pattern string, buildFlags []string) (*packages.Package, error) {
+	cfg, err := newConfig(dir, packages.NeedName|
+		packages.NeedFiles|
+		packages.NeedSyntax|
+		packages.NeedTypes|
+		packages.NeedImports|
+		packages.NeedDeps|
+		packages.NeedModule|
+		packages.NeedTypesInfo, buildFlags)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Tests = Tests
+	pkgs, err := loadPackages(cfg, pattern)
+	if err != nil {
+		return nil, err
+	}
+	pkg := pkgs[0]
+	for _, p := range pkgs {
+		// The test variant's ID is the package's path followed by that of
+		// the test binary, such as "example.com/m/store
+		// [example.com/m/store.test]", while the package of the binary's
+		// main function has no Go files of its own.
+		if strings.HasPrefix(p.ID, pkg.PkgPath+" [") {
+			pkg = p
+			break
+		}
+	}
+	if Lang != "" {
+		if err := checkLang(pkg, Lang); err != nil {
+			return nil, err
+		}
+	}
+	return pkg, nil
+}
+
+// methodDocs returns the doc comments of the interface methods and types
+// declared in pkg, by the position of their names.
+func methodDocs(pkg *packages.Package) map[token.Pos]string {
+	docs := make(map[token.Pos]string)
+	for _, file := range pkg.Syntax {
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.GenDecl:
+				// A lone type spec's doc comment is the declaration's.
+				if n.Tok == token.TYPE && len(n.Specs) == 1 && n.Doc != nil {
+					docs[n.Specs[0].(*ast.TypeSpec).Name.Pos()] = n.Doc.Text()
+				}
+			case *ast.TypeSpec:
+				if n.Doc != nil {
+					docs[n.Name.Pos()] = n.Doc.Text()
+				}
+			case *ast.InterfaceType:
+				for _, field := range n.Methods.List {
+					for _, name := range field.Names {
+						docs[name.Pos()] = field.Doc.Text()
+					}
+				}
+			}
+			return true
+		})
+	}
+	return docs
+}
+
+func newInterface(pkg *packages.Package, obj *types.TypeName, docs map[token.Pos]string) *Interface {
+	named := obj.Type().(*types.Named)
+	iface := &Interface{
+		Name:    obj.Name(),
+		Package: pkg.PkgPath,
+		Doc:     docs[obj.Pos()],
+		Pos:     pkg.Fset.Position(obj.Pos()),
+		Type:    named,
+		Hash:    Hash(named),
+	}
+	if pkg.Module != nil {
+		iface.Module = pkg.Module.Path
+		iface.ModuleDir = pkg.Module.Dir
+	}
+
+	qualifier := types.RelativeTo(pkg.Types)
+	tparams := named.TypeParams()
+	for i := 0; i < tparams.Len(); i++ {
+		iface.TypeParams = append(iface.TypeParams, &TypeParam{
+			Name:             tparams.At(i).Obj().Name(),
+			Constraint:       tparams.At(i).Constraint(),
+			ConstraintString: types.TypeString(tparams.At(i).Constraint(), qualifier),
+		})
+	}
+
+	var fns []*types.Func
+	if sig, ok := named.Underlying().(*types.Signature); ok {
+		iface.Func = true
+		fns = append(fns, funcMethod(obj, sig))
+	} else {
+		collectMethods(named.Underlying().(*types.Interface), make(map[string]bool), &fns)
+	}
+	for _, fn := range fns {
+		sig := fn.Type().(*types.Signature)
+		method := &Method{
+			Name:     fn.Name(),
+			Doc:      docs[fn.Pos()],
+			Pos:      pkg.Fset.Position(fn.Pos()),
+			Variadic: sig.Variadic(),
+			Func:     fn,
+		}
+		for i := 0; i < sig.Params().Len(); i++ {
+			method.Params = append(method.Params, newParam(sig.Params().At(i), qualifier))
+		}
+		for i := 0; i < sig.Results().Len(); i++ {
+			method.Results = append(method.Results, newParam(sig.Results().At(i), qualifier))
+		}
+		iface.Methods = append(iface.Methods, method)
+	}
+
+	refs := make(map[*types.Package]bool)
+	for _, fn := range fns {
+		collectPackages(fn.Type(), refs, make(map[types.Type]bool))
+	}
+	for p := range refs {
+		if p != pkg.Types {
+			iface.Imports = append(iface.Imports, Import{Name: p.Name(), Path: p.Path()})
+		}
+	}
+	sort.Slice(iface.Imports, func(i, j int) bool {
+		return iface.Imports[i].Path < iface.Imports[j].Path
+	})
+	return iface
+}
+
+func newParam(v *types.Var, qualifier types.Qualifier) *Param {
+	return &Param{
+		Name:          v.Name(),
+		Type:          v.Type(),
+		TypeString:    types.TypeString(v.Type(), qualifier),
+		QualifiedType: types.TypeString(v.Type(), nil),
+	}
+}
+
+// Hash returns a hash identifying the method set of the interface named,
+// or the signature of the func type named, which changes whenever the name,
+// parameters or results of any of its methods, or its type parameters,
+// change.
+func Hash(named *types.Named) string {
+	// Types are qualified with package paths, which are unique.
+	qualifier := func(pkg *types.Package) string { return pkg.Path() }
+
+	h := sha256.New()
+	tparams := named.TypeParams()
+	for i := 0; i < tparams.Len(); i++ {
+		fmt.Fprintf(h, "type %s %s\n", tparams.At(i).Obj().Name(),
+			types.TypeString(tparams.At(i).Constraint(), qualifier))
+	}
+	var methods []*types.Func
+	if sig, ok := named.Underlying().(*types.Signature); ok {
+		methods = append(methods, funcMethod(named.Obj(), sig))
+	} else {
+		iface := named.Underlying().(*types.Interface)
+		for i := 0; i < iface.NumMethods(); i++ {
+			methods = append(methods, iface.Method(i))
+		}
+	}
+	for _, m := range methods {
+		fmt.Fprintf(h, "func %s%s\n", m.Name(),
+			strings.TrimPrefix(types.TypeString(m.Type(), qualifier), "func"))
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// funcMethod returns the method modelling the func type obj with the
+// signature sig; see Interface.Func.
+func funcMethod(obj *types.TypeName, sig *types.Signature) *types.Func {
+	return types.NewFunc(obj.Pos(), obj.Pkg(), FuncMethod,
+		types.NewSignatureType(nil, nil, nil, sig.Params(), sig.Results(), sig.Variadic()))
+}
+
+// collectMethods appends the methods of iface to methods: its explicitly
+// declared methods in source order, followed by those of each embedded
+// interface in turn.
+func collectMethods(iface *types.Interface, seen map[string]bool, methods *[]*types.Func) {
+	var explicit []*types.Func
+	for i := 0; i < iface.NumExplicitMethods(); i++ {
+		explicit = append(explicit, iface.ExplicitMethod(i))
+	}
+	sort.SliceStable(explicit, func(i, j int) bool {
+		return explicit[i].Pos() < explicit[j].Pos()
+	})
+	for _, m := range explicit {
+		if !seen[m.Name()] {
+			seen[m.Name()] = true
+			*methods = append(*methods, m)
+		}
+	}
+
+	for i := 0; i < iface.NumEmbeddeds(); i++ {
+		if embedded, ok := iface.EmbeddedType(i).Underlying().(*types.Interface); ok {
+			collectMethods(embedded, seen, methods)
+		}
+	}
+}
+
+// collectPackages adds the packages of the named types that typ refers to
+// to pkgs.
+func collectPackages(typ types.Type, pkgs map[*types.Package]bool, seen map[types.Type]bool) {
+	if seen[typ] {
+		return
+	}
+	seen[typ] = true
+
+	switch t := typ.(type) {
+	case *types.Named:
+		if pkg := t.Obj().Pkg(); pkg != nil {
+			pkgs[pkg] = true
+		}
+		args := t.TypeArgs()
+		for i := 0; i < args.Len(); i++ {
+			collectPackages(args.At(i), pkgs, seen)
+		}
+	case *types.Alias:
+		if pkg := t.Obj().Pkg(); pkg != nil {
+			pkgs[pkg] = true
+		}
+	case *types.Pointer:
+		collectPackages(t.Elem(), pkgs, seen)
+	case *types.Slice:
+		collectPackages(t.Elem(), pkgs, seen)
+	case *types.Array:
+		collectPackages(t.Elem(), pkgs, seen)
+	case *types.Chan:
+		collectPackages(t.Elem(), pkgs, seen)
+	case *types.Map:
+		collectPackages(t.Key(), pkgs, seen)
+		collectPackages(t.Elem(), pkgs, seen)
+	case *types.Signature:
+		for _, tuple := range []*types.Tuple{t.Params(), t.Results()} {
+			for i := 0; i < tuple.Len(); i++ {
+				collectPackages(tuple.At(i).Type(), pkgs, seen)
+			}
+		}
+	case *types.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			collectPackages(t.Field(i).Type(), pkgs, seen)
+		}
+	case *types.Interface:
+		for i := 0; i < t.NumMethods(); i++ {
+			collectPackages(t.Method(i).Type(), pkgs, seen)
+		}
+	}
+}
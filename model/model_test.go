@@ -0,0 +1,307 @@
+package model_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/phildrip/toe/model"
+)
+
+func TestLoad(t *testing.T) {
+	pkg, err := model.Load("../ref")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.Name != "ref" {
+		t.Errorf("expected %v, got %v", "ref", pkg.Name)
+	}
+
+	iface, err := pkg.Lookup("Thinger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(iface.Methods) != 3 {
+		t.Fatalf("expected %v, got %v", 3, len(iface.Methods))
+	}
+
+	method := iface.Methods[2]
+	if method.Name != "ThingWithParams" {
+		t.Errorf("expected %v, got %v", "ThingWithParams", method.Name)
+	}
+	if len(method.Params) != 2 || method.Params[1].Name != "arg2" || method.Params[1].TypeString != "string" {
+		t.Errorf("expected %v, got %+v", "arg2 string", method.Params)
+	}
+	if len(method.Results) != 2 || method.Results[1].TypeString != "error" {
+		t.Errorf("expected %v, got %+v", "(string, error)", method.Results)
+	}
+}
+
+func TestLookupErrors(t *testing.T) {
+	pkg, err := model.Load("../ref")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"Nope", "RetryThingerPolicy"} {
+		if _, err := pkg.Lookup(name); err == nil {
+			t.Errorf("expected an error looking up %s", name)
+		}
+	}
+}
+
+func TestLookupErrorPosition(t *testing.T) {
+	pkg, err := model.Load("../ref")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = pkg.Lookup("RetryThingerPolicy")
+	var posErr *model.Error
+	if !errors.As(err, &posErr) {
+		t.Fatalf("expected a *model.Error, got %v", err)
+	}
+	if filepath.Base(posErr.Pos.Filename) != "retry_thinger.go" || posErr.Pos.Line == 0 {
+		t.Errorf("expected %v, got %v", "a position in retry_thinger.go", posErr.Pos)
+	}
+}
+
+func TestLoadDependencies(t *testing.T) {
+	deps, err := model.LoadDependencies("../generator")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var files []string
+	for _, file := range deps.Files {
+		files = append(files, filepath.Base(filepath.Dir(file))+"/"+filepath.Base(file))
+	}
+	if !slices.Contains(files, "generator/generator.go") || !slices.Contains(files, "model/model.go") {
+		t.Errorf("expected %v, got %v", "generator/generator.go and model/model.go", files)
+	}
+	if slices.Contains(files, "fmt/print.go") {
+		t.Errorf("expected %v, got %v", "no standard library files", files)
+	}
+
+	var modules []string
+	for _, m := range deps.Modules {
+		modules = append(modules, m.String())
+	}
+	if !slices.Contains(modules, "github.com/phildrip/toe") || !slices.Contains(modules, "golang.org/x/tools@v0.26.0") {
+		t.Errorf("expected %v, got %v", "github.com/phildrip/toe and golang.org/x/tools@v0.26.0", modules)
+	}
+}
+
+func TestLoadFromModule(t *testing.T) {
+	// The module, a dependency of toe, is in the module cache.
+	t.Setenv("GOPROXY", "off")
+	pkg, err := model.LoadFromModule("golang.org/x/tools@v0.26.0", "go/analysis")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.Path != "golang.org/x/tools/go/analysis" {
+		t.Errorf("expected %v, got %v", "golang.org/x/tools/go/analysis", pkg.Path)
+	}
+	if _, err := pkg.Lookup("Fact"); err != nil {
+		t.Errorf("expected %v, got %v", nil, err)
+	}
+
+	if _, err := model.LoadFromModule("golang.org/x/tools", "go/analysis"); err == nil {
+		t.Errorf("expected %v, got %v", "an error", err)
+	}
+}
+
+func TestDownloadModule(t *testing.T) {
+	// The module, a dependency of toe, is in the module cache.
+	t.Setenv("GOPROXY", "off")
+	dir, err := model.DownloadModule("golang.org/x/tools@v0.26.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "go.mod")); err != nil {
+		t.Errorf("expected %v, got %v", nil, err)
+	}
+
+	if _, err := model.DownloadModule("golang.org/x/tools@v0.0.0-nope"); err == nil {
+		t.Errorf("expected %v, got %v", "an error", err)
+	}
+}
+
+func TestLoadOverlay(t *testing.T) {
+	source, err := filepath.Abs("../ref/thinger.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	replacement := filepath.Join(dir, "thinger.go")
+	code := "package ref\n\ntype Thinger interface {\n\tThing() error\n\tThingWithParam(int) error\n" +
+		"\tThingWithParams(int, string) (string, error)\n\tExtra() bool\n}\n"
+	if err := os.WriteFile(replacement, []byte(code), 0644); err != nil {
+		t.Fatal(err)
+	}
+	overlay, err := json.Marshal(map[string]any{"Replace": map[string]string{source: replacement}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	overlayFile := filepath.Join(dir, "overlay.json")
+	if err := os.WriteFile(overlayFile, overlay, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	model.BuildFlags = []string{"-overlay=" + overlayFile}
+	defer func() { model.BuildFlags = nil }()
+	pkg, err := model.Load("../ref")
+	if err != nil {
+		t.Fatal(err)
+	}
+	iface, err := pkg.Lookup("Thinger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(iface.Methods) != 4 || iface.Methods[3].Name != "Extra" {
+		t.Errorf("expected %v, got %v", "Extra", iface.Methods)
+	}
+}
+
+func TestLoadLang(t *testing.T) {
+	defer func() { model.Lang = "" }()
+
+	// ref/results declares generic interfaces.
+	model.Lang = "go1.17"
+	_, err := model.Load("../ref/results")
+	var posErr *model.Error
+	if !errors.As(err, &posErr) || !strings.Contains(posErr.Msg, "requires go1.18") {
+		t.Errorf("expected %v, got %v", "an error requiring go1.18", err)
+	}
+
+	model.Lang = "go1.21"
+	if _, err := model.Load("../ref/results"); err != nil {
+		t.Errorf("expected %v, got %v", nil, err)
+	}
+}
+
+func TestLoadTests(t *testing.T) {
+	// The overlay adds a test file declaring an interface to ref.
+	testFile, err := filepath.Abs("../ref/narrow_test.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	content := filepath.Join(dir, "narrow_test.go")
+	code := "package ref\n\ntype narrowThinger interface {\n\tThing() error\n}\n"
+	if err := os.WriteFile(content, []byte(code), 0644); err != nil {
+		t.Fatal(err)
+	}
+	overlay, err := json.Marshal(map[string]any{"Replace": map[string]string{testFile: content}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	overlayFile := filepath.Join(dir, "overlay.json")
+	if err := os.WriteFile(overlayFile, overlay, 0644); err != nil {
+		t.Fatal(err)
+	}
+	model.BuildFlags = []string{"-overlay=" + overlayFile}
+	defer func() { model.BuildFlags = nil }()
+
+	pkg, err := model.Load("../ref")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pkg.Lookup("narrowThinger"); err == nil {
+		t.Errorf("expected an error without Tests, got nil")
+	}
+
+	model.Tests = true
+	defer func() { model.Tests = false }()
+	pkg, err = model.Load("../ref")
+	if err != nil {
+		t.Fatal(err)
+	}
+	iface, err := pkg.Lookup("narrowThinger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if iface.Pos.Filename != testFile || pkg.Path != "github.com/phildrip/toe/ref" {
+		t.Errorf("expected %v, got %v", testFile, iface.Pos.Filename)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "x.go"), []byte("package x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := model.Load(dir)
+	var loadErr *model.LoadError
+	if !errors.As(err, &loadErr) || len(loadErr.Errors) != 1 || !strings.HasPrefix(loadErr.Errors[0], "go: go.mod file not found") {
+		t.Fatalf("expected %v, got %v", "a go.mod error", err)
+	}
+	if len(loadErr.Hints) != 1 || !strings.Contains(loadErr.Hints[0], "go mod init") {
+		t.Errorf("expected %v, got %v", "a hint to run go mod init", loadErr.Hints)
+	}
+
+	_, err = model.Load("../ref/missing")
+	if !errors.As(err, &loadErr) || !strings.Contains(err.Error(), "hint: check the package directory exists") {
+		t.Errorf("expected %v, got %v", "a hint to check the directory", err)
+	}
+}
+
+func TestLoadNetworkErrors(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.22\n",
+		"m.go":   "package m\n\nimport _ \"example.com/missing\"\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Nothing listens on the proxy's port.
+	t.Setenv("GOPROXY", "http://127.0.0.1:1")
+	t.Setenv("GOFLAGS", "-mod=mod")
+	defer func(retries int, backoff time.Duration) {
+		model.LoadRetries, model.LoadBackoff = retries, backoff
+	}(model.LoadRetries, model.LoadBackoff)
+	model.LoadRetries, model.LoadBackoff = 1, time.Millisecond
+
+	_, err := model.Load(dir)
+	var loadErr *model.LoadError
+	if !errors.As(err, &loadErr) || !loadErr.Network || loadErr.Retries != 1 {
+		t.Errorf("expected %v, got %v", "a network error after 1 retry", err)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "api.go")
+	code := "package api\n\nimport (\n\t\"io\"\n\n\t\"example.com/missing\"\n)\n\n" +
+		"type Thinger interface {\n\tRead(r io.Reader) error\n}\n\n" +
+		"type Broken interface {\n\tGet() missing.Thing\n\tLocal() Other\n}\n"
+	if err := os.WriteFile(file, []byte(code), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pkg, err := model.LoadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.Path != "api" {
+		t.Errorf("expected %v, got %v", "api", pkg.Path)
+	}
+	iface, err := pkg.Lookup("Thinger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(iface.Imports) != 1 || iface.Imports[0].Path != "io" {
+		t.Errorf("expected %v, got %v", "io", iface.Imports)
+	}
+	var posErr *model.Error
+	if _, err := pkg.Lookup("Broken"); !errors.As(err, &posErr) || posErr.Pos.Line != 13 {
+		t.Errorf("expected %v, got %v", "an error at line 13", err)
+	}
+}
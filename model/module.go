@@ -0,0 +1,127 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// LoadFromModule loads the package in dir, relative to the root of the
+// module given as path@version, which need not be a dependency of the
+// module in the working directory: it is fetched into the module cache
+// through the module proxy, as by go get.
+func LoadFromModule(module string, dir string) (*Package, error) {
+	modPath, version, ok := strings.Cut(module, "@")
+	if !ok || modPath == "" || version == "" {
+		return nil, fmt.Errorf("module %q is not of the form path@version", module)
+	}
+
+	// The module is loaded as a dependency of a temporary main module,
+	// which resolves its own dependencies.
+	tmp, err := os.MkdirTemp("", "toe-module")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+	goMod := "module toe.invalid/fetch\n"
+	if err := os.WriteFile(filepath.Join(tmp, "go.mod"), []byte(goMod), 0644); err != nil {
+		return nil, err
+	}
+	retries, err := withRetries(func() error {
+		cmd := exec.Command("go", "get", modPath+"@"+version)
+		cmd.Dir = tmp
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fetchError(module, err, retries)
+	}
+
+	// The temporary module's dependencies are in the module cache, whatever
+	// the -mod flag given for the working directory's.
+	var buildFlags []string
+	for _, flag := range BuildFlags {
+		if !strings.HasPrefix(flag, "-mod=") {
+			buildFlags = append(buildFlags, flag)
+		}
+	}
+	return load(tmp, path.Join(modPath, dir), append(buildFlags, "-mod=mod"))
+}
+
+// downloaded caches the directories of the modules fetched by
+// DownloadModule, by path@version.
+var downloaded struct {
+	mut  sync.Mutex
+	dirs map[string]string
+}
+
+// DownloadModule returns the directory of the module given as
+// path@version, where version may be a query such as v1, fetching it into
+// the module cache through the module proxy if needed, as by go mod
+// download. It needn't be a dependency of the module in the working
+// directory.
+func DownloadModule(module string) (string, error) {
+	modPath, version, ok := strings.Cut(module, "@")
+	if !ok || modPath == "" || version == "" {
+		return "", fmt.Errorf("module %q is not of the form path@version", module)
+	}
+	downloaded.mut.Lock()
+	defer downloaded.mut.Unlock()
+	if dir, ok := downloaded.dirs[module]; ok {
+		return dir, nil
+	}
+
+	// The module is downloaded from a temporary main module, so that the
+	// working directory's go.mod and vendoring don't apply.
+	tmp, err := os.MkdirTemp("", "toe-module")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+	goMod := "module toe.invalid/fetch\n"
+	if err := os.WriteFile(filepath.Join(tmp, "go.mod"), []byte(goMod), 0644); err != nil {
+		return "", err
+	}
+	var info struct {
+		Dir   string
+		Error string
+	}
+	retries, err := withRetries(func() error {
+		cmd := exec.Command("go", "mod", "download", "-json", module)
+		cmd.Dir = tmp
+		out, err := cmd.Output()
+		if jsonErr := json.Unmarshal(out, &info); jsonErr != nil || info.Error != "" || info.Dir == "" {
+			if info.Error == "" && err != nil {
+				info.Error = err.Error()
+			}
+			return errors.New(info.Error)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fetchError(module, err, retries)
+	}
+
+	if downloaded.dirs == nil {
+		downloaded.dirs = make(map[string]string)
+	}
+	downloaded.dirs[module] = info.Dir
+	return info.Dir, nil
+}
+
+// fetchError returns the error fetching module, err, retried retries times,
+// telling network problems from others.
+func fetchError(module string, err error, retries int) error {
+	if isNetworkError(err.Error()) {
+		return fmt.Errorf("fetching %s failed, a network or module proxy problem, after %d retries: %v", module, retries, err)
+	}
+	return fmt.Errorf("fetching %s: %v", module, err)
+}
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// newConfig returns the configuration loading packages in dir with mode,
+// passing buildFlags to the go command.
+//
+// The go command applies an -overlay flag, given in buildFlags or in
+// GOFLAGS, to the files it lists, but packages parses them itself, so the
+// overlay's files are read into the configuration's Overlay instead.
+func newConfig(dir string, mode packages.LoadMode, buildFlags []string) (*packages.Config, error) {
+	cfg := &packages.Config{Mode: mode, Dir: dir}
+	var overlayFile string
+	for _, flag := range strings.Fields(os.Getenv("GOFLAGS")) {
+		if file, ok := strings.CutPrefix(flag, "-overlay="); ok {
+			overlayFile = file
+		}
+	}
+	for _, flag := range buildFlags {
+		if file, ok := strings.CutPrefix(flag, "-overlay="); ok {
+			overlayFile = file
+			continue
+		}
+		cfg.BuildFlags = append(cfg.BuildFlags, flag)
+	}
+	if overlayFile == "" {
+		return cfg, nil
+	}
+
+	b, err := os.ReadFile(overlayFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading overlay: %v", err)
+	}
+	var overlay struct {
+		Replace map[string]string
+	}
+	if err := json.Unmarshal(b, &overlay); err != nil {
+		return nil, fmt.Errorf("parsing overlay %s: %v", overlayFile, err)
+	}
+	cfg.Overlay = make(map[string][]byte)
+	for file, replacement := range overlay.Replace {
+		// As for the go command, relative paths are relative to the
+		// directory it runs in.
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+		if replacement == "" {
+			// A deleted file can't be represented in Overlay.
+			continue
+		}
+		if !filepath.IsAbs(replacement) {
+			replacement = filepath.Join(dir, replacement)
+		}
+		content, err := os.ReadFile(replacement)
+		if err != nil {
+			return nil, fmt.Errorf("reading overlay: %v", err)
+		}
+		cfg.Overlay[file] = content
+	}
+	return cfg, nil
+}
@@ -0,0 +1,86 @@
+package model
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// LoadRetries is the number of times loading packages or fetching modules is
+// retried when downloading modules fails with a network error, such as a
+// timeout or a 5xx response from the module proxy, rather than an error in
+// the code. The first retry waits LoadBackoff, and each later one twice as
+// long as the one before.
+var (
+	LoadRetries = 3
+	LoadBackoff = time.Second
+)
+
+// networkErrors are substrings of the messages of the go command's errors
+// downloading modules that are caused by the network or the module proxy,
+// and may succeed when retried.
+var networkErrors = []string{
+	"dial tcp",
+	"i/o timeout",
+	"connection refused",
+	"connection reset",
+	"TLS handshake timeout",
+	"no such host",
+	"server misbehaving",
+	"Temporary failure in name resolution",
+	"network is unreachable",
+	"unexpected EOF",
+	"500 Internal Server Error",
+	"502 Bad Gateway",
+	"503 Service Unavailable",
+	"504 Gateway Timeout",
+}
+
+// isNetworkError reports whether the error message msg is that of a network
+// failure; see networkErrors.
+func isNetworkError(msg string) bool {
+	for _, s := range networkErrors {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// withRetries calls try until it succeeds, fails with an error other than a
+// network one, or has been retried LoadRetries times, returning the number
+// of retries and its last error.
+func withRetries(try func() error) (int, error) {
+	wait := LoadBackoff
+	for retries := 0; ; retries++ {
+		err := try()
+		var loadErr *LoadError
+		network := errors.As(err, &loadErr) && loadErr.Network || err != nil && isNetworkError(err.Error())
+		if err == nil || retries >= LoadRetries || !network {
+			return retries, err
+		}
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+// loadPackages loads the packages matching pattern with cfg, retrying when
+// downloading modules fails with a network error. Errors loading them,
+// including those of the packages, are returned as a *LoadError.
+func loadPackages(cfg *packages.Config, pattern string) ([]*packages.Package, error) {
+	var pkgs []*packages.Package
+	retries, err := withRetries(func() error {
+		var err error
+		if pkgs, err = packages.Load(cfg, pattern); err != nil {
+			return newLoadError([]string{err.Error()})
+		}
+		return packageErrors(pkgs)
+	})
+	if err != nil {
+		err.(*LoadError).Retries = retries
+		return nil, err
+	}
+	return pkgs, nil
+}
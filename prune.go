@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+
+	"github.com/phildrip/toe/generator"
+	"github.com/phildrip/toe/model"
+)
+
+// readManifest reads the manifest at path, returning an empty one if there
+// is none.
+func readManifest(path string) (manifest, error) {
+	var m manifest
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return m, fmt.Errorf("error parsing manifest %s: %v", path, err)
+	}
+	return m, nil
+}
+
+// orphan is a file generated by an earlier batch run from an interface
+// that no longer exists.
+type orphan struct {
+	path string
+	// entry is the file's entry in the earlier manifest, holding only the
+	// file, to be carried over to the new manifest until it is pruned.
+	entry manifestEntry
+}
+
+// findOrphans returns the files recorded in previous, the manifest of an
+// earlier batch run from a config file in configDir, that are no longer
+// generated, as they aren't among outputs, and whose interface no longer
+// exists. Files that have since been replaced by code that wasn't
+// generated by toe are left alone.
+func findOrphans(previous manifest, outputs map[string]bool, configDir string) []orphan {
+	var orphans []orphan
+	fset := token.NewFileSet()
+	pkgs := make(map[string]*model.Package)
+	for _, entry := range previous.Stubs {
+		for _, file := range entry.Files {
+			path := filepath.Join(configDir, filepath.FromSlash(file.Path))
+			if outputs[path] {
+				continue
+			}
+			f, err := parser.ParseFile(fset, path, nil, parser.PackageClauseOnly|parser.ParseComments)
+			if err != nil {
+				continue
+			}
+			h, ok := generator.ParseHeader(f)
+			if !ok {
+				continue
+			}
+			pkg, ok := pkgs[h.Path]
+			if !ok {
+				// The package may be gone too.
+				pkg, _ = model.LoadImport(configDir, h.Path)
+				pkgs[h.Path] = pkg
+			}
+			if pkg != nil {
+				if _, err := pkg.Lookup(h.Name); err == nil {
+					continue
+				}
+			}
+			orphans = append(orphans, orphan{
+				path:  path,
+				entry: manifestEntry{Interface: entry.Interface, Style: entry.Style, Files: []manifestFile{file}},
+			})
+		}
+	}
+	return orphans
+}
+
+// pruneOrphans reports the orphaned files on stderr, deleting them if
+// prune is set, and otherwise adding them to record so that later runs
+// still find them.
+func pruneOrphans(orphans []orphan, prune bool, record *manifest) {
+	for _, o := range orphans {
+		if !prune {
+			fmt.Fprintf(os.Stderr, "%s is orphaned: interface %s no longer exists\n", o.path, o.entry.Interface)
+			record.Stubs = append(record.Stubs, o.entry)
+			continue
+		}
+		if err := os.Remove(o.path); err != nil {
+			fatal("pruning orphaned file", err)
+		}
+		fmt.Fprintf(os.Stderr, "removed %s: interface %s no longer exists\n", o.path, o.entry.Interface)
+	}
+	if len(orphans) > 0 && !prune {
+		fmt.Fprintf(os.Stderr, "run with -prune to delete orphaned files\n")
+	}
+}
@@ -0,0 +1,164 @@
+// Code generated by github.com/phildrip/toe. DO NOT EDIT.
+//toe:style breaker
+//toe:interface github.com/phildrip/toe/ref.Thinger
+//toe:hash 5f8dd1eebf87fa13
+//toe:version (devel)
+//toe:options {"argNaming":"param","assertions":"std"}
+
+package ref
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrBreakerThingerOpen is returned by BreakerThinger instead of calling the
+// wrapped Thinger while the breaker is open.
+var ErrBreakerThingerOpen = errors.New("Thinger: circuit breaker open")
+
+// BreakerThingerSettings configures BreakerThinger. The zero value is usable.
+type BreakerThingerSettings struct {
+	// FailureThreshold is the number of consecutive failures that opens the
+	// breaker. It defaults to 5.
+	FailureThreshold int
+
+	// OpenTimeout is how long the breaker stays open before letting a single
+	// trial call through. It defaults to 30 seconds.
+	OpenTimeout time.Duration
+
+	// IsFailure reports whether err counts as a failure. By default every
+	// error does.
+	IsFailure func(err error) bool
+
+	// Now returns the current time. It defaults to time.Now, and can be
+	// replaced in tests.
+	Now func() time.Time
+}
+
+// BreakerThinger wraps a Thinger in a circuit breaker. The breaker
+// starts closed, letting calls through. After FailureThreshold consecutive
+// failures it opens, failing calls with ErrBreakerThingerOpen without calling
+// the wrapped value. Once OpenTimeout has passed it is half-open: one trial
+// call is let through, closing the breaker if it succeeds and opening it
+// again if it fails. The results of calls let through before the breaker
+// last changed state are ignored.
+type BreakerThinger struct {
+	next     Thinger
+	settings BreakerThingerSettings
+
+	mut      sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	// generation counts the changes of state, so that the results of calls
+	// let through in an earlier state are ignored.
+	generation uint64
+}
+
+// NewBreakerThinger returns a closed BreakerThinger delegating to next.
+func NewBreakerThinger(next Thinger, settings BreakerThingerSettings) *BreakerThinger {
+	if settings.FailureThreshold <= 0 {
+		settings.FailureThreshold = 5
+	}
+	if settings.OpenTimeout <= 0 {
+		settings.OpenTimeout = 30 * time.Second
+	}
+	if settings.IsFailure == nil {
+		settings.IsFailure = func(err error) bool { return err != nil }
+	}
+	if settings.Now == nil {
+		settings.Now = time.Now
+	}
+	return &BreakerThinger{next: next, settings: settings, state: "closed"}
+}
+
+// State returns the state of the breaker: "closed", "open" or "half-open".
+func (b *BreakerThinger) State() string {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	switch {
+	case b.state == "trial":
+		return "half-open"
+	case b.state == "open" && b.settings.Now().Sub(b.openedAt) >= b.settings.OpenTimeout:
+		return "half-open"
+	}
+	return b.state
+}
+
+// allow returns the generation of the breaker's state the call goes
+// through in, or an error if it may not go through to the wrapped value.
+func (b *BreakerThinger) allow() (uint64, error) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	switch b.state {
+	case "open":
+		if b.settings.Now().Sub(b.openedAt) < b.settings.OpenTimeout {
+			return 0, ErrBreakerThingerOpen
+		}
+		b.setState("trial")
+	case "trial":
+		return 0, ErrBreakerThingerOpen
+	}
+	return b.generation, nil
+}
+
+// record updates the state of the breaker with the result of a call let
+// through in generation. Only the trial call closes an open breaker, and
+// the results of calls let through in an earlier state are ignored.
+func (b *BreakerThinger) record(generation uint64, err error) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	if generation != b.generation {
+		return
+	}
+	if err == nil || !b.settings.IsFailure(err) {
+		b.failures = 0
+		if b.state == "trial" {
+			b.setState("closed")
+		}
+		return
+	}
+	b.failures++
+	if b.state == "trial" || b.failures >= b.settings.FailureThreshold {
+		b.setState("open")
+		b.openedAt = b.settings.Now()
+	}
+}
+
+// setState changes the state of the breaker, starting a new generation.
+func (b *BreakerThinger) setState(state string) {
+	b.state = state
+	b.generation++
+}
+
+func (b *BreakerThinger) Thing() error {
+	generation, err := b.allow()
+	if err != nil {
+		return err
+	}
+	err = b.next.Thing()
+	b.record(generation, err)
+	return err
+}
+
+func (b *BreakerThinger) ThingWithParam(arg1 int) error {
+	generation, err := b.allow()
+	if err != nil {
+		return err
+	}
+	err = b.next.ThingWithParam(arg1)
+	b.record(generation, err)
+	return err
+}
+
+func (b *BreakerThinger) ThingWithParams(arg1 int, arg2 string) (string, error) {
+	var r0 string
+	generation, err := b.allow()
+	if err != nil {
+		return r0, err
+	}
+	r0, err = b.next.ThingWithParams(arg1, arg2)
+	b.record(generation, err)
+	return r0, err
+}
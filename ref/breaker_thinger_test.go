@@ -0,0 +1,127 @@
+package ref_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/phildrip/toe/ref"
+	refstubs "github.com/phildrip/toe/ref/stubs"
+)
+
+func TestBreakerThinger(t *testing.T) {
+	now := time.Now()
+	errFailed := errors.New("failed")
+
+	stub := refstubs.NewStubThinger()
+	breaker := ref.NewBreakerThinger(stub, ref.BreakerThingerSettings{
+		FailureThreshold: 2,
+		OpenTimeout:      time.Minute,
+		Now:              func() time.Time { return now },
+	})
+
+	stub.OnThing().Return(errFailed)
+	for i := 0; i < 2; i++ {
+		if err := breaker.Thing(); err != errFailed {
+			t.Errorf("expected %v, got %v", errFailed, err)
+		}
+	}
+	if breaker.State() != "open" {
+		t.Errorf("expected %v, got %v", "open", breaker.State())
+	}
+
+	err := breaker.ThingWithParam(1)
+	if err != ref.ErrBreakerThingerOpen {
+		t.Errorf("expected %v, got %v", ref.ErrBreakerThingerOpen, err)
+	}
+	if len(stub.ThingWithParamCalls) != 0 {
+		t.Errorf("expected %v, got %v", 0, len(stub.ThingWithParamCalls))
+	}
+
+	now = now.Add(time.Minute)
+	if breaker.State() != "half-open" {
+		t.Errorf("expected %v, got %v", "half-open", breaker.State())
+	}
+
+	stub.OnThingWithParam().Return(nil)
+	err = breaker.ThingWithParam(1)
+	if err != nil {
+		t.Errorf("expected %v, got %v", nil, err)
+	}
+	if breaker.State() != "closed" {
+		t.Errorf("expected %v, got %v", "closed", breaker.State())
+	}
+}
+
+func TestBreakerThingerStaleResults(t *testing.T) {
+	now := time.Now()
+	errFailed := errors.New("failed")
+
+	stub := refstubs.NewStubThinger()
+	breaker := ref.NewBreakerThinger(stub, ref.BreakerThingerSettings{
+		FailureThreshold: 1,
+		OpenTimeout:      time.Minute,
+		Now:              func() time.Time { return now },
+	})
+
+	// A call let through while the breaker is closed succeeds after it
+	// has opened.
+	started, finish := make(chan struct{}), make(chan struct{})
+	stub.OnThingWithParam().ReturnGenerated(func() error {
+		close(started)
+		<-finish
+		return nil
+	})
+	stub.OnThing().Return(errFailed)
+	done := make(chan error)
+	go func() { done <- breaker.ThingWithParam(1) }()
+	<-started
+	if err := breaker.Thing(); err != errFailed {
+		t.Errorf("expected %v, got %v", errFailed, err)
+	}
+	close(finish)
+	if err := <-done; err != nil {
+		t.Errorf("expected %v, got %v", nil, err)
+	}
+	if breaker.State() != "open" {
+		t.Errorf("expected %v, got %v", "open", breaker.State())
+	}
+
+	// A call let through while the breaker was closed succeeds during the
+	// trial call, which then fails.
+	started, finish = make(chan struct{}), make(chan struct{})
+	stub.OnThingWithParam(1).ReturnGenerated(func() error {
+		close(started)
+		<-finish
+		return nil
+	})
+	trialStarted, trialFinish := make(chan struct{}), make(chan struct{})
+	stub.OnThingWithParam(2).ReturnGenerated(func() error {
+		close(trialStarted)
+		<-trialFinish
+		return errFailed
+	})
+	now = now.Add(time.Minute)
+	stub.OnThing().Return(nil)
+	if err := breaker.Thing(); err != nil {
+		t.Errorf("expected %v, got %v", nil, err)
+	}
+	go func() { done <- breaker.ThingWithParam(1) }()
+	<-started
+	stub.OnThing().Return(errFailed)
+	breaker.Thing()
+	now = now.Add(time.Minute)
+	trialDone := make(chan error)
+	go func() { trialDone <- breaker.ThingWithParam(2) }()
+	<-trialStarted
+	close(finish)
+	<-done
+	if breaker.State() != "half-open" {
+		t.Errorf("expected %v, got %v", "half-open", breaker.State())
+	}
+	close(trialFinish)
+	<-trialDone
+	if breaker.State() != "open" {
+		t.Errorf("expected %v, got %v", "open", breaker.State())
+	}
+}
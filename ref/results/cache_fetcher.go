@@ -0,0 +1,120 @@
+// Code generated by github.com/phildrip/toe. DO NOT EDIT.
+//toe:style cache
+//toe:interface github.com/phildrip/toe/ref/results.Fetcher
+//toe:hash 67d2de74bfcde6c4
+//toe:version (devel)
+//toe:options {"argNaming":"param","assertions":"std"}
+
+package results
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CacheFetcherOpts configures CacheFetcher. The zero value is usable.
+type CacheFetcherOpts struct {
+	// TTL is how long a result is cached for. It defaults to one minute.
+	TTL time.Duration
+
+	// Key returns the cache key for a call to method with args, which
+	// exclude any context.Context arguments. By default the key is derived
+	// from the Go syntax representation of args, which holds the addresses
+	// of pointers, funcs and channels rather than what they refer to, so
+	// Key is required when a cached method takes arguments holding them,
+	// maps or interfaces.
+	Key func(method string, args ...any) string
+
+	// Now returns the current time. It defaults to time.Now, and can be
+	// replaced in tests.
+	Now func() time.Time
+}
+
+type CacheFetcherEntry struct {
+	value   any
+	expires time.Time
+}
+
+// CacheFetcher wraps a Fetcher, memoizing the results of its
+// getter-shaped methods - those taking arguments and returning a value and
+// an error. Results are cached per key until their TTL expires; errors are
+// never cached. Other methods are delegated to the wrapped value on every
+// call.
+type CacheFetcher struct {
+	next    Fetcher
+	opts    CacheFetcherOpts
+	mut     sync.Mutex
+	entries map[string]CacheFetcherEntry
+	// swept is when expired entries were last removed.
+	swept time.Time
+}
+
+// NewCacheFetcher returns a CacheFetcher delegating to next on cache
+// misses.
+func NewCacheFetcher(next Fetcher, opts CacheFetcherOpts) *CacheFetcher {
+	if opts.TTL <= 0 {
+		opts.TTL = time.Minute
+	}
+	if opts.Key == nil {
+		opts.Key = func(method string, args ...any) string {
+			return method + fmt.Sprintf("%#v", args)
+		}
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &CacheFetcher{
+		next:    next,
+		opts:    opts,
+		entries: make(map[string]CacheFetcherEntry),
+	}
+}
+
+// Purge removes every cached result.
+func (c *CacheFetcher) Purge() {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.entries = make(map[string]CacheFetcherEntry)
+}
+
+func (c *CacheFetcher) get(key string) (any, bool) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	e, ok := c.entries[key]
+	if !ok || !c.opts.Now().Before(e.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return e.value, true
+}
+
+// put caches value under key. Once per TTL it also removes the expired
+// entries, so that those never read again don't pile up.
+func (c *CacheFetcher) put(key string, value any) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	now := c.opts.Now()
+	if now.Sub(c.swept) >= c.opts.TTL {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		c.swept = now
+	}
+	c.entries[key] = CacheFetcherEntry{value: value, expires: now.Add(c.opts.TTL)}
+}
+
+func (c *CacheFetcher) Fetch(ctx context.Context, key string) (string, error) {
+	cacheKey := c.opts.Key("Fetch", key)
+	if v, ok := c.get(cacheKey); ok {
+		return v.(string), nil
+	}
+	r0, err := c.next.Fetch(ctx, key)
+	if err == nil {
+		c.put(cacheKey, r0)
+	}
+	return r0, err
+}
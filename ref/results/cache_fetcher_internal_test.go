@@ -0,0 +1,38 @@
+package results
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// echoFetcher fetches the key itself.
+type echoFetcher struct{}
+
+func (echoFetcher) Fetch(ctx context.Context, key string) (string, error) {
+	return key, nil
+}
+
+func TestCacheFetcherSweep(t *testing.T) {
+	now := time.Unix(0, 0)
+	cache := NewCacheFetcher(echoFetcher{}, CacheFetcherOpts{
+		TTL: time.Minute,
+		Now: func() time.Time { return now },
+	})
+
+	for _, key := range []string{"a", "b"} {
+		cache.Fetch(context.Background(), key)
+	}
+	now = now.Add(30 * time.Second)
+	cache.Fetch(context.Background(), "c")
+	if len(cache.entries) != 3 {
+		t.Errorf("expected %v, got %v", 3, len(cache.entries))
+	}
+
+	// a and b have expired without being read again, unlike c.
+	now = now.Add(40 * time.Second)
+	cache.Fetch(context.Background(), "d")
+	if len(cache.entries) != 2 {
+		t.Errorf("expected %v, got %v", "c and d", cache.entries)
+	}
+}
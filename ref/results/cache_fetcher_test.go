@@ -0,0 +1,55 @@
+package results_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/phildrip/toe/ref/results"
+)
+
+func TestCacheFetcher(t *testing.T) {
+	errFetch := errors.New("fetch failed")
+	now := time.Unix(0, 0)
+	calls := 0
+	var err error
+	cache := results.NewCacheFetcher(fetchFunc(func(ctx context.Context, key string) (string, error) {
+		calls++
+		return key, err
+	}), results.CacheFetcherOpts{
+		TTL: time.Minute,
+		Now: func() time.Time { return now },
+	})
+
+	// The context isn't part of the key, so the second call is a hit.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for _, ctx := range []context.Context{context.Background(), ctx} {
+		if got, err := cache.Fetch(ctx, "a"); got != "a" || err != nil {
+			t.Errorf("expected %v, got %v, %v", "a", got, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected %v, got %v", 1, calls)
+	}
+
+	err = errFetch
+	for i := 0; i < 2; i++ {
+		if _, got := cache.Fetch(context.Background(), "b"); got != errFetch {
+			t.Errorf("expected %v, got %v", errFetch, got)
+		}
+	}
+	if calls != 3 {
+		t.Errorf("expected %v, got %v", 3, calls)
+	}
+
+	err = nil
+	now = now.Add(time.Minute)
+	if _, err := cache.Fetch(context.Background(), "a"); err != nil {
+		t.Errorf("expected %v, got %v", nil, err)
+	}
+	if calls != 4 {
+		t.Errorf("expected %v, got %v", 4, calls)
+	}
+}
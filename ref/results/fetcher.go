@@ -0,0 +1,12 @@
+package results
+
+import "context"
+
+//go:generate go run ../.. -style retry -o retry_fetcher.go . Fetcher
+//go:generate go run ../.. -style cache -o cache_fetcher.go . Fetcher
+
+// Fetcher's method takes a context, which bounds the retries of its retry
+// decorator and is left out of its cache decorator's keys.
+type Fetcher interface {
+	Fetch(ctx context.Context, key string) (string, error)
+}
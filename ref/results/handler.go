@@ -0,0 +1,8 @@
+package results
+
+import "context"
+
+//go:generate go run ../.. -pkg ref_stubs -o ../stubs/stubhandler.go . Handler
+
+// Handler is a named func type, stubbed through the Call method of its stub.
+type Handler func(ctx context.Context, req string) (string, error)
@@ -0,0 +1,24 @@
+package results_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/phildrip/toe/ref/results"
+	refstubs "github.com/phildrip/toe/ref/stubs"
+	"github.com/phildrip/toe/runtime"
+)
+
+func TestHandlerFunc(t *testing.T) {
+	stub := refstubs.NewStubHandler()
+	stub.OnCall(runtime.Any(), "ping").Return("pong", nil)
+
+	var handler results.Handler = stub.Func()
+	resp, err := handler(context.Background(), "ping")
+	if err != nil || resp != "pong" {
+		t.Errorf("expected %v, got %v, %v", "pong", resp, err)
+	}
+	if stub.CallCalls.Len() != 1 || stub.CallCalls.Last().Req != "ping" {
+		t.Errorf("expected 1 call with %q, got %v", "ping", stub.CallCalls)
+	}
+}
@@ -0,0 +1,19 @@
+package results
+
+//go:generate go run ../.. -pkg ref_stubs -o ../stubs/stubresulter.go . Resulter
+
+type Point struct {
+	X, Y int
+}
+
+// Resulter returns a result of each kind of type, exercising the zero values
+// stubs return when no result is configured.
+type Resulter interface {
+	Map() map[string]int
+	Chan() <-chan int
+	Func() func() error
+	Array() [2]int
+	Struct() Point
+	Pointer() *Point
+	Values() ([]string, any, error)
+}
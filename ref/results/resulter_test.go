@@ -0,0 +1,44 @@
+package results_test
+
+import (
+	"testing"
+
+	"github.com/phildrip/toe/ref/results"
+	refstubs "github.com/phildrip/toe/ref/stubs"
+)
+
+func TestResulterZeroValues(t *testing.T) {
+	stub := refstubs.NewStubResulter()
+
+	if m := stub.Map(); m != nil {
+		t.Errorf("expected %v, got %v", nil, m)
+	}
+	if ch := stub.Chan(); ch != nil {
+		t.Errorf("expected %v, got %v", nil, ch)
+	}
+	if f := stub.Func(); f != nil {
+		t.Errorf("expected nil func, got non-nil")
+	}
+	if a := stub.Array(); a != [2]int{} {
+		t.Errorf("expected %v, got %v", [2]int{}, a)
+	}
+	if p := stub.Struct(); p != (results.Point{}) {
+		t.Errorf("expected %v, got %v", results.Point{}, p)
+	}
+	if p := stub.Pointer(); p != nil {
+		t.Errorf("expected %v, got %v", nil, p)
+	}
+	s, v, err := stub.Values()
+	if s != nil || v != nil || err != nil {
+		t.Errorf("expected %v, got %v", "nil, nil, nil", []any{s, v, err})
+	}
+
+	// Configuring one method leaves the others returning zero values.
+	stub.OnStruct().Return(results.Point{X: 1, Y: 2})
+	if p := stub.Struct(); p != (results.Point{X: 1, Y: 2}) {
+		t.Errorf("expected %v, got %v", results.Point{X: 1, Y: 2}, p)
+	}
+	if a := stub.Array(); a != [2]int{} {
+		t.Errorf("expected %v, got %v", [2]int{}, a)
+	}
+}
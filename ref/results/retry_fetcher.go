@@ -0,0 +1,88 @@
+// Code generated by github.com/phildrip/toe. DO NOT EDIT.
+//toe:style retry
+//toe:interface github.com/phildrip/toe/ref/results.Fetcher
+//toe:hash 67d2de74bfcde6c4
+//toe:version (devel)
+//toe:options {"argNaming":"param","assertions":"std"}
+
+package results
+
+import (
+	"context"
+	"time"
+)
+
+// RetryFetcherPolicy configures the retries made by RetryFetcher. The zero
+// value is usable.
+type RetryFetcherPolicy struct {
+	// MaxAttempts is the maximum number of attempts made for each call,
+	// including the first. It defaults to 3.
+	MaxAttempts int
+
+	// Backoff returns the delay before the given retry, starting at 1. It
+	// defaults to an exponential backoff starting at 100ms.
+	Backoff func(retry int) time.Duration
+
+	// Retryable reports whether a call that failed with err should be
+	// retried. By default every error is retried.
+	Retryable func(err error) bool
+
+	// After returns a channel receiving the time once d has passed, to wait
+	// for between attempts. It defaults to time.After, and can be replaced
+	// in tests.
+	After func(d time.Duration) <-chan time.Time
+}
+
+// RetryFetcher wraps a Fetcher, retrying calls that return an
+// error according to its policy. Calls taking a context stop retrying once
+// it's done, returning the last error.
+type RetryFetcher struct {
+	next   Fetcher
+	policy RetryFetcherPolicy
+}
+
+// NewRetryFetcher returns a RetryFetcher delegating to next.
+func NewRetryFetcher(next Fetcher, policy RetryFetcherPolicy) *RetryFetcher {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = 3
+	}
+	if policy.Backoff == nil {
+		policy.Backoff = func(retry int) time.Duration {
+			return 100 * time.Millisecond << (retry - 1)
+		}
+	}
+	if policy.Retryable == nil {
+		policy.Retryable = func(error) bool { return true }
+	}
+	if policy.After == nil {
+		policy.After = time.After
+	}
+	return &RetryFetcher{next: next, policy: policy}
+}
+
+// retry makes the attempts of a call, returning the last error. It stops
+// waiting for the next attempt once done is closed; done is nil for calls
+// without a context.
+func (r *RetryFetcher) retry(done <-chan struct{}, call func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := call()
+		if err == nil || attempt >= r.policy.MaxAttempts || !r.policy.Retryable(err) {
+			return err
+		}
+		select {
+		case <-r.policy.After(r.policy.Backoff(attempt)):
+		case <-done:
+			return err
+		}
+	}
+}
+
+func (r *RetryFetcher) Fetch(ctx context.Context, key string) (string, error) {
+	var r0 string
+	err := r.retry(ctx.Done(), func() error {
+		var err error
+		r0, err = r.next.Fetch(ctx, key)
+		return err
+	})
+	return r0, err
+}
@@ -0,0 +1,62 @@
+package results_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/phildrip/toe/ref/results"
+)
+
+// fetchFunc adapts a func to results.Fetcher.
+type fetchFunc func(ctx context.Context, key string) (string, error)
+
+func (f fetchFunc) Fetch(ctx context.Context, key string) (string, error) {
+	return f(ctx, key)
+}
+
+func TestRetryFetcherContextDone(t *testing.T) {
+	errTransient := errors.New("transient")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	calls := 0
+	retry := results.NewRetryFetcher(fetchFunc(func(context.Context, string) (string, error) {
+		calls++
+		cancel()
+		return "", errTransient
+	}), results.RetryFetcherPolicy{
+		// The backoff never ends, so only the context ends the wait.
+		After: func(time.Duration) <-chan time.Time { return nil },
+	})
+
+	_, err := retry.Fetch(ctx, "key")
+	if err != errTransient {
+		t.Errorf("expected %v, got %v", errTransient, err)
+	}
+	if calls != 1 {
+		t.Errorf("expected %v, got %v", 1, calls)
+	}
+}
+
+func TestRetryFetcherRetries(t *testing.T) {
+	errTransient := errors.New("transient")
+	calls := 0
+	retry := results.NewRetryFetcher(fetchFunc(func(ctx context.Context, key string) (string, error) {
+		if calls++; calls < 3 {
+			return "", errTransient
+		}
+		return key, nil
+	}), results.RetryFetcherPolicy{
+		After: func(time.Duration) <-chan time.Time { return time.After(0) },
+	})
+
+	got, err := retry.Fetch(context.Background(), "key")
+	if got != "key" || err != nil {
+		t.Errorf("expected %v, got %v, %v", "key", got, err)
+	}
+	if calls != 3 {
+		t.Errorf("expected %v, got %v", 3, calls)
+	}
+}
@@ -0,0 +1,19 @@
+package results
+
+//go:generate go run ../.. -pkg ref_stubs -o ../stubs/stubstore.go . Store
+
+// Store is a generic interface whose results are type parameters.
+type Store[K comparable, V any] interface {
+	Get(key K) (V, error)
+	Put(key K, v V) error
+	Keys() []K
+}
+
+//go:generate go run ../.. -style spy -pkg ref_stubs -o ../stubs/spyclosingstore.go . ClosingStore
+
+// ClosingStore embeds the generic Store, binding its type parameters to its
+// own.
+type ClosingStore[K comparable, V any] interface {
+	Store[K, V]
+	Close() error
+}
@@ -0,0 +1,63 @@
+package results_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/phildrip/toe/ref/results"
+	refstubs "github.com/phildrip/toe/ref/stubs"
+)
+
+func TestStoreZeroValues(t *testing.T) {
+	var store results.Store[string, results.Point] = refstubs.NewStubStore[string, results.Point]()
+
+	p, err := store.Get("a")
+	if p != (results.Point{}) || err != nil {
+		t.Errorf("expected %v, got %v", "{0 0} <nil>", []any{p, err})
+	}
+	if keys := store.Keys(); keys != nil {
+		t.Errorf("expected %v, got %v", nil, keys)
+	}
+}
+
+func TestStoreReturn(t *testing.T) {
+	stub := refstubs.NewStubStore[int, string]()
+	errMissing := errors.New("missing")
+
+	stub.OnGet(1).Return("one", nil)
+	stub.OnGet(2).Return("", errMissing)
+
+	if v, err := stub.Get(1); v != "one" || err != nil {
+		t.Errorf("expected %v, got %v", "one <nil>", []any{v, err})
+	}
+	if _, err := stub.Get(2); err != errMissing {
+		t.Errorf("expected %v, got %v", errMissing, err)
+	}
+	if stub.GetCalls.Last().Key != 2 {
+		t.Errorf("expected %v, got %v", 2, stub.GetCalls.Last().Key)
+	}
+}
+
+func TestClosingStoreSpy(t *testing.T) {
+	stub := refstubs.NewStubStore[string, int]()
+	stub.OnGet("a").Return(1, nil)
+	next := struct {
+		results.Store[string, int]
+		closer
+	}{stub, closer{}}
+
+	var store results.ClosingStore[string, int] = refstubs.NewSpyClosingStore[string, int](next)
+	if v, err := store.Get("a"); v != 1 || err != nil {
+		t.Errorf("expected %v, got %v", "1 <nil>", []any{v, err})
+	}
+	store.Close()
+
+	spy := store.(*refstubs.SpyClosingStore[string, int])
+	if spy.GetCalls.Len() != 1 || spy.GetCalls.Last().Key != "a" || spy.CloseCalls.Len() != 1 {
+		t.Errorf("expected a call to Get with %q and one to Close, got %v", "a", spy.Sequence())
+	}
+}
+
+type closer struct{}
+
+func (closer) Close() error { return nil }
@@ -0,0 +1,96 @@
+package ref_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/phildrip/toe/ref"
+	refstubs "github.com/phildrip/toe/ref/stubs"
+)
+
+// immediately returns a channel that is ready at once, so that retries
+// don't wait.
+func immediately(time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	ch <- time.Time{}
+	return ch
+}
+
+func TestRetryThinger(t *testing.T) {
+	errTransient := errors.New("transient")
+	errPermanent := errors.New("permanent")
+
+	stub := refstubs.NewStubThinger()
+	retry := ref.NewRetryThinger(stub, ref.RetryThingerPolicy{
+		MaxAttempts: 4,
+		Retryable:   func(err error) bool { return err != errPermanent },
+		After:       immediately,
+	})
+
+	stub.OnThing().Return(errTransient)
+	err := retry.Thing()
+	if err != errTransient {
+		t.Errorf("expected %v, got %v", errTransient, err)
+	}
+	if len(stub.ThingCalls) != 4 {
+		t.Errorf("expected %v, got %v", 4, len(stub.ThingCalls))
+	}
+
+	stub.OnThingWithParam().Return(errPermanent)
+	err = retry.ThingWithParam(1)
+	if err != errPermanent {
+		t.Errorf("expected %v, got %v", errPermanent, err)
+	}
+	if len(stub.ThingWithParamCalls) != 1 {
+		t.Errorf("expected %v, got %v", 1, len(stub.ThingWithParamCalls))
+	}
+
+	stub.OnThingWithParam().Return(nil)
+	err = retry.ThingWithParam(2)
+	if err != nil {
+		t.Errorf("expected %v, got %v", nil, err)
+	}
+	if len(stub.ThingWithParamCalls) != 2 {
+		t.Errorf("expected %v, got %v", 2, len(stub.ThingWithParamCalls))
+	}
+}
+
+func TestRetryThingerRecovers(t *testing.T) {
+	errTransient := errors.New("transient")
+	stub := refstubs.NewStubThinger()
+	retry := ref.NewRetryThinger(stub, ref.RetryThingerPolicy{
+		After: immediately,
+	})
+
+	stub.OnThingWithParams().
+		ReturnOnce("", errTransient).
+		ReturnOnce("", errTransient).
+		Return("done", nil)
+
+	out, err := retry.ThingWithParams(1, "a")
+	if out != "done" || err != nil {
+		t.Errorf("expected %v, got %v, %v", "done", out, err)
+	}
+	if stub.ThingWithParamsCalls.Len() != 3 {
+		t.Errorf("expected %v, got %v", 3, stub.ThingWithParamsCalls.Len())
+	}
+}
+
+func TestRetryThingerBackoff(t *testing.T) {
+	var delays []time.Duration
+	stub := refstubs.NewStubThinger()
+	stub.OnThing().Return(errors.New("error"))
+	retry := ref.NewRetryThinger(stub, ref.RetryThingerPolicy{
+		After: func(d time.Duration) <-chan time.Time {
+			delays = append(delays, d)
+			return immediately(d)
+		},
+	})
+
+	_ = retry.Thing()
+	expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}
+	if len(delays) != len(expected) || delays[0] != expected[0] || delays[1] != expected[1] {
+		t.Errorf("expected %v, got %v", expected, delays)
+	}
+}
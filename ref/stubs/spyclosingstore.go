@@ -0,0 +1,75 @@
+// Code generated by github.com/phildrip/toe. DO NOT EDIT.
+//toe:style spy
+//toe:interface github.com/phildrip/toe/ref/results.ClosingStore
+//toe:hash be8eef56460fe55e
+//toe:version (devel)
+//toe:options {"packageName":"ref_stubs","argNaming":"param","assertions":"std"}
+
+package ref_stubs
+
+import (
+	"github.com/phildrip/toe/ref/results"
+	"github.com/phildrip/toe/runtime"
+)
+
+type SpyClosingStoreCloseParams[K comparable, V any] struct {
+}
+
+type SpyClosingStoreGetParams[K comparable, V any] struct {
+	Key K
+}
+
+type SpyClosingStorePutParams[K comparable, V any] struct {
+	Key K
+	V   V
+}
+
+type SpyClosingStoreKeysParams[K comparable, V any] struct {
+}
+
+// SpyClosingStore wraps a ClosingStore, recording the arguments of every
+// call before delegating it to the wrapped value.
+type SpyClosingStore[K comparable, V any] struct {
+	CloseCalls runtime.Calls[SpyClosingStoreCloseParams[K, V]]
+	GetCalls   runtime.Calls[SpyClosingStoreGetParams[K, V]]
+	PutCalls   runtime.Calls[SpyClosingStorePutParams[K, V]]
+	KeysCalls  runtime.Calls[SpyClosingStoreKeysParams[K, V]]
+
+	next results.ClosingStore[K, V]
+	stub runtime.Stub
+}
+
+// NewSpyClosingStore returns a SpyClosingStore delegating to next.
+func NewSpyClosingStore[K comparable, V any](next results.ClosingStore[K, V]) *SpyClosingStore[K, V] {
+	return &SpyClosingStore[K, V]{next: next}
+}
+
+// Sequence returns every call made to the spy, in the order they were made.
+func (s *SpyClosingStore[K, V]) Sequence() []runtime.Call {
+	return s.stub.Calls()
+}
+
+func (s *SpyClosingStore[K, V]) Close() error {
+	runtime.Record(&s.stub, "Close", &s.CloseCalls, SpyClosingStoreCloseParams[K, V]{})
+	return s.next.Close()
+}
+
+func (s *SpyClosingStore[K, V]) Get(key K) (V, error) {
+	runtime.Record(&s.stub, "Get", &s.GetCalls, SpyClosingStoreGetParams[K, V]{
+		Key: key,
+	})
+	return s.next.Get(key)
+}
+
+func (s *SpyClosingStore[K, V]) Put(key K, v V) error {
+	runtime.Record(&s.stub, "Put", &s.PutCalls, SpyClosingStorePutParams[K, V]{
+		Key: key,
+		V:   v,
+	})
+	return s.next.Put(key, v)
+}
+
+func (s *SpyClosingStore[K, V]) Keys() []K {
+	runtime.Record(&s.stub, "Keys", &s.KeysCalls, SpyClosingStoreKeysParams[K, V]{})
+	return s.next.Keys()
+}
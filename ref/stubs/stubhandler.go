@@ -0,0 +1,277 @@
+// Code generated by github.com/phildrip/toe. DO NOT EDIT.
+//toe:style stub
+//toe:interface github.com/phildrip/toe/ref/results.Handler
+//toe:hash d9bde7a95ac00a17
+//toe:version (devel)
+//toe:options {"packageName":"ref_stubs","argNaming":"param","assertions":"std"}
+
+package ref_stubs
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/phildrip/toe/ref/results"
+	"github.com/phildrip/toe/runtime"
+)
+
+// StubHandlerCallRet holds the results of a call to Call.
+type StubHandlerCallRet struct {
+	R0 string
+	R1 error
+}
+
+// StubHandlerCallParams holds the arguments of a call to Call, as recorded in
+// CallCalls.
+type StubHandlerCallParams struct {
+	Ctx context.Context
+	Req string
+}
+
+// StubHandlerCallThen sets the results of the calls configured with
+// OnCall.
+type StubHandlerCallThen struct {
+	exp *runtime.Expectation[StubHandlerCallParams, StubHandlerCallRet]
+}
+
+// Return sets the results of the configured calls.
+func (s *StubHandlerCallThen) Return(R0 string, R1 error) *StubHandlerCallThen {
+	s.exp.Return(StubHandlerCallRet{
+		R0: R0,
+		R1: R1,
+	})
+	return s
+}
+
+// ReturnOnce adds results to be returned by a single configured call, before
+// those set with Return.
+func (s *StubHandlerCallThen) ReturnOnce(R0 string, R1 error) *StubHandlerCallThen {
+	s.exp.ReturnOnce(StubHandlerCallRet{
+		R0: R0,
+		R1: R1,
+	})
+	return s
+}
+
+// ReturnGenerated sets gen to generate the results of the configured calls,
+// such as from a property-based testing library's generators, after those
+// added with ReturnOnce. It replaces the results set with Return.
+func (s *StubHandlerCallThen) ReturnGenerated(gen func() (string, error)) *StubHandlerCallThen {
+	s.exp.ReturnFunc(func(StubHandlerCallParams) StubHandlerCallRet {
+		var ret StubHandlerCallRet
+		ret.R0, ret.R1 = gen()
+		return ret
+	})
+	return s
+}
+
+// TimeoutAfter makes the configured calls block until ctx is done, or
+// d passes on the clock passed to NewStubHandler with runtime.WithClock, or the
+// system clock, and return its error or else context.DeadlineExceeded, with
+// zero values for the other results.
+func (s *StubHandlerCallThen) TimeoutAfter(d time.Duration) *StubHandlerCallThen {
+	s.exp.TimeoutAfter(d)
+	return s
+}
+
+// FailRandomly makes each configured call fail with probability rate,
+// returning err with zero values for the other results. The failures are
+// derived from seed, so the same calls fail on every run.
+func (s *StubHandlerCallThen) FailRandomly(rate float64, err error, seed uint64) *StubHandlerCallThen {
+	s.exp.FailRandomly(rate, err, seed)
+	return s
+}
+
+// MaxConcurrent makes the configured calls return err, with zero values for
+// the other results, while n others are in progress, such as running the
+// function set with ReturnGenerated, to simulate a dependency limiting
+// concurrency.
+func (s *StubHandlerCallThen) MaxConcurrent(n int, err error) *StubHandlerCallThen {
+	s.exp.MaxConcurrent(n, StubHandlerCallRet{R1: err})
+	return s
+}
+
+// Latency makes the configured calls take a duration drawn from l, with a
+// pseudo-random sequence derived from seed, on the clock passed to
+// NewStubHandler with runtime.WithClock, or the system clock.
+func (s *StubHandlerCallThen) Latency(l runtime.Latency, seed uint64) *StubHandlerCallThen {
+	s.exp.Latency(l, seed)
+	return s
+}
+
+// MaxTimes sets the most calls the configuration may match. Each later
+// matching call fails the test passed to NewStubHandler with runtime.WithT,
+// or panics without one.
+func (s *StubHandlerCallThen) MaxTimes(n int) *StubHandlerCallThen {
+	s.exp.MaxTimes(n)
+	return s
+}
+
+// NewStubHandler returns a StubHandler whose methods return zero values
+// until configured, or panic if opts include runtime.Strict().
+func NewStubHandler(opts ...runtime.Option) *StubHandler {
+	s := &StubHandler{}
+	s.stub.Init("StubHandler", opts...)
+	return s
+}
+
+// StubHandler is a stub implementation of Handler. Each method
+// records its arguments in the method's Calls field and returns the results
+// configured with its On method, or zero values. Its methods may be called
+// concurrently, including while it is being configured.
+//
+// Handler is a func type, stubbed by the Call method: pass the func
+// returned by Func to the code under test.
+type StubHandler struct {
+	// CallCalls holds the arguments of each call to Call, in order.
+	CallCalls runtime.Calls[StubHandlerCallParams]
+
+	stub runtime.Stub
+}
+
+// runtimeStub returns the runtime.Stub backing the stub, named lazily so that
+// a zero StubHandler is ready to use without NewStubHandler.
+func (s *StubHandler) runtimeStub() *runtime.Stub {
+	return s.stub.Named("StubHandler")
+}
+
+// Func returns a Handler calling s.Call.
+func (s *StubHandler) Func() results.Handler {
+	return s.Call
+}
+
+// Sequence returns every call made to the stub, in the order they were made.
+func (s *StubHandler) Sequence() []runtime.Call {
+	return s.runtimeStub().Calls()
+}
+
+// Verify fails t if the calls made to the stub fail the checks added with the
+// Expect methods. Stubs created with runtime.WithT are verified when the test
+// finishes.
+func (s *StubHandler) Verify(t runtime.TB) {
+	if err := s.runtimeStub().Verify(); err != nil {
+		t.Errorf("%v", err)
+	}
+}
+
+// Scope scopes the stub to the test t, usually a subtest sharing a stub
+// configured by its parent: the stub's recorded calls are cleared, and once t
+// finishes they are restored, along with the results configured with the On
+// methods as they were when Scope was called. Tests sharing a stub can't run
+// in parallel.
+func (s *StubHandler) Scope(t runtime.TB) {
+	s.runtimeStub().Scope(t,
+		runtime.ResetCalls(&s.CallCalls),
+	)
+}
+
+// DumpInteractions writes the calls made to the stub to w in format,
+// runtime.FormatDOT for a Graphviz graph of the timeline or
+// runtime.FormatJSON. Stubs sharing a runtime.Recorder write the calls made
+// to each of them.
+func (s *StubHandler) DumpInteractions(w io.Writer, format string) error {
+	return s.runtimeStub().DumpInteractions(w, format)
+}
+
+// RateLimit limits the calls to the stub's methods returning an error, or
+// to those named in methods, to n every per, as a token bucket: calls over
+// the limit return err, with zero values for the other results. Time is
+// measured with the clock passed to NewStubHandler with runtime.WithClock,
+// or the system clock.
+func (s *StubHandler) RateLimit(n int, per time.Duration, err error, methods ...string) {
+	s.runtimeStub().RateLimit(n, per, err, methods...)
+}
+
+// AllowSequence restricts the order in which the methods named in steps are
+// called: each step is a method name, followed by "?" if the call is
+// optional, "*" if it may be repeated or "+" if it must be made at least
+// once. Calls out of the sequence fail the test bound with runtime.WithT, or
+// panic, and Verify fails if the calls stop before its end.
+func (s *StubHandler) AllowSequence(steps ...string) {
+	s.runtimeStub().AllowSequence([]string{"Call"}, steps...)
+}
+
+// SnapshotConfig returns a snapshot of the stub's configuration, made with its
+// On, Expect and other methods, for RestoreConfig.
+func (s *StubHandler) SnapshotConfig() runtime.Config {
+	return s.runtimeStub().SnapshotConfig()
+}
+
+// RestoreConfig restores the stub's configuration to a snapshot taken with
+// SnapshotConfig, such as a baseline shared by several scenarios, keeping
+// the calls recorded.
+func (s *StubHandler) RestoreConfig(c runtime.Config) {
+	s.runtimeStub().RestoreConfig(c)
+}
+
+// Clone returns a new stub with copies of the results and checks configured
+// on the stub, and the options it was created with, but none of its recorded
+// calls, so that table-driven tests can derive a stub for each case from a
+// shared base: configuring or calling either stub doesn't affect the other.
+func (s *StubHandler) Clone() *StubHandler {
+	clone := &StubHandler{}
+	s.runtimeStub().CloneTo(&clone.stub)
+	return clone
+}
+
+// CaptureContextValues records the values of keys in the context argument of
+// each later call, in the calls' ContextValues returned by Sequence.
+func (s *StubHandler) CaptureContextValues(keys ...any) {
+	s.runtimeStub().CaptureContextValues(keys...)
+}
+
+// PropagateContextErrors sets whether calls whose context is done
+// short-circuit, skipping the configured results: methods returning an error
+// return the context's error, and the others zero values.
+func (s *StubHandler) PropagateContextErrors(on bool) {
+	s.runtimeStub().PropagateContextErrors(on)
+}
+
+// Begin StubHandler.Call
+
+// Call records the call in CallCalls and returns the results
+// configured with OnCall, or zero values if none match.
+func (s *StubHandler) Call(ctx context.Context, req string) (string, error) {
+	ret := runtime.Invoke[StubHandlerCallRet](s.runtimeStub(), "Call", &s.CallCalls, StubHandlerCallParams{
+		Ctx: ctx,
+		Req: req,
+	})
+	if err := s.runtimeStub().ContextErr(ctx); err != nil {
+		ret = StubHandlerCallRet{R1: err}
+	}
+	return ret.R0, ret.R1
+}
+
+// OnCall configures calls to Call whose arguments match args.
+// Each of args is a runtime.Matcher or a value the argument must equal; with
+// no args, every call is matched.
+func (s *StubHandler) OnCall(args ...any) *StubHandlerCallThen {
+	return &StubHandlerCallThen{
+		exp: runtime.On[StubHandlerCallParams, StubHandlerCallRet](s.runtimeStub(), "Call", args...),
+	}
+}
+
+// AssertCallCalledWith fails t unless Call was called with the
+// arguments in want, showing a diff against the closest call.
+func (s *StubHandler) AssertCallCalledWith(t runtime.TB, want StubHandlerCallParams) {
+	runtime.AssertCalledWith(t, s.runtimeStub(), "Call", &s.CallCalls, want)
+}
+
+// ExpectCall adds a check, made by Verify, on the calls to Call
+// whose arguments match args, which are as for OnCall.
+func (s *StubHandler) ExpectCall(args ...any) *runtime.Verification {
+	return runtime.Expect(s.runtimeStub(), "Call", args...)
+}
+
+// RecordCall records a call to Call without returning results,
+// for types embedding the stub that override Call, so their calls are
+// checked like those of the stub.
+func (s *StubHandler) RecordCall(ctx context.Context, req string) {
+	runtime.Record(s.runtimeStub(), "Call", &s.CallCalls, StubHandlerCallParams{
+		Ctx: ctx,
+		Req: req,
+	})
+}
+
+// End StubHandler.Call
@@ -0,0 +1,814 @@
+// Code generated by github.com/phildrip/toe. DO NOT EDIT.
+//toe:style stub
+//toe:interface github.com/phildrip/toe/ref/results.Resulter
+//toe:hash fd8e0a29dc374e62
+//toe:version (devel)
+//toe:options {"packageName":"ref_stubs","argNaming":"param","assertions":"std"}
+
+package ref_stubs
+
+import (
+	"io"
+	"time"
+
+	"github.com/phildrip/toe/ref/results"
+	"github.com/phildrip/toe/runtime"
+)
+
+// StubResulterMapRet holds the results of a call to Map.
+type StubResulterMapRet struct {
+	R0 map[string]int
+}
+
+// StubResulterMapParams holds the arguments of a call to Map, as recorded in
+// MapCalls.
+type StubResulterMapParams struct {
+}
+
+// StubResulterMapThen sets the results of the calls configured with
+// OnMap.
+type StubResulterMapThen struct {
+	exp *runtime.Expectation[StubResulterMapParams, StubResulterMapRet]
+}
+
+// Return sets the results of the configured calls.
+func (s *StubResulterMapThen) Return(R0 map[string]int) *StubResulterMapThen {
+	s.exp.Return(StubResulterMapRet{
+		R0: R0,
+	})
+	return s
+}
+
+// ReturnOnce adds results to be returned by a single configured call, before
+// those set with Return.
+func (s *StubResulterMapThen) ReturnOnce(R0 map[string]int) *StubResulterMapThen {
+	s.exp.ReturnOnce(StubResulterMapRet{
+		R0: R0,
+	})
+	return s
+}
+
+// ReturnGenerated sets gen to generate the results of the configured calls,
+// such as from a property-based testing library's generators, after those
+// added with ReturnOnce. It replaces the results set with Return.
+func (s *StubResulterMapThen) ReturnGenerated(gen func() map[string]int) *StubResulterMapThen {
+	s.exp.ReturnFunc(func(StubResulterMapParams) StubResulterMapRet {
+		var ret StubResulterMapRet
+		ret.R0 = gen()
+		return ret
+	})
+	return s
+}
+
+// Latency makes the configured calls take a duration drawn from l, with a
+// pseudo-random sequence derived from seed, on the clock passed to
+// NewStubResulter with runtime.WithClock, or the system clock.
+func (s *StubResulterMapThen) Latency(l runtime.Latency, seed uint64) *StubResulterMapThen {
+	s.exp.Latency(l, seed)
+	return s
+}
+
+// MaxTimes sets the most calls the configuration may match. Each later
+// matching call fails the test passed to NewStubResulter with runtime.WithT,
+// or panics without one.
+func (s *StubResulterMapThen) MaxTimes(n int) *StubResulterMapThen {
+	s.exp.MaxTimes(n)
+	return s
+}
+
+// StubResulterChanRet holds the results of a call to Chan.
+type StubResulterChanRet struct {
+	R0 <-chan int
+}
+
+// StubResulterChanParams holds the arguments of a call to Chan, as recorded in
+// ChanCalls.
+type StubResulterChanParams struct {
+}
+
+// StubResulterChanThen sets the results of the calls configured with
+// OnChan.
+type StubResulterChanThen struct {
+	exp *runtime.Expectation[StubResulterChanParams, StubResulterChanRet]
+}
+
+// Return sets the results of the configured calls.
+func (s *StubResulterChanThen) Return(R0 <-chan int) *StubResulterChanThen {
+	s.exp.Return(StubResulterChanRet{
+		R0: R0,
+	})
+	return s
+}
+
+// ReturnOnce adds results to be returned by a single configured call, before
+// those set with Return.
+func (s *StubResulterChanThen) ReturnOnce(R0 <-chan int) *StubResulterChanThen {
+	s.exp.ReturnOnce(StubResulterChanRet{
+		R0: R0,
+	})
+	return s
+}
+
+// ReturnGenerated sets gen to generate the results of the configured calls,
+// such as from a property-based testing library's generators, after those
+// added with ReturnOnce. It replaces the results set with Return.
+func (s *StubResulterChanThen) ReturnGenerated(gen func() <-chan int) *StubResulterChanThen {
+	s.exp.ReturnFunc(func(StubResulterChanParams) StubResulterChanRet {
+		var ret StubResulterChanRet
+		ret.R0 = gen()
+		return ret
+	})
+	return s
+}
+
+// Latency makes the configured calls take a duration drawn from l, with a
+// pseudo-random sequence derived from seed, on the clock passed to
+// NewStubResulter with runtime.WithClock, or the system clock.
+func (s *StubResulterChanThen) Latency(l runtime.Latency, seed uint64) *StubResulterChanThen {
+	s.exp.Latency(l, seed)
+	return s
+}
+
+// MaxTimes sets the most calls the configuration may match. Each later
+// matching call fails the test passed to NewStubResulter with runtime.WithT,
+// or panics without one.
+func (s *StubResulterChanThen) MaxTimes(n int) *StubResulterChanThen {
+	s.exp.MaxTimes(n)
+	return s
+}
+
+// StubResulterFuncRet holds the results of a call to Func.
+type StubResulterFuncRet struct {
+	R0 func() error
+}
+
+// StubResulterFuncParams holds the arguments of a call to Func, as recorded in
+// FuncCalls.
+type StubResulterFuncParams struct {
+}
+
+// StubResulterFuncThen sets the results of the calls configured with
+// OnFunc.
+type StubResulterFuncThen struct {
+	exp *runtime.Expectation[StubResulterFuncParams, StubResulterFuncRet]
+}
+
+// Return sets the results of the configured calls.
+func (s *StubResulterFuncThen) Return(R0 func() error) *StubResulterFuncThen {
+	s.exp.Return(StubResulterFuncRet{
+		R0: R0,
+	})
+	return s
+}
+
+// ReturnOnce adds results to be returned by a single configured call, before
+// those set with Return.
+func (s *StubResulterFuncThen) ReturnOnce(R0 func() error) *StubResulterFuncThen {
+	s.exp.ReturnOnce(StubResulterFuncRet{
+		R0: R0,
+	})
+	return s
+}
+
+// ReturnGenerated sets gen to generate the results of the configured calls,
+// such as from a property-based testing library's generators, after those
+// added with ReturnOnce. It replaces the results set with Return.
+func (s *StubResulterFuncThen) ReturnGenerated(gen func() func() error) *StubResulterFuncThen {
+	s.exp.ReturnFunc(func(StubResulterFuncParams) StubResulterFuncRet {
+		var ret StubResulterFuncRet
+		ret.R0 = gen()
+		return ret
+	})
+	return s
+}
+
+// Latency makes the configured calls take a duration drawn from l, with a
+// pseudo-random sequence derived from seed, on the clock passed to
+// NewStubResulter with runtime.WithClock, or the system clock.
+func (s *StubResulterFuncThen) Latency(l runtime.Latency, seed uint64) *StubResulterFuncThen {
+	s.exp.Latency(l, seed)
+	return s
+}
+
+// MaxTimes sets the most calls the configuration may match. Each later
+// matching call fails the test passed to NewStubResulter with runtime.WithT,
+// or panics without one.
+func (s *StubResulterFuncThen) MaxTimes(n int) *StubResulterFuncThen {
+	s.exp.MaxTimes(n)
+	return s
+}
+
+// StubResulterArrayRet holds the results of a call to Array.
+type StubResulterArrayRet struct {
+	R0 [2]int
+}
+
+// StubResulterArrayParams holds the arguments of a call to Array, as recorded in
+// ArrayCalls.
+type StubResulterArrayParams struct {
+}
+
+// StubResulterArrayThen sets the results of the calls configured with
+// OnArray.
+type StubResulterArrayThen struct {
+	exp *runtime.Expectation[StubResulterArrayParams, StubResulterArrayRet]
+}
+
+// Return sets the results of the configured calls.
+func (s *StubResulterArrayThen) Return(R0 [2]int) *StubResulterArrayThen {
+	s.exp.Return(StubResulterArrayRet{
+		R0: R0,
+	})
+	return s
+}
+
+// ReturnOnce adds results to be returned by a single configured call, before
+// those set with Return.
+func (s *StubResulterArrayThen) ReturnOnce(R0 [2]int) *StubResulterArrayThen {
+	s.exp.ReturnOnce(StubResulterArrayRet{
+		R0: R0,
+	})
+	return s
+}
+
+// ReturnGenerated sets gen to generate the results of the configured calls,
+// such as from a property-based testing library's generators, after those
+// added with ReturnOnce. It replaces the results set with Return.
+func (s *StubResulterArrayThen) ReturnGenerated(gen func() [2]int) *StubResulterArrayThen {
+	s.exp.ReturnFunc(func(StubResulterArrayParams) StubResulterArrayRet {
+		var ret StubResulterArrayRet
+		ret.R0 = gen()
+		return ret
+	})
+	return s
+}
+
+// Latency makes the configured calls take a duration drawn from l, with a
+// pseudo-random sequence derived from seed, on the clock passed to
+// NewStubResulter with runtime.WithClock, or the system clock.
+func (s *StubResulterArrayThen) Latency(l runtime.Latency, seed uint64) *StubResulterArrayThen {
+	s.exp.Latency(l, seed)
+	return s
+}
+
+// MaxTimes sets the most calls the configuration may match. Each later
+// matching call fails the test passed to NewStubResulter with runtime.WithT,
+// or panics without one.
+func (s *StubResulterArrayThen) MaxTimes(n int) *StubResulterArrayThen {
+	s.exp.MaxTimes(n)
+	return s
+}
+
+// StubResulterStructRet holds the results of a call to Struct.
+type StubResulterStructRet struct {
+	R0 results.Point
+}
+
+// StubResulterStructParams holds the arguments of a call to Struct, as recorded in
+// StructCalls.
+type StubResulterStructParams struct {
+}
+
+// StubResulterStructThen sets the results of the calls configured with
+// OnStruct.
+type StubResulterStructThen struct {
+	exp *runtime.Expectation[StubResulterStructParams, StubResulterStructRet]
+}
+
+// Return sets the results of the configured calls.
+func (s *StubResulterStructThen) Return(R0 results.Point) *StubResulterStructThen {
+	s.exp.Return(StubResulterStructRet{
+		R0: R0,
+	})
+	return s
+}
+
+// ReturnOnce adds results to be returned by a single configured call, before
+// those set with Return.
+func (s *StubResulterStructThen) ReturnOnce(R0 results.Point) *StubResulterStructThen {
+	s.exp.ReturnOnce(StubResulterStructRet{
+		R0: R0,
+	})
+	return s
+}
+
+// ReturnGenerated sets gen to generate the results of the configured calls,
+// such as from a property-based testing library's generators, after those
+// added with ReturnOnce. It replaces the results set with Return.
+func (s *StubResulterStructThen) ReturnGenerated(gen func() results.Point) *StubResulterStructThen {
+	s.exp.ReturnFunc(func(StubResulterStructParams) StubResulterStructRet {
+		var ret StubResulterStructRet
+		ret.R0 = gen()
+		return ret
+	})
+	return s
+}
+
+// Latency makes the configured calls take a duration drawn from l, with a
+// pseudo-random sequence derived from seed, on the clock passed to
+// NewStubResulter with runtime.WithClock, or the system clock.
+func (s *StubResulterStructThen) Latency(l runtime.Latency, seed uint64) *StubResulterStructThen {
+	s.exp.Latency(l, seed)
+	return s
+}
+
+// MaxTimes sets the most calls the configuration may match. Each later
+// matching call fails the test passed to NewStubResulter with runtime.WithT,
+// or panics without one.
+func (s *StubResulterStructThen) MaxTimes(n int) *StubResulterStructThen {
+	s.exp.MaxTimes(n)
+	return s
+}
+
+// StubResulterPointerRet holds the results of a call to Pointer.
+type StubResulterPointerRet struct {
+	R0 *results.Point
+}
+
+// StubResulterPointerParams holds the arguments of a call to Pointer, as recorded in
+// PointerCalls.
+type StubResulterPointerParams struct {
+}
+
+// StubResulterPointerThen sets the results of the calls configured with
+// OnPointer.
+type StubResulterPointerThen struct {
+	exp *runtime.Expectation[StubResulterPointerParams, StubResulterPointerRet]
+}
+
+// Return sets the results of the configured calls.
+func (s *StubResulterPointerThen) Return(R0 *results.Point) *StubResulterPointerThen {
+	s.exp.Return(StubResulterPointerRet{
+		R0: R0,
+	})
+	return s
+}
+
+// ReturnOnce adds results to be returned by a single configured call, before
+// those set with Return.
+func (s *StubResulterPointerThen) ReturnOnce(R0 *results.Point) *StubResulterPointerThen {
+	s.exp.ReturnOnce(StubResulterPointerRet{
+		R0: R0,
+	})
+	return s
+}
+
+// ReturnGenerated sets gen to generate the results of the configured calls,
+// such as from a property-based testing library's generators, after those
+// added with ReturnOnce. It replaces the results set with Return.
+func (s *StubResulterPointerThen) ReturnGenerated(gen func() *results.Point) *StubResulterPointerThen {
+	s.exp.ReturnFunc(func(StubResulterPointerParams) StubResulterPointerRet {
+		var ret StubResulterPointerRet
+		ret.R0 = gen()
+		return ret
+	})
+	return s
+}
+
+// Latency makes the configured calls take a duration drawn from l, with a
+// pseudo-random sequence derived from seed, on the clock passed to
+// NewStubResulter with runtime.WithClock, or the system clock.
+func (s *StubResulterPointerThen) Latency(l runtime.Latency, seed uint64) *StubResulterPointerThen {
+	s.exp.Latency(l, seed)
+	return s
+}
+
+// MaxTimes sets the most calls the configuration may match. Each later
+// matching call fails the test passed to NewStubResulter with runtime.WithT,
+// or panics without one.
+func (s *StubResulterPointerThen) MaxTimes(n int) *StubResulterPointerThen {
+	s.exp.MaxTimes(n)
+	return s
+}
+
+// StubResulterValuesRet holds the results of a call to Values.
+type StubResulterValuesRet struct {
+	R0 []string
+	R1 any
+	R2 error
+}
+
+// StubResulterValuesParams holds the arguments of a call to Values, as recorded in
+// ValuesCalls.
+type StubResulterValuesParams struct {
+}
+
+// StubResulterValuesThen sets the results of the calls configured with
+// OnValues.
+type StubResulterValuesThen struct {
+	exp *runtime.Expectation[StubResulterValuesParams, StubResulterValuesRet]
+}
+
+// Return sets the results of the configured calls.
+func (s *StubResulterValuesThen) Return(R0 []string, R1 any, R2 error) *StubResulterValuesThen {
+	s.exp.Return(StubResulterValuesRet{
+		R0: R0,
+		R1: R1,
+		R2: R2,
+	})
+	return s
+}
+
+// ReturnOnce adds results to be returned by a single configured call, before
+// those set with Return.
+func (s *StubResulterValuesThen) ReturnOnce(R0 []string, R1 any, R2 error) *StubResulterValuesThen {
+	s.exp.ReturnOnce(StubResulterValuesRet{
+		R0: R0,
+		R1: R1,
+		R2: R2,
+	})
+	return s
+}
+
+// ReturnGenerated sets gen to generate the results of the configured calls,
+// such as from a property-based testing library's generators, after those
+// added with ReturnOnce. It replaces the results set with Return.
+func (s *StubResulterValuesThen) ReturnGenerated(gen func() ([]string, any, error)) *StubResulterValuesThen {
+	s.exp.ReturnFunc(func(StubResulterValuesParams) StubResulterValuesRet {
+		var ret StubResulterValuesRet
+		ret.R0, ret.R1, ret.R2 = gen()
+		return ret
+	})
+	return s
+}
+
+// FailRandomly makes each configured call fail with probability rate,
+// returning err with zero values for the other results. The failures are
+// derived from seed, so the same calls fail on every run.
+func (s *StubResulterValuesThen) FailRandomly(rate float64, err error, seed uint64) *StubResulterValuesThen {
+	s.exp.FailRandomly(rate, err, seed)
+	return s
+}
+
+// MaxConcurrent makes the configured calls return err, with zero values for
+// the other results, while n others are in progress, such as running the
+// function set with ReturnGenerated, to simulate a dependency limiting
+// concurrency.
+func (s *StubResulterValuesThen) MaxConcurrent(n int, err error) *StubResulterValuesThen {
+	s.exp.MaxConcurrent(n, StubResulterValuesRet{R2: err})
+	return s
+}
+
+// Latency makes the configured calls take a duration drawn from l, with a
+// pseudo-random sequence derived from seed, on the clock passed to
+// NewStubResulter with runtime.WithClock, or the system clock.
+func (s *StubResulterValuesThen) Latency(l runtime.Latency, seed uint64) *StubResulterValuesThen {
+	s.exp.Latency(l, seed)
+	return s
+}
+
+// MaxTimes sets the most calls the configuration may match. Each later
+// matching call fails the test passed to NewStubResulter with runtime.WithT,
+// or panics without one.
+func (s *StubResulterValuesThen) MaxTimes(n int) *StubResulterValuesThen {
+	s.exp.MaxTimes(n)
+	return s
+}
+
+// NewStubResulter returns a StubResulter whose methods return zero values
+// until configured, or panic if opts include runtime.Strict().
+func NewStubResulter(opts ...runtime.Option) *StubResulter {
+	s := &StubResulter{}
+	s.stub.Init("StubResulter", opts...)
+	return s
+}
+
+// StubResulter is a stub implementation of Resulter. Each method
+// records its arguments in the method's Calls field and returns the results
+// configured with its On method, or zero values. Its methods may be called
+// concurrently, including while it is being configured.
+type StubResulter struct {
+	// MapCalls holds the arguments of each call to Map, in order.
+	MapCalls runtime.Calls[StubResulterMapParams]
+	// ChanCalls holds the arguments of each call to Chan, in order.
+	ChanCalls runtime.Calls[StubResulterChanParams]
+	// FuncCalls holds the arguments of each call to Func, in order.
+	FuncCalls runtime.Calls[StubResulterFuncParams]
+	// ArrayCalls holds the arguments of each call to Array, in order.
+	ArrayCalls runtime.Calls[StubResulterArrayParams]
+	// StructCalls holds the arguments of each call to Struct, in order.
+	StructCalls runtime.Calls[StubResulterStructParams]
+	// PointerCalls holds the arguments of each call to Pointer, in order.
+	PointerCalls runtime.Calls[StubResulterPointerParams]
+	// ValuesCalls holds the arguments of each call to Values, in order.
+	ValuesCalls runtime.Calls[StubResulterValuesParams]
+
+	stub runtime.Stub
+}
+
+// runtimeStub returns the runtime.Stub backing the stub, named lazily so that
+// a zero StubResulter is ready to use without NewStubResulter.
+func (s *StubResulter) runtimeStub() *runtime.Stub {
+	return s.stub.Named("StubResulter")
+}
+
+// Sequence returns every call made to the stub, in the order they were made.
+func (s *StubResulter) Sequence() []runtime.Call {
+	return s.runtimeStub().Calls()
+}
+
+// Verify fails t if the calls made to the stub fail the checks added with the
+// Expect methods. Stubs created with runtime.WithT are verified when the test
+// finishes.
+func (s *StubResulter) Verify(t runtime.TB) {
+	if err := s.runtimeStub().Verify(); err != nil {
+		t.Errorf("%v", err)
+	}
+}
+
+// Scope scopes the stub to the test t, usually a subtest sharing a stub
+// configured by its parent: the stub's recorded calls are cleared, and once t
+// finishes they are restored, along with the results configured with the On
+// methods as they were when Scope was called. Tests sharing a stub can't run
+// in parallel.
+func (s *StubResulter) Scope(t runtime.TB) {
+	s.runtimeStub().Scope(t,
+		runtime.ResetCalls(&s.MapCalls),
+		runtime.ResetCalls(&s.ChanCalls),
+		runtime.ResetCalls(&s.FuncCalls),
+		runtime.ResetCalls(&s.ArrayCalls),
+		runtime.ResetCalls(&s.StructCalls),
+		runtime.ResetCalls(&s.PointerCalls),
+		runtime.ResetCalls(&s.ValuesCalls),
+	)
+}
+
+// DumpInteractions writes the calls made to the stub to w in format,
+// runtime.FormatDOT for a Graphviz graph of the timeline or
+// runtime.FormatJSON. Stubs sharing a runtime.Recorder write the calls made
+// to each of them.
+func (s *StubResulter) DumpInteractions(w io.Writer, format string) error {
+	return s.runtimeStub().DumpInteractions(w, format)
+}
+
+// RateLimit limits the calls to the stub's methods returning an error, or
+// to those named in methods, to n every per, as a token bucket: calls over
+// the limit return err, with zero values for the other results. Time is
+// measured with the clock passed to NewStubResulter with runtime.WithClock,
+// or the system clock.
+func (s *StubResulter) RateLimit(n int, per time.Duration, err error, methods ...string) {
+	s.runtimeStub().RateLimit(n, per, err, methods...)
+}
+
+// AllowSequence restricts the order in which the methods named in steps are
+// called: each step is a method name, followed by "?" if the call is
+// optional, "*" if it may be repeated or "+" if it must be made at least
+// once. Calls out of the sequence fail the test bound with runtime.WithT, or
+// panic, and Verify fails if the calls stop before its end.
+func (s *StubResulter) AllowSequence(steps ...string) {
+	s.runtimeStub().AllowSequence([]string{"Map", "Chan", "Func", "Array", "Struct", "Pointer", "Values"}, steps...)
+}
+
+// SnapshotConfig returns a snapshot of the stub's configuration, made with its
+// On, Expect and other methods, for RestoreConfig.
+func (s *StubResulter) SnapshotConfig() runtime.Config {
+	return s.runtimeStub().SnapshotConfig()
+}
+
+// RestoreConfig restores the stub's configuration to a snapshot taken with
+// SnapshotConfig, such as a baseline shared by several scenarios, keeping
+// the calls recorded.
+func (s *StubResulter) RestoreConfig(c runtime.Config) {
+	s.runtimeStub().RestoreConfig(c)
+}
+
+// Clone returns a new stub with copies of the results and checks configured
+// on the stub, and the options it was created with, but none of its recorded
+// calls, so that table-driven tests can derive a stub for each case from a
+// shared base: configuring or calling either stub doesn't affect the other.
+func (s *StubResulter) Clone() *StubResulter {
+	clone := &StubResulter{}
+	s.runtimeStub().CloneTo(&clone.stub)
+	return clone
+}
+
+// Begin StubResulter.Map
+
+// Map records the call in MapCalls and returns the results
+// configured with OnMap, or zero values if none match.
+func (s *StubResulter) Map() map[string]int {
+	ret := runtime.Invoke[StubResulterMapRet](s.runtimeStub(), "Map", &s.MapCalls, StubResulterMapParams{})
+	return ret.R0
+}
+
+// OnMap configures calls to Map whose arguments match args.
+// Each of args is a runtime.Matcher or a value the argument must equal; with
+// no args, every call is matched.
+func (s *StubResulter) OnMap(args ...any) *StubResulterMapThen {
+	return &StubResulterMapThen{
+		exp: runtime.On[StubResulterMapParams, StubResulterMapRet](s.runtimeStub(), "Map", args...),
+	}
+}
+
+// ExpectMap adds a check, made by Verify, on the calls to Map
+// whose arguments match args, which are as for OnMap.
+func (s *StubResulter) ExpectMap(args ...any) *runtime.Verification {
+	return runtime.Expect(s.runtimeStub(), "Map", args...)
+}
+
+// RecordMap records a call to Map without returning results,
+// for types embedding the stub that override Map, so their calls are
+// checked like those of the stub.
+func (s *StubResulter) RecordMap() {
+	runtime.Record(s.runtimeStub(), "Map", &s.MapCalls, StubResulterMapParams{})
+}
+
+// End StubResulter.Map
+
+// Begin StubResulter.Chan
+
+// Chan records the call in ChanCalls and returns the results
+// configured with OnChan, or zero values if none match.
+func (s *StubResulter) Chan() <-chan int {
+	ret := runtime.Invoke[StubResulterChanRet](s.runtimeStub(), "Chan", &s.ChanCalls, StubResulterChanParams{})
+	return ret.R0
+}
+
+// OnChan configures calls to Chan whose arguments match args.
+// Each of args is a runtime.Matcher or a value the argument must equal; with
+// no args, every call is matched.
+func (s *StubResulter) OnChan(args ...any) *StubResulterChanThen {
+	return &StubResulterChanThen{
+		exp: runtime.On[StubResulterChanParams, StubResulterChanRet](s.runtimeStub(), "Chan", args...),
+	}
+}
+
+// ExpectChan adds a check, made by Verify, on the calls to Chan
+// whose arguments match args, which are as for OnChan.
+func (s *StubResulter) ExpectChan(args ...any) *runtime.Verification {
+	return runtime.Expect(s.runtimeStub(), "Chan", args...)
+}
+
+// RecordChan records a call to Chan without returning results,
+// for types embedding the stub that override Chan, so their calls are
+// checked like those of the stub.
+func (s *StubResulter) RecordChan() {
+	runtime.Record(s.runtimeStub(), "Chan", &s.ChanCalls, StubResulterChanParams{})
+}
+
+// End StubResulter.Chan
+
+// Begin StubResulter.Func
+
+// Func records the call in FuncCalls and returns the results
+// configured with OnFunc, or zero values if none match.
+func (s *StubResulter) Func() func() error {
+	ret := runtime.Invoke[StubResulterFuncRet](s.runtimeStub(), "Func", &s.FuncCalls, StubResulterFuncParams{})
+	return ret.R0
+}
+
+// OnFunc configures calls to Func whose arguments match args.
+// Each of args is a runtime.Matcher or a value the argument must equal; with
+// no args, every call is matched.
+func (s *StubResulter) OnFunc(args ...any) *StubResulterFuncThen {
+	return &StubResulterFuncThen{
+		exp: runtime.On[StubResulterFuncParams, StubResulterFuncRet](s.runtimeStub(), "Func", args...),
+	}
+}
+
+// ExpectFunc adds a check, made by Verify, on the calls to Func
+// whose arguments match args, which are as for OnFunc.
+func (s *StubResulter) ExpectFunc(args ...any) *runtime.Verification {
+	return runtime.Expect(s.runtimeStub(), "Func", args...)
+}
+
+// RecordFunc records a call to Func without returning results,
+// for types embedding the stub that override Func, so their calls are
+// checked like those of the stub.
+func (s *StubResulter) RecordFunc() {
+	runtime.Record(s.runtimeStub(), "Func", &s.FuncCalls, StubResulterFuncParams{})
+}
+
+// End StubResulter.Func
+
+// Begin StubResulter.Array
+
+// Array records the call in ArrayCalls and returns the results
+// configured with OnArray, or zero values if none match.
+func (s *StubResulter) Array() [2]int {
+	ret := runtime.Invoke[StubResulterArrayRet](s.runtimeStub(), "Array", &s.ArrayCalls, StubResulterArrayParams{})
+	return ret.R0
+}
+
+// OnArray configures calls to Array whose arguments match args.
+// Each of args is a runtime.Matcher or a value the argument must equal; with
+// no args, every call is matched.
+func (s *StubResulter) OnArray(args ...any) *StubResulterArrayThen {
+	return &StubResulterArrayThen{
+		exp: runtime.On[StubResulterArrayParams, StubResulterArrayRet](s.runtimeStub(), "Array", args...),
+	}
+}
+
+// ExpectArray adds a check, made by Verify, on the calls to Array
+// whose arguments match args, which are as for OnArray.
+func (s *StubResulter) ExpectArray(args ...any) *runtime.Verification {
+	return runtime.Expect(s.runtimeStub(), "Array", args...)
+}
+
+// RecordArray records a call to Array without returning results,
+// for types embedding the stub that override Array, so their calls are
+// checked like those of the stub.
+func (s *StubResulter) RecordArray() {
+	runtime.Record(s.runtimeStub(), "Array", &s.ArrayCalls, StubResulterArrayParams{})
+}
+
+// End StubResulter.Array
+
+// Begin StubResulter.Struct
+
+// Struct records the call in StructCalls and returns the results
+// configured with OnStruct, or zero values if none match.
+func (s *StubResulter) Struct() results.Point {
+	ret := runtime.Invoke[StubResulterStructRet](s.runtimeStub(), "Struct", &s.StructCalls, StubResulterStructParams{})
+	return ret.R0
+}
+
+// OnStruct configures calls to Struct whose arguments match args.
+// Each of args is a runtime.Matcher or a value the argument must equal; with
+// no args, every call is matched.
+func (s *StubResulter) OnStruct(args ...any) *StubResulterStructThen {
+	return &StubResulterStructThen{
+		exp: runtime.On[StubResulterStructParams, StubResulterStructRet](s.runtimeStub(), "Struct", args...),
+	}
+}
+
+// ExpectStruct adds a check, made by Verify, on the calls to Struct
+// whose arguments match args, which are as for OnStruct.
+func (s *StubResulter) ExpectStruct(args ...any) *runtime.Verification {
+	return runtime.Expect(s.runtimeStub(), "Struct", args...)
+}
+
+// RecordStruct records a call to Struct without returning results,
+// for types embedding the stub that override Struct, so their calls are
+// checked like those of the stub.
+func (s *StubResulter) RecordStruct() {
+	runtime.Record(s.runtimeStub(), "Struct", &s.StructCalls, StubResulterStructParams{})
+}
+
+// End StubResulter.Struct
+
+// Begin StubResulter.Pointer
+
+// Pointer records the call in PointerCalls and returns the results
+// configured with OnPointer, or zero values if none match.
+func (s *StubResulter) Pointer() *results.Point {
+	ret := runtime.Invoke[StubResulterPointerRet](s.runtimeStub(), "Pointer", &s.PointerCalls, StubResulterPointerParams{})
+	return ret.R0
+}
+
+// OnPointer configures calls to Pointer whose arguments match args.
+// Each of args is a runtime.Matcher or a value the argument must equal; with
+// no args, every call is matched.
+func (s *StubResulter) OnPointer(args ...any) *StubResulterPointerThen {
+	return &StubResulterPointerThen{
+		exp: runtime.On[StubResulterPointerParams, StubResulterPointerRet](s.runtimeStub(), "Pointer", args...),
+	}
+}
+
+// ExpectPointer adds a check, made by Verify, on the calls to Pointer
+// whose arguments match args, which are as for OnPointer.
+func (s *StubResulter) ExpectPointer(args ...any) *runtime.Verification {
+	return runtime.Expect(s.runtimeStub(), "Pointer", args...)
+}
+
+// RecordPointer records a call to Pointer without returning results,
+// for types embedding the stub that override Pointer, so their calls are
+// checked like those of the stub.
+func (s *StubResulter) RecordPointer() {
+	runtime.Record(s.runtimeStub(), "Pointer", &s.PointerCalls, StubResulterPointerParams{})
+}
+
+// End StubResulter.Pointer
+
+// Begin StubResulter.Values
+
+// Values records the call in ValuesCalls and returns the results
+// configured with OnValues, or zero values if none match.
+func (s *StubResulter) Values() ([]string, any, error) {
+	ret := runtime.Invoke[StubResulterValuesRet](s.runtimeStub(), "Values", &s.ValuesCalls, StubResulterValuesParams{})
+	return ret.R0, ret.R1, ret.R2
+}
+
+// OnValues configures calls to Values whose arguments match args.
+// Each of args is a runtime.Matcher or a value the argument must equal; with
+// no args, every call is matched.
+func (s *StubResulter) OnValues(args ...any) *StubResulterValuesThen {
+	return &StubResulterValuesThen{
+		exp: runtime.On[StubResulterValuesParams, StubResulterValuesRet](s.runtimeStub(), "Values", args...),
+	}
+}
+
+// ExpectValues adds a check, made by Verify, on the calls to Values
+// whose arguments match args, which are as for OnValues.
+func (s *StubResulter) ExpectValues(args ...any) *runtime.Verification {
+	return runtime.Expect(s.runtimeStub(), "Values", args...)
+}
+
+// RecordValues records a call to Values without returning results,
+// for types embedding the stub that override Values, so their calls are
+// checked like those of the stub.
+func (s *StubResulter) RecordValues() {
+	runtime.Record(s.runtimeStub(), "Values", &s.ValuesCalls, StubResulterValuesParams{})
+}
+
+// End StubResulter.Values
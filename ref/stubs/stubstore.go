@@ -0,0 +1,464 @@
+// Code generated by github.com/phildrip/toe. DO NOT EDIT.
+//toe:style stub
+//toe:interface github.com/phildrip/toe/ref/results.Store
+//toe:hash 6a14e1cb17e1fcea
+//toe:version (devel)
+//toe:options {"packageName":"ref_stubs","argNaming":"param","assertions":"std"}
+
+package ref_stubs
+
+import (
+	"io"
+	"time"
+
+	"github.com/phildrip/toe/runtime"
+)
+
+// StubStoreGetRet holds the results of a call to Get.
+type StubStoreGetRet[K comparable, V any] struct {
+	R0 V
+	R1 error
+}
+
+// StubStoreGetParams holds the arguments of a call to Get, as recorded in
+// GetCalls.
+type StubStoreGetParams[K comparable, V any] struct {
+	Key K
+}
+
+// StubStoreGetThen sets the results of the calls configured with
+// OnGet.
+type StubStoreGetThen[K comparable, V any] struct {
+	exp *runtime.Expectation[StubStoreGetParams[K, V], StubStoreGetRet[K, V]]
+}
+
+// Return sets the results of the configured calls.
+func (s *StubStoreGetThen[K, V]) Return(R0 V, R1 error) *StubStoreGetThen[K, V] {
+	s.exp.Return(StubStoreGetRet[K, V]{
+		R0: R0,
+		R1: R1,
+	})
+	return s
+}
+
+// ReturnOnce adds results to be returned by a single configured call, before
+// those set with Return.
+func (s *StubStoreGetThen[K, V]) ReturnOnce(R0 V, R1 error) *StubStoreGetThen[K, V] {
+	s.exp.ReturnOnce(StubStoreGetRet[K, V]{
+		R0: R0,
+		R1: R1,
+	})
+	return s
+}
+
+// ReturnGenerated sets gen to generate the results of the configured calls,
+// such as from a property-based testing library's generators, after those
+// added with ReturnOnce. It replaces the results set with Return.
+func (s *StubStoreGetThen[K, V]) ReturnGenerated(gen func() (V, error)) *StubStoreGetThen[K, V] {
+	s.exp.ReturnFunc(func(StubStoreGetParams[K, V]) StubStoreGetRet[K, V] {
+		var ret StubStoreGetRet[K, V]
+		ret.R0, ret.R1 = gen()
+		return ret
+	})
+	return s
+}
+
+// FailRandomly makes each configured call fail with probability rate,
+// returning err with zero values for the other results. The failures are
+// derived from seed, so the same calls fail on every run.
+func (s *StubStoreGetThen[K, V]) FailRandomly(rate float64, err error, seed uint64) *StubStoreGetThen[K, V] {
+	s.exp.FailRandomly(rate, err, seed)
+	return s
+}
+
+// MaxConcurrent makes the configured calls return err, with zero values for
+// the other results, while n others are in progress, such as running the
+// function set with ReturnGenerated, to simulate a dependency limiting
+// concurrency.
+func (s *StubStoreGetThen[K, V]) MaxConcurrent(n int, err error) *StubStoreGetThen[K, V] {
+	s.exp.MaxConcurrent(n, StubStoreGetRet[K, V]{R1: err})
+	return s
+}
+
+// Latency makes the configured calls take a duration drawn from l, with a
+// pseudo-random sequence derived from seed, on the clock passed to
+// NewStubStore with runtime.WithClock, or the system clock.
+func (s *StubStoreGetThen[K, V]) Latency(l runtime.Latency, seed uint64) *StubStoreGetThen[K, V] {
+	s.exp.Latency(l, seed)
+	return s
+}
+
+// MaxTimes sets the most calls the configuration may match. Each later
+// matching call fails the test passed to NewStubStore with runtime.WithT,
+// or panics without one.
+func (s *StubStoreGetThen[K, V]) MaxTimes(n int) *StubStoreGetThen[K, V] {
+	s.exp.MaxTimes(n)
+	return s
+}
+
+// StubStorePutRet holds the results of a call to Put.
+type StubStorePutRet[K comparable, V any] struct {
+	R0 error
+}
+
+// StubStorePutParams holds the arguments of a call to Put, as recorded in
+// PutCalls.
+type StubStorePutParams[K comparable, V any] struct {
+	Key K
+	V   V
+}
+
+// StubStorePutThen sets the results of the calls configured with
+// OnPut.
+type StubStorePutThen[K comparable, V any] struct {
+	exp *runtime.Expectation[StubStorePutParams[K, V], StubStorePutRet[K, V]]
+}
+
+// Return sets the results of the configured calls.
+func (s *StubStorePutThen[K, V]) Return(R0 error) *StubStorePutThen[K, V] {
+	s.exp.Return(StubStorePutRet[K, V]{
+		R0: R0,
+	})
+	return s
+}
+
+// ReturnOnce adds results to be returned by a single configured call, before
+// those set with Return.
+func (s *StubStorePutThen[K, V]) ReturnOnce(R0 error) *StubStorePutThen[K, V] {
+	s.exp.ReturnOnce(StubStorePutRet[K, V]{
+		R0: R0,
+	})
+	return s
+}
+
+// ReturnGenerated sets gen to generate the results of the configured calls,
+// such as from a property-based testing library's generators, after those
+// added with ReturnOnce. It replaces the results set with Return.
+func (s *StubStorePutThen[K, V]) ReturnGenerated(gen func() error) *StubStorePutThen[K, V] {
+	s.exp.ReturnFunc(func(StubStorePutParams[K, V]) StubStorePutRet[K, V] {
+		var ret StubStorePutRet[K, V]
+		ret.R0 = gen()
+		return ret
+	})
+	return s
+}
+
+// FailRandomly makes each configured call fail with probability rate,
+// returning err with zero values for the other results. The failures are
+// derived from seed, so the same calls fail on every run.
+func (s *StubStorePutThen[K, V]) FailRandomly(rate float64, err error, seed uint64) *StubStorePutThen[K, V] {
+	s.exp.FailRandomly(rate, err, seed)
+	return s
+}
+
+// MaxConcurrent makes the configured calls return err, with zero values for
+// the other results, while n others are in progress, such as running the
+// function set with ReturnGenerated, to simulate a dependency limiting
+// concurrency.
+func (s *StubStorePutThen[K, V]) MaxConcurrent(n int, err error) *StubStorePutThen[K, V] {
+	s.exp.MaxConcurrent(n, StubStorePutRet[K, V]{R0: err})
+	return s
+}
+
+// Latency makes the configured calls take a duration drawn from l, with a
+// pseudo-random sequence derived from seed, on the clock passed to
+// NewStubStore with runtime.WithClock, or the system clock.
+func (s *StubStorePutThen[K, V]) Latency(l runtime.Latency, seed uint64) *StubStorePutThen[K, V] {
+	s.exp.Latency(l, seed)
+	return s
+}
+
+// MaxTimes sets the most calls the configuration may match. Each later
+// matching call fails the test passed to NewStubStore with runtime.WithT,
+// or panics without one.
+func (s *StubStorePutThen[K, V]) MaxTimes(n int) *StubStorePutThen[K, V] {
+	s.exp.MaxTimes(n)
+	return s
+}
+
+// StubStoreKeysRet holds the results of a call to Keys.
+type StubStoreKeysRet[K comparable, V any] struct {
+	R0 []K
+}
+
+// StubStoreKeysParams holds the arguments of a call to Keys, as recorded in
+// KeysCalls.
+type StubStoreKeysParams[K comparable, V any] struct {
+}
+
+// StubStoreKeysThen sets the results of the calls configured with
+// OnKeys.
+type StubStoreKeysThen[K comparable, V any] struct {
+	exp *runtime.Expectation[StubStoreKeysParams[K, V], StubStoreKeysRet[K, V]]
+}
+
+// Return sets the results of the configured calls.
+func (s *StubStoreKeysThen[K, V]) Return(R0 []K) *StubStoreKeysThen[K, V] {
+	s.exp.Return(StubStoreKeysRet[K, V]{
+		R0: R0,
+	})
+	return s
+}
+
+// ReturnOnce adds results to be returned by a single configured call, before
+// those set with Return.
+func (s *StubStoreKeysThen[K, V]) ReturnOnce(R0 []K) *StubStoreKeysThen[K, V] {
+	s.exp.ReturnOnce(StubStoreKeysRet[K, V]{
+		R0: R0,
+	})
+	return s
+}
+
+// ReturnGenerated sets gen to generate the results of the configured calls,
+// such as from a property-based testing library's generators, after those
+// added with ReturnOnce. It replaces the results set with Return.
+func (s *StubStoreKeysThen[K, V]) ReturnGenerated(gen func() []K) *StubStoreKeysThen[K, V] {
+	s.exp.ReturnFunc(func(StubStoreKeysParams[K, V]) StubStoreKeysRet[K, V] {
+		var ret StubStoreKeysRet[K, V]
+		ret.R0 = gen()
+		return ret
+	})
+	return s
+}
+
+// Latency makes the configured calls take a duration drawn from l, with a
+// pseudo-random sequence derived from seed, on the clock passed to
+// NewStubStore with runtime.WithClock, or the system clock.
+func (s *StubStoreKeysThen[K, V]) Latency(l runtime.Latency, seed uint64) *StubStoreKeysThen[K, V] {
+	s.exp.Latency(l, seed)
+	return s
+}
+
+// MaxTimes sets the most calls the configuration may match. Each later
+// matching call fails the test passed to NewStubStore with runtime.WithT,
+// or panics without one.
+func (s *StubStoreKeysThen[K, V]) MaxTimes(n int) *StubStoreKeysThen[K, V] {
+	s.exp.MaxTimes(n)
+	return s
+}
+
+// NewStubStore returns a StubStore whose methods return zero values
+// until configured, or panic if opts include runtime.Strict().
+func NewStubStore[K comparable, V any](opts ...runtime.Option) *StubStore[K, V] {
+	s := &StubStore[K, V]{}
+	s.stub.Init("StubStore", opts...)
+	return s
+}
+
+// StubStore is a stub implementation of Store. Each method
+// records its arguments in the method's Calls field and returns the results
+// configured with its On method, or zero values. Its methods may be called
+// concurrently, including while it is being configured.
+type StubStore[K comparable, V any] struct {
+	// GetCalls holds the arguments of each call to Get, in order.
+	GetCalls runtime.Calls[StubStoreGetParams[K, V]]
+	// PutCalls holds the arguments of each call to Put, in order.
+	PutCalls runtime.Calls[StubStorePutParams[K, V]]
+	// KeysCalls holds the arguments of each call to Keys, in order.
+	KeysCalls runtime.Calls[StubStoreKeysParams[K, V]]
+
+	stub runtime.Stub
+}
+
+// runtimeStub returns the runtime.Stub backing the stub, named lazily so that
+// a zero StubStore is ready to use without NewStubStore.
+func (s *StubStore[K, V]) runtimeStub() *runtime.Stub {
+	return s.stub.Named("StubStore")
+}
+
+// Sequence returns every call made to the stub, in the order they were made.
+func (s *StubStore[K, V]) Sequence() []runtime.Call {
+	return s.runtimeStub().Calls()
+}
+
+// Verify fails t if the calls made to the stub fail the checks added with the
+// Expect methods. Stubs created with runtime.WithT are verified when the test
+// finishes.
+func (s *StubStore[K, V]) Verify(t runtime.TB) {
+	if err := s.runtimeStub().Verify(); err != nil {
+		t.Errorf("%v", err)
+	}
+}
+
+// Scope scopes the stub to the test t, usually a subtest sharing a stub
+// configured by its parent: the stub's recorded calls are cleared, and once t
+// finishes they are restored, along with the results configured with the On
+// methods as they were when Scope was called. Tests sharing a stub can't run
+// in parallel.
+func (s *StubStore[K, V]) Scope(t runtime.TB) {
+	s.runtimeStub().Scope(t,
+		runtime.ResetCalls(&s.GetCalls),
+		runtime.ResetCalls(&s.PutCalls),
+		runtime.ResetCalls(&s.KeysCalls),
+	)
+}
+
+// DumpInteractions writes the calls made to the stub to w in format,
+// runtime.FormatDOT for a Graphviz graph of the timeline or
+// runtime.FormatJSON. Stubs sharing a runtime.Recorder write the calls made
+// to each of them.
+func (s *StubStore[K, V]) DumpInteractions(w io.Writer, format string) error {
+	return s.runtimeStub().DumpInteractions(w, format)
+}
+
+// RateLimit limits the calls to the stub's methods returning an error, or
+// to those named in methods, to n every per, as a token bucket: calls over
+// the limit return err, with zero values for the other results. Time is
+// measured with the clock passed to NewStubStore with runtime.WithClock,
+// or the system clock.
+func (s *StubStore[K, V]) RateLimit(n int, per time.Duration, err error, methods ...string) {
+	s.runtimeStub().RateLimit(n, per, err, methods...)
+}
+
+// AllowSequence restricts the order in which the methods named in steps are
+// called: each step is a method name, followed by "?" if the call is
+// optional, "*" if it may be repeated or "+" if it must be made at least
+// once. Calls out of the sequence fail the test bound with runtime.WithT, or
+// panic, and Verify fails if the calls stop before its end.
+func (s *StubStore[K, V]) AllowSequence(steps ...string) {
+	s.runtimeStub().AllowSequence([]string{"Get", "Put", "Keys"}, steps...)
+}
+
+// SnapshotConfig returns a snapshot of the stub's configuration, made with its
+// On, Expect and other methods, for RestoreConfig.
+func (s *StubStore[K, V]) SnapshotConfig() runtime.Config {
+	return s.runtimeStub().SnapshotConfig()
+}
+
+// RestoreConfig restores the stub's configuration to a snapshot taken with
+// SnapshotConfig, such as a baseline shared by several scenarios, keeping
+// the calls recorded.
+func (s *StubStore[K, V]) RestoreConfig(c runtime.Config) {
+	s.runtimeStub().RestoreConfig(c)
+}
+
+// Clone returns a new stub with copies of the results and checks configured
+// on the stub, and the options it was created with, but none of its recorded
+// calls, so that table-driven tests can derive a stub for each case from a
+// shared base: configuring or calling either stub doesn't affect the other.
+func (s *StubStore[K, V]) Clone() *StubStore[K, V] {
+	clone := &StubStore[K, V]{}
+	s.runtimeStub().CloneTo(&clone.stub)
+	return clone
+}
+
+// Begin StubStore.Get
+
+// Get records the call in GetCalls and returns the results
+// configured with OnGet, or zero values if none match.
+func (s *StubStore[K, V]) Get(key K) (V, error) {
+	ret := runtime.Invoke[StubStoreGetRet[K, V]](s.runtimeStub(), "Get", &s.GetCalls, StubStoreGetParams[K, V]{
+		Key: key,
+	})
+	return ret.R0, ret.R1
+}
+
+// OnGet configures calls to Get whose arguments match args.
+// Each of args is a runtime.Matcher or a value the argument must equal; with
+// no args, every call is matched.
+func (s *StubStore[K, V]) OnGet(args ...any) *StubStoreGetThen[K, V] {
+	return &StubStoreGetThen[K, V]{
+		exp: runtime.On[StubStoreGetParams[K, V], StubStoreGetRet[K, V]](s.runtimeStub(), "Get", args...),
+	}
+}
+
+// AssertGetCalledWith fails t unless Get was called with the
+// arguments in want, showing a diff against the closest call.
+func (s *StubStore[K, V]) AssertGetCalledWith(t runtime.TB, want StubStoreGetParams[K, V]) {
+	runtime.AssertCalledWith(t, s.runtimeStub(), "Get", &s.GetCalls, want)
+}
+
+// ExpectGet adds a check, made by Verify, on the calls to Get
+// whose arguments match args, which are as for OnGet.
+func (s *StubStore[K, V]) ExpectGet(args ...any) *runtime.Verification {
+	return runtime.Expect(s.runtimeStub(), "Get", args...)
+}
+
+// RecordGet records a call to Get without returning results,
+// for types embedding the stub that override Get, so their calls are
+// checked like those of the stub.
+func (s *StubStore[K, V]) RecordGet(key K) {
+	runtime.Record(s.runtimeStub(), "Get", &s.GetCalls, StubStoreGetParams[K, V]{
+		Key: key,
+	})
+}
+
+// End StubStore.Get
+
+// Begin StubStore.Put
+
+// Put records the call in PutCalls and returns the results
+// configured with OnPut, or zero values if none match.
+func (s *StubStore[K, V]) Put(key K, v V) error {
+	ret := runtime.Invoke[StubStorePutRet[K, V]](s.runtimeStub(), "Put", &s.PutCalls, StubStorePutParams[K, V]{
+		Key: key,
+		V:   v,
+	})
+	return ret.R0
+}
+
+// OnPut configures calls to Put whose arguments match args.
+// Each of args is a runtime.Matcher or a value the argument must equal; with
+// no args, every call is matched.
+func (s *StubStore[K, V]) OnPut(args ...any) *StubStorePutThen[K, V] {
+	return &StubStorePutThen[K, V]{
+		exp: runtime.On[StubStorePutParams[K, V], StubStorePutRet[K, V]](s.runtimeStub(), "Put", args...),
+	}
+}
+
+// AssertPutCalledWith fails t unless Put was called with the
+// arguments in want, showing a diff against the closest call.
+func (s *StubStore[K, V]) AssertPutCalledWith(t runtime.TB, want StubStorePutParams[K, V]) {
+	runtime.AssertCalledWith(t, s.runtimeStub(), "Put", &s.PutCalls, want)
+}
+
+// ExpectPut adds a check, made by Verify, on the calls to Put
+// whose arguments match args, which are as for OnPut.
+func (s *StubStore[K, V]) ExpectPut(args ...any) *runtime.Verification {
+	return runtime.Expect(s.runtimeStub(), "Put", args...)
+}
+
+// RecordPut records a call to Put without returning results,
+// for types embedding the stub that override Put, so their calls are
+// checked like those of the stub.
+func (s *StubStore[K, V]) RecordPut(key K, v V) {
+	runtime.Record(s.runtimeStub(), "Put", &s.PutCalls, StubStorePutParams[K, V]{
+		Key: key,
+		V:   v,
+	})
+}
+
+// End StubStore.Put
+
+// Begin StubStore.Keys
+
+// Keys records the call in KeysCalls and returns the results
+// configured with OnKeys, or zero values if none match.
+func (s *StubStore[K, V]) Keys() []K {
+	ret := runtime.Invoke[StubStoreKeysRet[K, V]](s.runtimeStub(), "Keys", &s.KeysCalls, StubStoreKeysParams[K, V]{})
+	return ret.R0
+}
+
+// OnKeys configures calls to Keys whose arguments match args.
+// Each of args is a runtime.Matcher or a value the argument must equal; with
+// no args, every call is matched.
+func (s *StubStore[K, V]) OnKeys(args ...any) *StubStoreKeysThen[K, V] {
+	return &StubStoreKeysThen[K, V]{
+		exp: runtime.On[StubStoreKeysParams[K, V], StubStoreKeysRet[K, V]](s.runtimeStub(), "Keys", args...),
+	}
+}
+
+// ExpectKeys adds a check, made by Verify, on the calls to Keys
+// whose arguments match args, which are as for OnKeys.
+func (s *StubStore[K, V]) ExpectKeys(args ...any) *runtime.Verification {
+	return runtime.Expect(s.runtimeStub(), "Keys", args...)
+}
+
+// RecordKeys records a call to Keys without returning results,
+// for types embedding the stub that override Keys, so their calls are
+// checked like those of the stub.
+func (s *StubStore[K, V]) RecordKeys() {
+	runtime.Record(s.runtimeStub(), "Keys", &s.KeysCalls, StubStoreKeysParams[K, V]{})
+}
+
+// End StubStore.Keys
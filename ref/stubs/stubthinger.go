@@ -1,131 +1,481 @@
+// Code generated by github.com/phildrip/toe. DO NOT EDIT.
+//toe:style stub
+//toe:interface github.com/phildrip/toe/ref.Thinger
+//toe:hash 5f8dd1eebf87fa13
+//toe:version (devel)
+//toe:options {"packageName":"ref_stubs","argNaming":"param","withExample":true,"withRaceTest":true,"withFuzz":true,"assertions":"std"}
+
 package ref_stubs
 
-import "sync"
+import (
+	"io"
+	"time"
 
-// type Thinger interface {
-//	Thing() error
-//	ThingWithParam(arg1 int) error
-// }
+	"github.com/phildrip/toe/runtime"
+)
 
-type ThingWithParamsRet struct {
-	R0 string
-	R1 error
+// StubThingerThingRet holds the results of a call to Thing.
+type StubThingerThingRet struct {
+	R0 error
 }
 
-type ThingWithParamRet struct {
-	R0 error
+// StubThingerThingParams holds the arguments of a call to Thing, as recorded in
+// ThingCalls.
+type StubThingerThingParams struct {
 }
 
-type ThingRet struct {
+// StubThingerThingThen sets the results of the calls configured with
+// OnThing.
+type StubThingerThingThen struct {
+	exp *runtime.Expectation[StubThingerThingParams, StubThingerThingRet]
+}
+
+// Return sets the results of the configured calls.
+func (s *StubThingerThingThen) Return(R0 error) *StubThingerThingThen {
+	s.exp.Return(StubThingerThingRet{
+		R0: R0,
+	})
+	return s
+}
+
+// ReturnOnce adds results to be returned by a single configured call, before
+// those set with Return.
+func (s *StubThingerThingThen) ReturnOnce(R0 error) *StubThingerThingThen {
+	s.exp.ReturnOnce(StubThingerThingRet{
+		R0: R0,
+	})
+	return s
+}
+
+// ReturnGenerated sets gen to generate the results of the configured calls,
+// such as from a property-based testing library's generators, after those
+// added with ReturnOnce. It replaces the results set with Return.
+func (s *StubThingerThingThen) ReturnGenerated(gen func() error) *StubThingerThingThen {
+	s.exp.ReturnFunc(func(StubThingerThingParams) StubThingerThingRet {
+		var ret StubThingerThingRet
+		ret.R0 = gen()
+		return ret
+	})
+	return s
+}
+
+// FailRandomly makes each configured call fail with probability rate,
+// returning err with zero values for the other results. The failures are
+// derived from seed, so the same calls fail on every run.
+func (s *StubThingerThingThen) FailRandomly(rate float64, err error, seed uint64) *StubThingerThingThen {
+	s.exp.FailRandomly(rate, err, seed)
+	return s
+}
+
+// MaxConcurrent makes the configured calls return err, with zero values for
+// the other results, while n others are in progress, such as running the
+// function set with ReturnGenerated, to simulate a dependency limiting
+// concurrency.
+func (s *StubThingerThingThen) MaxConcurrent(n int, err error) *StubThingerThingThen {
+	s.exp.MaxConcurrent(n, StubThingerThingRet{R0: err})
+	return s
+}
+
+// Latency makes the configured calls take a duration drawn from l, with a
+// pseudo-random sequence derived from seed, on the clock passed to
+// NewStubThinger with runtime.WithClock, or the system clock.
+func (s *StubThingerThingThen) Latency(l runtime.Latency, seed uint64) *StubThingerThingThen {
+	s.exp.Latency(l, seed)
+	return s
+}
+
+// MaxTimes sets the most calls the configuration may match. Each later
+// matching call fails the test passed to NewStubThinger with runtime.WithT,
+// or panics without one.
+func (s *StubThingerThingThen) MaxTimes(n int) *StubThingerThingThen {
+	s.exp.MaxTimes(n)
+	return s
+}
+
+// StubThingerThingWithParamRet holds the results of a call to ThingWithParam.
+type StubThingerThingWithParamRet struct {
 	R0 error
 }
 
-type ThingParams struct{}
+// StubThingerThingWithParamParams holds the arguments of a call to ThingWithParam, as recorded in
+// ThingWithParamCalls.
+type StubThingerThingWithParamParams struct {
+	Arg1 int
+}
+
+// StubThingerThingWithParamThen sets the results of the calls configured with
+// OnThingWithParam.
+type StubThingerThingWithParamThen struct {
+	exp *runtime.Expectation[StubThingerThingWithParamParams, StubThingerThingWithParamRet]
+}
 
-type ThingWithParamsParams struct {
+// Return sets the results of the configured calls.
+func (s *StubThingerThingWithParamThen) Return(R0 error) *StubThingerThingWithParamThen {
+	s.exp.Return(StubThingerThingWithParamRet{
+		R0: R0,
+	})
+	return s
+}
+
+// ReturnOnce adds results to be returned by a single configured call, before
+// those set with Return.
+func (s *StubThingerThingWithParamThen) ReturnOnce(R0 error) *StubThingerThingWithParamThen {
+	s.exp.ReturnOnce(StubThingerThingWithParamRet{
+		R0: R0,
+	})
+	return s
+}
+
+// ReturnGenerated sets gen to generate the results of the configured calls,
+// such as from a property-based testing library's generators, after those
+// added with ReturnOnce. It replaces the results set with Return.
+func (s *StubThingerThingWithParamThen) ReturnGenerated(gen func() error) *StubThingerThingWithParamThen {
+	s.exp.ReturnFunc(func(StubThingerThingWithParamParams) StubThingerThingWithParamRet {
+		var ret StubThingerThingWithParamRet
+		ret.R0 = gen()
+		return ret
+	})
+	return s
+}
+
+// FailRandomly makes each configured call fail with probability rate,
+// returning err with zero values for the other results. The failures are
+// derived from seed, so the same calls fail on every run.
+func (s *StubThingerThingWithParamThen) FailRandomly(rate float64, err error, seed uint64) *StubThingerThingWithParamThen {
+	s.exp.FailRandomly(rate, err, seed)
+	return s
+}
+
+// MaxConcurrent makes the configured calls return err, with zero values for
+// the other results, while n others are in progress, such as running the
+// function set with ReturnGenerated, to simulate a dependency limiting
+// concurrency.
+func (s *StubThingerThingWithParamThen) MaxConcurrent(n int, err error) *StubThingerThingWithParamThen {
+	s.exp.MaxConcurrent(n, StubThingerThingWithParamRet{R0: err})
+	return s
+}
+
+// Latency makes the configured calls take a duration drawn from l, with a
+// pseudo-random sequence derived from seed, on the clock passed to
+// NewStubThinger with runtime.WithClock, or the system clock.
+func (s *StubThingerThingWithParamThen) Latency(l runtime.Latency, seed uint64) *StubThingerThingWithParamThen {
+	s.exp.Latency(l, seed)
+	return s
+}
+
+// MaxTimes sets the most calls the configuration may match. Each later
+// matching call fails the test passed to NewStubThinger with runtime.WithT,
+// or panics without one.
+func (s *StubThingerThingWithParamThen) MaxTimes(n int) *StubThingerThingWithParamThen {
+	s.exp.MaxTimes(n)
+	return s
+}
+
+// StubThingerThingWithParamsRet holds the results of a call to ThingWithParams.
+type StubThingerThingWithParamsRet struct {
+	R0 string
+	R1 error
+}
+
+// StubThingerThingWithParamsParams holds the arguments of a call to ThingWithParams, as recorded in
+// ThingWithParamsCalls.
+type StubThingerThingWithParamsParams struct {
 	Arg1 int
 	Arg2 string
 }
 
-type ThingWithParamParams struct {
-	Arg1 int
+// StubThingerThingWithParamsThen sets the results of the calls configured with
+// OnThingWithParams.
+type StubThingerThingWithParamsThen struct {
+	exp *runtime.Expectation[StubThingerThingWithParamsParams, StubThingerThingWithParamsRet]
 }
 
-func NewStubThinger() *StubThinger {
-	stub := &StubThinger{}
-	stub.StubThingThen = &StubThingThen{
-		stub: stub,
-	}
-	stub.StubThingWithParamThen = &StubThingWithParamThen{
-		stub: stub,
-	}
-	stub.StubThingWithParamsThen = &StubThingWithParamsThen{
-		stub: stub,
-	}
-	return stub
+// Return sets the results of the configured calls.
+func (s *StubThingerThingWithParamsThen) Return(R0 string, R1 error) *StubThingerThingWithParamsThen {
+	s.exp.Return(StubThingerThingWithParamsRet{
+		R0: R0,
+		R1: R1,
+	})
+	return s
+}
+
+// ReturnOnce adds results to be returned by a single configured call, before
+// those set with Return.
+func (s *StubThingerThingWithParamsThen) ReturnOnce(R0 string, R1 error) *StubThingerThingWithParamsThen {
+	s.exp.ReturnOnce(StubThingerThingWithParamsRet{
+		R0: R0,
+		R1: R1,
+	})
+	return s
 }
 
+// ReturnGenerated sets gen to generate the results of the configured calls,
+// such as from a property-based testing library's generators, after those
+// added with ReturnOnce. It replaces the results set with Return.
+func (s *StubThingerThingWithParamsThen) ReturnGenerated(gen func() (string, error)) *StubThingerThingWithParamsThen {
+	s.exp.ReturnFunc(func(StubThingerThingWithParamsParams) StubThingerThingWithParamsRet {
+		var ret StubThingerThingWithParamsRet
+		ret.R0, ret.R1 = gen()
+		return ret
+	})
+	return s
+}
+
+// FailRandomly makes each configured call fail with probability rate,
+// returning err with zero values for the other results. The failures are
+// derived from seed, so the same calls fail on every run.
+func (s *StubThingerThingWithParamsThen) FailRandomly(rate float64, err error, seed uint64) *StubThingerThingWithParamsThen {
+	s.exp.FailRandomly(rate, err, seed)
+	return s
+}
+
+// MaxConcurrent makes the configured calls return err, with zero values for
+// the other results, while n others are in progress, such as running the
+// function set with ReturnGenerated, to simulate a dependency limiting
+// concurrency.
+func (s *StubThingerThingWithParamsThen) MaxConcurrent(n int, err error) *StubThingerThingWithParamsThen {
+	s.exp.MaxConcurrent(n, StubThingerThingWithParamsRet{R1: err})
+	return s
+}
+
+// Latency makes the configured calls take a duration drawn from l, with a
+// pseudo-random sequence derived from seed, on the clock passed to
+// NewStubThinger with runtime.WithClock, or the system clock.
+func (s *StubThingerThingWithParamsThen) Latency(l runtime.Latency, seed uint64) *StubThingerThingWithParamsThen {
+	s.exp.Latency(l, seed)
+	return s
+}
+
+// MaxTimes sets the most calls the configuration may match. Each later
+// matching call fails the test passed to NewStubThinger with runtime.WithT,
+// or panics without one.
+func (s *StubThingerThingWithParamsThen) MaxTimes(n int) *StubThingerThingWithParamsThen {
+	s.exp.MaxTimes(n)
+	return s
+}
+
+// NewStubThinger returns a StubThinger whose methods return zero values
+// until configured, or panic if opts include runtime.Strict().
+func NewStubThinger(opts ...runtime.Option) *StubThinger {
+	s := &StubThinger{}
+	s.stub.Init("StubThinger", opts...)
+	return s
+}
+
+// StubThinger is a stub implementation of Thinger. Each method
+// records its arguments in the method's Calls field and returns the results
+// configured with its On method, or zero values. Its methods may be called
+// concurrently, including while it is being configured.
 type StubThinger struct {
-	ThingRet           ThingRet
-	ThingWithParamRet  ThingWithParamRet
-	ThingWithParamsRet ThingWithParamsRet
+	// ThingCalls holds the arguments of each call to Thing, in order.
+	ThingCalls runtime.Calls[StubThingerThingParams]
+	// ThingWithParamCalls holds the arguments of each call to ThingWithParam, in order.
+	ThingWithParamCalls runtime.Calls[StubThingerThingWithParamParams]
+	// ThingWithParamsCalls holds the arguments of each call to ThingWithParams, in order.
+	ThingWithParamsCalls runtime.Calls[StubThingerThingWithParamsParams]
 
-	ThingCalls           []ThingParams
-	ThingWithParamCalls  []ThingWithParamParams
-	ThingWithParamsCalls []ThingWithParamsParams
+	stub runtime.Stub
+}
 
-	StubThingThen           *StubThingThen
-	StubThingWithParamThen  *StubThingWithParamThen
-	StubThingWithParamsThen *StubThingWithParamsThen
+// runtimeStub returns the runtime.Stub backing the stub, named lazily so that
+// a zero StubThinger is ready to use without NewStubThinger.
+func (s *StubThinger) runtimeStub() *runtime.Stub {
+	return s.stub.Named("StubThinger")
+}
 
-	mut sync.Mutex
+// Sequence returns every call made to the stub, in the order they were made.
+func (s *StubThinger) Sequence() []runtime.Call {
+	return s.runtimeStub().Calls()
 }
 
+// Verify fails t if the calls made to the stub fail the checks added with the
+// Expect methods. Stubs created with runtime.WithT are verified when the test
+// finishes.
+func (s *StubThinger) Verify(t runtime.TB) {
+	if err := s.runtimeStub().Verify(); err != nil {
+		t.Errorf("%v", err)
+	}
+}
+
+// Scope scopes the stub to the test t, usually a subtest sharing a stub
+// configured by its parent: the stub's recorded calls are cleared, and once t
+// finishes they are restored, along with the results configured with the On
+// methods as they were when Scope was called. Tests sharing a stub can't run
+// in parallel.
+func (s *StubThinger) Scope(t runtime.TB) {
+	s.runtimeStub().Scope(t,
+		runtime.ResetCalls(&s.ThingCalls),
+		runtime.ResetCalls(&s.ThingWithParamCalls),
+		runtime.ResetCalls(&s.ThingWithParamsCalls),
+	)
+}
+
+// DumpInteractions writes the calls made to the stub to w in format,
+// runtime.FormatDOT for a Graphviz graph of the timeline or
+// runtime.FormatJSON. Stubs sharing a runtime.Recorder write the calls made
+// to each of them.
+func (s *StubThinger) DumpInteractions(w io.Writer, format string) error {
+	return s.runtimeStub().DumpInteractions(w, format)
+}
+
+// RateLimit limits the calls to the stub's methods returning an error, or
+// to those named in methods, to n every per, as a token bucket: calls over
+// the limit return err, with zero values for the other results. Time is
+// measured with the clock passed to NewStubThinger with runtime.WithClock,
+// or the system clock.
+func (s *StubThinger) RateLimit(n int, per time.Duration, err error, methods ...string) {
+	s.runtimeStub().RateLimit(n, per, err, methods...)
+}
+
+// AllowSequence restricts the order in which the methods named in steps are
+// called: each step is a method name, followed by "?" if the call is
+// optional, "*" if it may be repeated or "+" if it must be made at least
+// once. Calls out of the sequence fail the test bound with runtime.WithT, or
+// panic, and Verify fails if the calls stop before its end.
+func (s *StubThinger) AllowSequence(steps ...string) {
+	s.runtimeStub().AllowSequence([]string{"Thing", "ThingWithParam", "ThingWithParams"}, steps...)
+}
+
+// SnapshotConfig returns a snapshot of the stub's configuration, made with its
+// On, Expect and other methods, for RestoreConfig.
+func (s *StubThinger) SnapshotConfig() runtime.Config {
+	return s.runtimeStub().SnapshotConfig()
+}
+
+// RestoreConfig restores the stub's configuration to a snapshot taken with
+// SnapshotConfig, such as a baseline shared by several scenarios, keeping
+// the calls recorded.
+func (s *StubThinger) RestoreConfig(c runtime.Config) {
+	s.runtimeStub().RestoreConfig(c)
+}
+
+// Clone returns a new stub with copies of the results and checks configured
+// on the stub, and the options it was created with, but none of its recorded
+// calls, so that table-driven tests can derive a stub for each case from a
+// shared base: configuring or calling either stub doesn't affect the other.
+func (s *StubThinger) Clone() *StubThinger {
+	clone := &StubThinger{}
+	s.runtimeStub().CloneTo(&clone.stub)
+	return clone
+}
+
+// Begin StubThinger.Thing
+
+// Thing records the call in ThingCalls and returns the results
+// configured with OnThing, or zero values if none match.
 func (s *StubThinger) Thing() error {
-	s.mut.Lock()
-	defer s.mut.Unlock()
-	s.ThingCalls = append(s.ThingCalls, ThingParams{})
-	return s.ThingRet.R0
+	ret := runtime.Invoke[StubThingerThingRet](s.runtimeStub(), "Thing", &s.ThingCalls, StubThingerThingParams{})
+	return ret.R0
 }
 
-func (s *StubThinger) ThingWithParam(arg1 int) error {
-	s.mut.Lock()
-	defer s.mut.Unlock()
-	s.ThingWithParamCalls = append(s.ThingWithParamCalls, ThingWithParamParams{
-		Arg1: arg1,
-	})
-	return s.ThingWithParamRet.R0
+// OnThing configures calls to Thing whose arguments match args.
+// Each of args is a runtime.Matcher or a value the argument must equal; with
+// no args, every call is matched.
+func (s *StubThinger) OnThing(args ...any) *StubThingerThingThen {
+	return &StubThingerThingThen{
+		exp: runtime.On[StubThingerThingParams, StubThingerThingRet](s.runtimeStub(), "Thing", args...),
+	}
 }
 
-func (s *StubThinger) ThingWithParams(arg1 int, arg2 string) (string, error) {
-	s.mut.Lock()
-	defer s.mut.Unlock()
-	s.ThingWithParamsCalls = append(s.ThingWithParamsCalls, ThingWithParamsParams{
+// ExpectThing adds a check, made by Verify, on the calls to Thing
+// whose arguments match args, which are as for OnThing.
+func (s *StubThinger) ExpectThing(args ...any) *runtime.Verification {
+	return runtime.Expect(s.runtimeStub(), "Thing", args...)
+}
+
+// RecordThing records a call to Thing without returning results,
+// for types embedding the stub that override Thing, so their calls are
+// checked like those of the stub.
+func (s *StubThinger) RecordThing() {
+	runtime.Record(s.runtimeStub(), "Thing", &s.ThingCalls, StubThingerThingParams{})
+}
+
+// End StubThinger.Thing
+
+// Begin StubThinger.ThingWithParam
+
+// ThingWithParam records the call in ThingWithParamCalls and returns the results
+// configured with OnThingWithParam, or zero values if none match.
+func (s *StubThinger) ThingWithParam(arg1 int) error {
+	ret := runtime.Invoke[StubThingerThingWithParamRet](s.runtimeStub(), "ThingWithParam", &s.ThingWithParamCalls, StubThingerThingWithParamParams{
 		Arg1: arg1,
-		Arg2: arg2,
 	})
-	return s.ThingWithParamsRet.R0, s.ThingWithParamsRet.R1
+	return ret.R0
 }
 
-type StubThingThen struct {
-	stub *StubThinger
+// OnThingWithParam configures calls to ThingWithParam whose arguments match args.
+// Each of args is a runtime.Matcher or a value the argument must equal; with
+// no args, every call is matched.
+func (s *StubThinger) OnThingWithParam(args ...any) *StubThingerThingWithParamThen {
+	return &StubThingerThingWithParamThen{
+		exp: runtime.On[StubThingerThingWithParamParams, StubThingerThingWithParamRet](s.runtimeStub(), "ThingWithParam", args...),
+	}
 }
 
-func (s *StubThingThen) Return(R0 error) {
-	s.stub.ThingRet = ThingRet{
-		R0: R0,
-	}
+// AssertThingWithParamCalledWith fails t unless ThingWithParam was called with the
+// arguments in want, showing a diff against the closest call.
+func (s *StubThinger) AssertThingWithParamCalledWith(t runtime.TB, want StubThingerThingWithParamParams) {
+	runtime.AssertCalledWith(t, s.runtimeStub(), "ThingWithParam", &s.ThingWithParamCalls, want)
 }
 
-type StubThingWithParamThen struct {
-	stub *StubThinger
+// ExpectThingWithParam adds a check, made by Verify, on the calls to ThingWithParam
+// whose arguments match args, which are as for OnThingWithParam.
+func (s *StubThinger) ExpectThingWithParam(args ...any) *runtime.Verification {
+	return runtime.Expect(s.runtimeStub(), "ThingWithParam", args...)
 }
 
-func (s *StubThingWithParamThen) Return(R0 error) {
-	s.stub.ThingWithParamRet = ThingWithParamRet{
-		R0: R0,
-	}
+// RecordThingWithParam records a call to ThingWithParam without returning results,
+// for types embedding the stub that override ThingWithParam, so their calls are
+// checked like those of the stub.
+func (s *StubThinger) RecordThingWithParam(arg1 int) {
+	runtime.Record(s.runtimeStub(), "ThingWithParam", &s.ThingWithParamCalls, StubThingerThingWithParamParams{
+		Arg1: arg1,
+	})
 }
 
-type StubThingWithParamsThen struct {
-	stub *StubThinger
+// End StubThinger.ThingWithParam
+
+// Begin StubThinger.ThingWithParams
+
+// ThingWithParams records the call in ThingWithParamsCalls and returns the results
+// configured with OnThingWithParams, or zero values if none match.
+func (s *StubThinger) ThingWithParams(arg1 int, arg2 string) (string, error) {
+	ret := runtime.Invoke[StubThingerThingWithParamsRet](s.runtimeStub(), "ThingWithParams", &s.ThingWithParamsCalls, StubThingerThingWithParamsParams{
+		Arg1: arg1,
+		Arg2: arg2,
+	})
+	return ret.R0, ret.R1
 }
 
-func (s *StubThingWithParamsThen) Return(R0 string, R1 error) {
-	s.stub.ThingWithParamsRet = ThingWithParamsRet{
-		R0: R0,
-		R1: R1,
+// OnThingWithParams configures calls to ThingWithParams whose arguments match args.
+// Each of args is a runtime.Matcher or a value the argument must equal; with
+// no args, every call is matched.
+func (s *StubThinger) OnThingWithParams(args ...any) *StubThingerThingWithParamsThen {
+	return &StubThingerThingWithParamsThen{
+		exp: runtime.On[StubThingerThingWithParamsParams, StubThingerThingWithParamsRet](s.runtimeStub(), "ThingWithParams", args...),
 	}
 }
 
-func (s *StubThinger) OnThing() *StubThingThen {
-	return s.StubThingThen
+// AssertThingWithParamsCalledWith fails t unless ThingWithParams was called with the
+// arguments in want, showing a diff against the closest call.
+func (s *StubThinger) AssertThingWithParamsCalledWith(t runtime.TB, want StubThingerThingWithParamsParams) {
+	runtime.AssertCalledWith(t, s.runtimeStub(), "ThingWithParams", &s.ThingWithParamsCalls, want)
 }
 
-func (s *StubThinger) OnThingWithParam() *StubThingWithParamThen {
-	return s.StubThingWithParamThen
+// ExpectThingWithParams adds a check, made by Verify, on the calls to ThingWithParams
+// whose arguments match args, which are as for OnThingWithParams.
+func (s *StubThinger) ExpectThingWithParams(args ...any) *runtime.Verification {
+	return runtime.Expect(s.runtimeStub(), "ThingWithParams", args...)
 }
 
-func (s *StubThinger) OnThingWithParams() *StubThingWithParamsThen {
-	return s.StubThingWithParamsThen
+// RecordThingWithParams records a call to ThingWithParams without returning results,
+// for types embedding the stub that override ThingWithParams, so their calls are
+// checked like those of the stub.
+func (s *StubThinger) RecordThingWithParams(arg1 int, arg2 string) {
+	runtime.Record(s.runtimeStub(), "ThingWithParams", &s.ThingWithParamsCalls, StubThingerThingWithParamsParams{
+		Arg1: arg1,
+		Arg2: arg2,
+	})
 }
+
+// End StubThinger.ThingWithParams
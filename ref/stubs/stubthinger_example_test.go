@@ -0,0 +1,42 @@
+// Code generated by github.com/phildrip/toe. DO NOT EDIT.
+//toe:style stub
+//toe:interface github.com/phildrip/toe/ref.Thinger
+//toe:hash 5f8dd1eebf87fa13
+//toe:version (devel)
+//toe:options {"packageName":"ref_stubs","argNaming":"param","withExample":true,"withRaceTest":true,"withFuzz":true,"assertions":"std"}
+
+package ref_stubs
+
+import (
+	"fmt"
+)
+
+// ExampleStubThinger configures the results of the stub's methods with
+// On<Method>, calls them, and checks the calls the stub recorded.
+func ExampleStubThinger() {
+	stub := NewStubThinger()
+
+	stub.OnThing().Return(nil)
+	fmt.Println(stub.Thing())
+	fmt.Println(stub.ThingCalls.Len())
+
+	stub.OnThingWithParam().Return(nil)
+	fmt.Println(stub.ThingWithParam(42))
+	fmt.Println(stub.ThingWithParamCalls.Len())
+	fmt.Printf("%+v\n", stub.ThingWithParamCalls.Last())
+
+	stub.OnThingWithParams().Return("example", nil)
+	fmt.Println(stub.ThingWithParams(42, "example"))
+	fmt.Println(stub.ThingWithParamsCalls.Len())
+	fmt.Printf("%+v\n", stub.ThingWithParamsCalls.Last())
+
+	// Output:
+	// <nil>
+	// 1
+	// <nil>
+	// 1
+	// {Arg1:42}
+	// example <nil>
+	// 1
+	// {Arg1:42 Arg2:example}
+}
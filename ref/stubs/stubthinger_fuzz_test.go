@@ -0,0 +1,57 @@
+// Code generated by github.com/phildrip/toe. DO NOT EDIT.
+//toe:style stub
+//toe:interface github.com/phildrip/toe/ref.Thinger
+//toe:hash 5f8dd1eebf87fa13
+//toe:version (devel)
+//toe:options {"packageName":"ref_stubs","argNaming":"param","withExample":true,"withRaceTest":true,"withFuzz":true,"assertions":"std"}
+
+package ref_stubs
+
+import (
+	"fmt"
+	"testing"
+)
+
+// FuzzStubThingerThingWithParam calls the stub's ThingWithParam with
+// fuzzed arguments, failing if it panics or doesn't record the call as made.
+func FuzzStubThingerThingWithParam(f *testing.F) {
+	f.Add(int(42))
+	f.Fuzz(func(t *testing.T, fuzz0 int) {
+		stub := NewStubThinger()
+		stub.OnThingWithParam().Return(nil)
+		stub.ThingWithParam(fuzz0)
+
+		if got := stub.ThingWithParamCalls.Len(); got != 1 {
+			t.Fatalf("expected %v calls to ThingWithParam, got %v", 1, got)
+		}
+		want := StubThingerThingWithParamParams{Arg1: fuzz0}
+		if got := stub.ThingWithParamCalls.Last(); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("expected call to ThingWithParam with %+v, got %+v", want, got)
+		}
+		if seq := stub.Sequence(); len(seq) != 1 || seq[0].Method != "ThingWithParam" {
+			t.Errorf("expected sequence of a call to %v, got %v", "ThingWithParam", seq)
+		}
+	})
+}
+
+// FuzzStubThingerThingWithParams calls the stub's ThingWithParams with
+// fuzzed arguments, failing if it panics or doesn't record the call as made.
+func FuzzStubThingerThingWithParams(f *testing.F) {
+	f.Add(int(42), string("example"))
+	f.Fuzz(func(t *testing.T, fuzz0 int, fuzz1 string) {
+		stub := NewStubThinger()
+		stub.OnThingWithParams().Return("example", nil)
+		stub.ThingWithParams(fuzz0, fuzz1)
+
+		if got := stub.ThingWithParamsCalls.Len(); got != 1 {
+			t.Fatalf("expected %v calls to ThingWithParams, got %v", 1, got)
+		}
+		want := StubThingerThingWithParamsParams{Arg1: fuzz0, Arg2: fuzz1}
+		if got := stub.ThingWithParamsCalls.Last(); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("expected call to ThingWithParams with %+v, got %+v", want, got)
+		}
+		if seq := stub.Sequence(); len(seq) != 1 || seq[0].Method != "ThingWithParams" {
+			t.Errorf("expected sequence of a call to %v, got %v", "ThingWithParams", seq)
+		}
+	})
+}
@@ -0,0 +1,50 @@
+// Code generated by github.com/phildrip/toe. DO NOT EDIT.
+//toe:style stub
+//toe:interface github.com/phildrip/toe/ref.Thinger
+//toe:hash 5f8dd1eebf87fa13
+//toe:version (devel)
+//toe:options {"packageName":"ref_stubs","argNaming":"param","withExample":true,"withRaceTest":true,"withFuzz":true,"assertions":"std"}
+
+package ref_stubs
+
+import (
+	"sync"
+	"testing"
+)
+
+// TestStubThingerRace calls every method of the stub from several
+// goroutines at once, for the race detector to check the stub's locking.
+// Run it with go test -race.
+func TestStubThingerRace(t *testing.T) {
+	const goroutines, calls = 8, 100
+
+	stub := NewStubThinger()
+	stub.OnThing().Return(nil)
+	stub.OnThingWithParam().Return(nil)
+	stub.OnThingWithParams().Return("example", nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < calls; j++ {
+				stub.Thing()
+				stub.ThingWithParam(42)
+				stub.ThingWithParams(42, "example")
+				stub.Sequence()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := stub.ThingCalls.Len(); got != goroutines*calls {
+		t.Errorf("expected %v calls to Thing, got %v", goroutines*calls, got)
+	}
+	if got := stub.ThingWithParamCalls.Len(); got != goroutines*calls {
+		t.Errorf("expected %v calls to ThingWithParam, got %v", goroutines*calls, got)
+	}
+	if got := stub.ThingWithParamsCalls.Len(); got != goroutines*calls {
+		t.Errorf("expected %v calls to ThingWithParams, got %v", goroutines*calls, got)
+	}
+}
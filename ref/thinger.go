@@ -1,6 +1,11 @@
 package ref
 
+//go:generate go run .. -pkg ref_stubs -with-example -with-race-test -with-fuzz -o stubs/stubthinger.go . Thinger
+//go:generate go run .. -style retry -o retry_thinger.go . Thinger
+//go:generate go run .. -style breaker -o breaker_thinger.go . Thinger
+
 type Thinger interface {
 	Thing() error
 	ThingWithParam(arg1 int) error
+	ThingWithParams(arg1 int, arg2 string) (string, error)
 }
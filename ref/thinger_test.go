@@ -2,8 +2,12 @@ package ref_test
 
 import (
 	"errors"
+	"strings"
 	"testing"
-	refstubs "toe/ref/stubs"
+
+	"github.com/phildrip/toe/ref"
+	refstubs "github.com/phildrip/toe/ref/stubs"
+	"github.com/phildrip/toe/runtime"
 )
 
 func TestRef(t *testing.T) {
@@ -54,3 +58,97 @@ func TestRef(t *testing.T) {
 	}
 
 }
+
+func TestRefMatchers(t *testing.T) {
+	stub := refstubs.NewStubThinger()
+
+	stub.OnThingWithParams().Return("default", nil)
+	stub.OnThingWithParams(1, runtime.Any()).Return("one", nil)
+
+	out, _ := stub.ThingWithParams(1, "a")
+	if out != "one" {
+		t.Errorf("expected %v, got %v", "one", out)
+	}
+
+	out, _ = stub.ThingWithParams(2, "a")
+	if out != "default" {
+		t.Errorf("expected %v, got %v", "default", out)
+	}
+
+	stub.Thing()
+	sequence := stub.Sequence()
+	if len(sequence) != 3 || sequence[2].Method != "Thing" {
+		t.Errorf("expected 3 calls ending with Thing, got %v", sequence)
+	}
+}
+
+func TestRefReturnGenerated(t *testing.T) {
+	stub := refstubs.NewStubThinger()
+
+	n := 0
+	stub.OnThingWithParams().ReturnOnce("once", nil).ReturnGenerated(func() (string, error) {
+		n++
+		return strings.Repeat("x", n), nil
+	})
+
+	for _, expected := range []string{"once", "x", "xx"} {
+		if out, _ := stub.ThingWithParams(1, "a"); out != expected {
+			t.Errorf("expected %v, got %v", expected, out)
+		}
+	}
+}
+
+func TestRefZeroValue(t *testing.T) {
+	var fixture struct {
+		stub refstubs.StubThinger
+	}
+	err1 := errors.New("error1")
+	fixture.stub.OnThingWithParam(1).Return(err1)
+
+	if err := fixture.stub.ThingWithParam(1); err != err1 {
+		t.Errorf("expected %v, got %v", err1, err)
+	}
+	if fixture.stub.ThingWithParamCalls.Len() != 1 {
+		t.Errorf("expected %v, got %v", 1, fixture.stub.ThingWithParamCalls.Len())
+	}
+	var b strings.Builder
+	if err := fixture.stub.DumpInteractions(&b, runtime.FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), `"StubThinger"`) {
+		t.Errorf("expected %v, got %v", "a call to StubThinger", b.String())
+	}
+}
+
+// hybridThinger overrides ThingWithParam of the stub it embeds.
+type hybridThinger struct {
+	*refstubs.StubThinger
+}
+
+func (h hybridThinger) ThingWithParam(arg1 int) error {
+	h.RecordThingWithParam(arg1)
+	if arg1 < 0 {
+		return errors.New("negative")
+	}
+	return nil
+}
+
+func TestRefEmbedded(t *testing.T) {
+	stub := refstubs.NewStubThinger()
+	err1 := errors.New("error1")
+	stub.OnThing().Return(err1)
+	var thinger ref.Thinger = hybridThinger{stub}
+
+	if err := thinger.Thing(); err != err1 {
+		t.Errorf("expected %v, got %v", err1, err)
+	}
+	if err := thinger.ThingWithParam(-1); err == nil {
+		t.Errorf("expected an error, got nil")
+	}
+	if stub.ThingWithParamCalls.Len() != 1 || stub.ThingWithParamCalls.Last().Arg1 != -1 {
+		t.Errorf("expected %v, got %v", -1, stub.ThingWithParamCalls)
+	}
+	if calls := stub.Sequence(); len(calls) != 2 {
+		t.Errorf("expected %v, got %v", 2, len(calls))
+	}
+}
@@ -0,0 +1,40 @@
+package runtime
+
+import (
+	"reflect"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// diffOptions compare the unexported fields of arguments too, as the
+// stub's calls are only compared in tests.
+var diffOptions = []cmp.Option{cmp.Exporter(func(reflect.Type) bool { return true })}
+
+// AssertCalledWith fails t unless one of the calls to method recorded in
+// calls had arguments equal to those in want. The failure shows a diff of
+// want against the call that differs least from it.
+func AssertCalledWith[P any](t TB, s *Stub, method string, calls *Calls[P], want P) {
+	s.mut.Lock()
+	recorded := append(Calls[P](nil), *calls...)
+	name := s.name
+	s.mut.Unlock()
+	useMethod(name, method, asserted)
+
+	call := formatCall(name, method, argsOf(want))
+	if len(recorded) == 0 {
+		t.Errorf("toe: %s not called: there were no calls to %s", call, method)
+		return
+	}
+	var closest string
+	for _, got := range recorded {
+		diff := cmp.Diff(want, got, diffOptions...)
+		if diff == "" {
+			return
+		}
+		if closest == "" || len(diff) < len(closest) {
+			closest = diff
+		}
+	}
+	t.Errorf("toe: %s not called; diff with the closest of %d calls (-want +got):\n%s",
+		call, len(recorded), closest)
+}
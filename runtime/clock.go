@@ -0,0 +1,111 @@
+package runtime
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock is a fake clock backing the Now, Since, Until, After and Sleep
+// methods of stubs of clock-like interfaces when they aren't configured.
+// Its time only moves when it is advanced, firing the timers started by
+// After and Sleep whose deadline it passes, in deadline order. Its zero
+// value is ready to use, at the zero time.
+type Clock struct {
+	mut    sync.Mutex
+	cond   *sync.Cond
+	now    time.Time
+	timers []clockTimer
+}
+
+// clockTimer is a timer started by After or Sleep.
+type clockTimer struct {
+	deadline time.Time
+	c        chan time.Time
+}
+
+// Now returns the clock's time.
+func (c *Clock) Now() time.Time {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return c.now
+}
+
+// Since returns the time elapsed since t.
+func (c *Clock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// Until returns the duration until t.
+func (c *Clock) Until(t time.Time) time.Duration {
+	return t.Sub(c.Now())
+}
+
+// After returns a channel receiving the time d after the clock's time, once
+// the clock reaches it.
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.timers = append(c.timers, clockTimer{deadline: c.now.Add(d), c: ch})
+	sort.SliceStable(c.timers, func(i, j int) bool {
+		return c.timers[i].deadline.Before(c.timers[j].deadline)
+	})
+	c.broadcast()
+	return ch
+}
+
+// Sleep blocks until the clock has been advanced by d.
+func (c *Clock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+// Advance moves the clock forward by d, firing the timers it passes.
+func (c *Clock) Advance(d time.Duration) {
+	c.Set(c.Now().Add(d))
+}
+
+// Set sets the clock's time, firing the timers whose deadline is at or
+// before it.
+func (c *Clock) Set(t time.Time) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	c.now = t
+	for len(c.timers) > 0 && !c.timers[0].deadline.After(t) {
+		c.timers[0].c <- c.timers[0].deadline
+		c.timers = c.timers[1:]
+	}
+	c.broadcast()
+}
+
+// WaitForTimers blocks until at least n timers are waiting for the clock to
+// be advanced, so that a test can advance it once the code under test is
+// sleeping.
+func (c *Clock) WaitForTimers(n int) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	for len(c.timers) < n {
+		c.condition().Wait()
+	}
+}
+
+// condition returns the condition broadcast when the timers change. c.mut
+// must be held.
+func (c *Clock) condition() *sync.Cond {
+	if c.cond == nil {
+		c.cond = sync.NewCond(&c.mut)
+	}
+	return c.cond
+}
+
+// broadcast wakes the goroutines waiting in WaitForTimers. c.mut must be
+// held.
+func (c *Clock) broadcast() {
+	c.condition().Broadcast()
+}
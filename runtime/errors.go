@@ -0,0 +1,45 @@
+package runtime
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ErrNotConfigured is the error for a call to a stub method without a
+// configured result, returned by the methods of stubs generated with
+// -error-unconfigured.
+type ErrNotConfigured struct {
+	// Stub and Method are the names of the stub type and the method.
+	Stub   string
+	Method string
+	// Args are the arguments of the call.
+	Args []any
+}
+
+// NotConfigured returns an ErrNotConfigured for a call to method of stub
+// with params, the method's Params struct.
+func NotConfigured(stub string, method string, params any) error {
+	return &ErrNotConfigured{Stub: stub, Method: method, Args: argsOf(params)}
+}
+
+func (e *ErrNotConfigured) Error() string {
+	return "toe: " + formatCall(e.Stub, e.Method, e.Args) + " not configured"
+}
+
+// formatCall formats a call to method of the stub named stub with args, as
+// in `StubThinger.ThingWithParams(1, "a")`.
+func formatCall(stub string, method string, args []any) string {
+	formatted := make([]string, len(args))
+	for i, arg := range args {
+		formatted[i] = formatArg(arg)
+	}
+	return fmt.Sprintf("%s.%s(%s)", stub, method, strings.Join(formatted, ", "))
+}
+
+// formatArg formats an argument of a call, quoting strings.
+func formatArg(arg any) string {
+	if s, ok := arg.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	return fmt.Sprint(arg)
+}
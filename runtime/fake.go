@@ -0,0 +1,111 @@
+package runtime
+
+import (
+	"hash/fnv"
+	"math/rand/v2"
+	"reflect"
+)
+
+// fakeDepth is how deep Fake populates nested values; pointers, slices and
+// maps below it are left nil, so recursive types are finite.
+const fakeDepth = 4
+
+// fakeSource generates the values returned by the unconfigured calls of a
+// stub in fake mode.
+type fakeSource struct {
+	seed uint64
+	// calls is the number of unconfigured calls made to each method.
+	calls map[string]uint64
+}
+
+// rand returns the generator for the next unconfigured call to method.
+// The stub's lock must be held.
+func (f *fakeSource) rand(method string) *rand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(method))
+	n := f.calls[method]
+	f.calls[method]++
+	return rand.New(rand.NewPCG(f.seed, h.Sum64()+n))
+}
+
+// fakeResult returns the result of an unconfigured call to method, and
+// false if the stub isn't in fake mode.
+func fakeResult[R any](s *Stub, method string) (R, bool) {
+	var ret R
+	s.mut.Lock()
+	if s.fake == nil {
+		s.mut.Unlock()
+		return ret, false
+	}
+	r := s.fake.rand(method)
+	s.mut.Unlock()
+	fill(r, reflect.ValueOf(&ret).Elem(), fakeDepth)
+	return ret, true
+}
+
+// fill sets v, which must be settable, to a pseudo-random value from r.
+func fill(r *rand.Rand, v reflect.Value, depth int) {
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(r.IntN(2) == 1)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(1 + r.Int64N(100))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		v.SetUint(1 + r.Uint64N(100))
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(float64(1+r.IntN(10000)) / 100)
+	case reflect.Complex64, reflect.Complex128:
+		v.SetComplex(complex(float64(1+r.IntN(100)), float64(1+r.IntN(100))))
+	case reflect.String:
+		v.SetString(fakeString(r))
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			fill(r, v.Index(i), depth)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				fill(r, v.Field(i), depth)
+			}
+		}
+	case reflect.Pointer:
+		if depth == 0 {
+			return
+		}
+		p := reflect.New(v.Type().Elem())
+		fill(r, p.Elem(), depth-1)
+		v.Set(p)
+	case reflect.Slice:
+		if depth == 0 {
+			return
+		}
+		s := reflect.MakeSlice(v.Type(), 1+r.IntN(3), 3)
+		for i := 0; i < s.Len(); i++ {
+			fill(r, s.Index(i), depth-1)
+		}
+		v.Set(s)
+	case reflect.Map:
+		if depth == 0 {
+			return
+		}
+		m := reflect.MakeMap(v.Type())
+		for n := 1 + r.IntN(3); n > 0; n-- {
+			key := reflect.New(v.Type().Key()).Elem()
+			elem := reflect.New(v.Type().Elem()).Elem()
+			fill(r, key, depth-1)
+			fill(r, elem, depth-1)
+			m.SetMapIndex(key, elem)
+		}
+		v.Set(m)
+	}
+}
+
+// fakeString returns a pseudo-random lower-case word from r.
+func fakeString(r *rand.Rand) string {
+	const letters = "abcdefghijklmnopqrstuvwxyz"
+	b := make([]byte, 4+r.IntN(8))
+	for i := range b {
+		b[i] = letters[r.IntN(len(letters))]
+	}
+	return string(b)
+}
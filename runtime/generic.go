@@ -0,0 +1,353 @@
+package runtime
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"time"
+)
+
+// Calls is the list of calls made to a method, each recorded as the
+// method's Params struct.
+type Calls[T any] []T
+
+// ResetCalls returns a function, for Stub.Scope, that clears calls and
+// returns a function restoring them.
+func ResetCalls[T any](calls *Calls[T]) func() func() {
+	return func() func() {
+		saved := *calls
+		*calls = nil
+		return func() { *calls = saved }
+	}
+}
+
+// Len returns the number of calls.
+func (c Calls[T]) Len() int {
+	return len(c)
+}
+
+// Last returns the most recent call, or the zero T if there are none.
+func (c Calls[T]) Last() T {
+	if len(c) == 0 {
+		var zero T
+		return zero
+	}
+	return c[len(c)-1]
+}
+
+// CallsOf returns a copy of calls, the calls to a method of s, for
+// assertions made while the stub may still be called.
+func CallsOf[T any](s *Stub, calls *Calls[T]) Calls[T] {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return append(Calls[T](nil), *calls...)
+}
+
+// AssertedCalls is CallsOf for the assertions on the calls to method made
+// by generated helpers, which count towards the method's usage; see
+// CollectUsage.
+func AssertedCalls[T any](s *Stub, method string, calls *Calls[T]) Calls[T] {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	useMethod(s.name, method, asserted)
+	return append(Calls[T](nil), *calls...)
+}
+
+// ReturnQueue holds the results returned by an expectation. Results added
+// with Push are returned once each, in order; once they are used up, the
+// result added with Set is returned by every call.
+type ReturnQueue[R any] struct {
+	queue  []R
+	ret    R
+	hasRet bool
+}
+
+// Push queues r to be returned once.
+func (q *ReturnQueue[R]) Push(r R) {
+	q.queue = append(q.queue, r)
+}
+
+// Set sets the result returned once the queue is empty.
+func (q *ReturnQueue[R]) Set(r R) {
+	q.ret = r
+	q.hasRet = true
+}
+
+// Next returns the next result, or false if there is none.
+func (q *ReturnQueue[R]) Next() (R, bool) {
+	if len(q.queue) > 0 {
+		r := q.queue[0]
+		q.queue = q.queue[1:]
+		return r, true
+	}
+	return q.ret, q.hasRet
+}
+
+// Expectation is the behaviour configured for the calls to a method whose
+// arguments match. P and R are the method's Params and Ret structs.
+type Expectation[P any, R any] struct {
+	stub     *Stub
+	matchers []Matcher
+	rets     ReturnQueue[R]
+	// fn computes the results once rets is used up, if set.
+	fn func(P) R
+	// times is the number of calls matched, and maxTimes the most allowed,
+	// or -1 for no limit.
+	times    int
+	maxTimes int
+	// setArgs are the values set with SetArg.
+	setArgs []setArg
+	// inFlight is the number of matching calls in progress, and
+	// maxConcurrent the most allowed, or 0 for no limit, beyond which calls
+	// return busy.
+	inFlight      int
+	maxConcurrent int
+	busy          R
+	// timeout is the duration set with TimeoutAfter, if hasTimeout.
+	timeout    time.Duration
+	hasTimeout bool
+	// failRate is the probability that a call fails with failErr, drawn
+	// from failRand, set with FailRandomly.
+	failRate float64
+	failErr  error
+	failRand *rand.PCG
+	// latency is the distribution of the latency of calls, drawn from
+	// latencyRand, set with Latency.
+	latency     Latency
+	latencyRand *rand.PCG
+}
+
+// setArg is a value to store through a pointer argument of a call.
+type setArg struct {
+	index int
+	value reflect.Value
+}
+
+// Return sets the result of every matching call, after any results added
+// with ReturnOnce have been used.
+func (e *Expectation[P, R]) Return(ret R) {
+	e.stub.mut.Lock()
+	defer e.stub.mut.Unlock()
+	e.rets.Set(ret)
+	e.fn = nil
+}
+
+// ReturnOnce adds a result to be returned by a single matching call.
+func (e *Expectation[P, R]) ReturnOnce(ret R) {
+	e.stub.mut.Lock()
+	defer e.stub.mut.Unlock()
+	e.rets.Push(ret)
+}
+
+// ReturnFunc sets a function computing the result of every matching call
+// from its arguments, after any results added with ReturnOnce have been
+// used. It replaces the result set with Return. fn is called without the
+// stub's lock held, so it may configure the stub.
+func (e *Expectation[P, R]) ReturnFunc(fn func(P) R) {
+	e.stub.mut.Lock()
+	defer e.stub.mut.Unlock()
+	e.fn = fn
+	e.rets.hasRet = false
+}
+
+// MaxTimes sets the most calls the expectation may match. Each later
+// matching call fails the test bound with WithT, or panics if there is
+// none; it still returns the configured results.
+func (e *Expectation[P, R]) MaxTimes(n int) {
+	e.stub.mut.Lock()
+	defer e.stub.mut.Unlock()
+	e.maxTimes = n
+}
+
+// MaxConcurrent makes matching calls return busy while n others are in
+// progress, such as running the function set with ReturnFunc, rather than
+// the configured results, to simulate a dependency limiting concurrency.
+// n must be positive.
+func (e *Expectation[P, R]) MaxConcurrent(n int, busy R) {
+	if n <= 0 {
+		panic(fmt.Sprintf("toe: MaxConcurrent: %d calls must be positive", n))
+	}
+	e.stub.mut.Lock()
+	defer e.stub.mut.Unlock()
+	e.maxConcurrent = n
+	e.busy = busy
+}
+
+// TimeoutAfter makes matching calls block until their context argument is
+// done, or d passes on the stub's clock, and return the context's error or
+// else context.DeadlineExceeded, with zero values for the other results,
+// instead of the configured results. The stub's clock is that set with
+// WithClock if it has an After method, as a *Clock does, and otherwise the
+// system clock.
+func (e *Expectation[P, R]) TimeoutAfter(d time.Duration) {
+	e.stub.mut.Lock()
+	defer e.stub.mut.Unlock()
+	e.timeout = d
+	e.hasTimeout = true
+}
+
+// FailRandomly makes each matching call fail with probability rate,
+// returning err with zero values for the other results instead of the
+// configured results. The failures are drawn from a pseudo-random sequence
+// derived from seed, so the same calls fail on every run. rate must be
+// between 0 and 1.
+func (e *Expectation[P, R]) FailRandomly(rate float64, err error, seed uint64) {
+	if rate < 0 || rate > 1 {
+		panic(fmt.Sprintf("toe: FailRandomly: rate %v must be between 0 and 1", rate))
+	}
+	e.stub.mut.Lock()
+	defer e.stub.mut.Unlock()
+	e.failRate = rate
+	e.failErr = err
+	e.failRand = rand.NewPCG(seed, 0)
+}
+
+// Latency makes matching calls take a duration drawn from l, on the
+// stub's clock, before returning, unless their context argument is done
+// first, in which case calls returning an error return its error instead.
+// The durations are drawn from a pseudo-random sequence derived from seed,
+// so they are the same on every run. The stub's clock is that set with
+// WithClock if it has an After method, as a *Clock does, and otherwise the
+// system clock.
+func (e *Expectation[P, R]) Latency(l Latency, seed uint64) {
+	e.stub.mut.Lock()
+	defer e.stub.mut.Unlock()
+	e.latency = l
+	e.latencyRand = rand.NewPCG(seed, 0)
+}
+
+// fails reports whether a matching call fails randomly; see FailRandomly.
+// The stub's lock must be held.
+func (e *Expectation[P, R]) fails() bool {
+	return e.failRand != nil && rand.New(e.failRand).Float64() < e.failRate
+}
+
+// acquire counts a matching call as in progress, returning false if there
+// are already as many as MaxConcurrent allows. The stub's lock must be
+// held.
+func (e *Expectation[P, R]) acquire() bool {
+	if e.maxConcurrent > 0 && e.inFlight >= e.maxConcurrent {
+		return false
+	}
+	e.inFlight++
+	return true
+}
+
+// release counts a matching call as finished.
+func (e *Expectation[P, R]) release() {
+	e.stub.mut.Lock()
+	defer e.stub.mut.Unlock()
+	e.inFlight--
+}
+
+// count counts a call to method of the stub named stub with args matching
+// the expectation, returning an error if it is more than MaxTimes allows.
+// The stub's lock must be held.
+func (e *Expectation[P, R]) count(stub string, method string, args []any) error {
+	e.times++
+	if e.maxTimes >= 0 && e.times > e.maxTimes {
+		return fmt.Errorf("toe: %s called %d times, more than the maximum of %d",
+			formatCall(stub, method, args), e.times, e.maxTimes)
+	}
+	return nil
+}
+
+// SetArg makes every matching call store value where its argument i, a
+// pointer, points, for methods filling in values passed by pointer. It
+// panics if argument i isn't a pointer value can be stored through.
+func (e *Expectation[P, R]) SetArg(i int, value any) {
+	params := reflect.TypeOf((*P)(nil)).Elem()
+	if params.Kind() != reflect.Struct || i < 0 || i >= params.NumField() ||
+		params.Field(i).Type.Kind() != reflect.Pointer {
+		panic(fmt.Sprintf("toe: SetArg: argument %d of %v is not a pointer", i, params))
+	}
+	v := reflect.ValueOf(value)
+	target := params.Field(i).Type.Elem()
+	if !v.IsValid() {
+		v = reflect.Zero(target)
+	}
+	if !v.Type().AssignableTo(target) {
+		panic(fmt.Sprintf("toe: SetArg: %v can't be stored in argument %d of type %v",
+			v.Type(), i, params.Field(i).Type))
+	}
+
+	e.stub.mut.Lock()
+	defer e.stub.mut.Unlock()
+	e.setArgs = append(e.setArgs, setArg{index: i, value: v})
+}
+
+// set stores the values set with SetArg through the arguments of a call
+// the expectation is used for, skipping nil pointers. The stub's lock must
+// be held.
+func (e *Expectation[P, R]) set(params P) {
+	fields := reflect.ValueOf(params)
+	for _, a := range e.setArgs {
+		if ptr := fields.Field(a.index); !ptr.IsNil() {
+			ptr.Elem().Set(a.value)
+		}
+	}
+}
+
+// capture copies args, those of a call the expectation is used for, into the
+// variables of its Capture matchers. The stub's lock must be held.
+func (e *Expectation[P, R]) capture(args []any) {
+	for i, m := range e.matchers {
+		if c, ok := m.(capturer); ok {
+			c.capture(args[i])
+		}
+	}
+}
+
+// next returns the result of a call, or the function set with ReturnFunc
+// computing it, or false if there is neither. The function is returned
+// rather than called so that it runs without the stub's lock, free to
+// configure the stub.
+func (e *Expectation[P, R]) next() (R, func(P) R, bool) {
+	if ret, ok := e.rets.Next(); ok || e.fn == nil {
+		return ret, nil, ok
+	}
+	var zero R
+	return zero, e.fn, true
+}
+
+func (e *Expectation[P, R]) clone(s *Stub) expectation {
+	c := *e
+	c.stub = s
+	c.rets.queue = append([]R(nil), e.rets.queue...)
+	c.setArgs = append([]setArg(nil), e.setArgs...)
+	c.inFlight = 0
+	if e.failRand != nil {
+		pcg := *e.failRand
+		c.failRand = &pcg
+	}
+	if e.latencyRand != nil {
+		pcg := *e.latencyRand
+		c.latencyRand = &pcg
+	}
+	return &c
+}
+
+func (e *Expectation[P, R]) catchAll() bool {
+	return len(e.matchers) == 0
+}
+
+func (e *Expectation[P, R]) matches(args []any) bool {
+	return matchAll(e.matchers, args)
+}
+
+// matchAll reports whether each of args matches the corresponding matcher,
+// or there are no matchers.
+func matchAll(matchers []Matcher, args []any) bool {
+	if len(matchers) == 0 {
+		return true
+	}
+	if len(matchers) != len(args) {
+		return false
+	}
+	for i, m := range matchers {
+		if !m.Matches(args[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,24 @@
+package runtime
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// NewHTTPResponse returns a response to req with status and body, as
+// returned by the RespondWith configurator of stubs of http.RoundTripper.
+func NewHTTPResponse(req *http.Request, status int, body string) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        make(http.Header),
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,142 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Formats of the interactions written by DumpInteractions.
+const (
+	FormatDOT  = "dot"
+	FormatJSON = "json"
+)
+
+// Interaction is a call recorded for DumpInteractions.
+type Interaction struct {
+	// Seq is the call's position in the timeline, from 1.
+	Seq    int    `json:"seq"`
+	Stub   string `json:"stub"`
+	Method string `json:"method"`
+	// Args are the call's arguments, formatted as in errors.
+	Args []string `json:"args"`
+}
+
+// Recorder records the calls made to several stubs in a single timeline,
+// for DumpInteractions. Stubs share a recorder with the WithRecorder
+// option. Its zero value is ready to use.
+type Recorder struct {
+	mut          sync.Mutex
+	interactions []Interaction
+}
+
+// record adds a call to the timeline.
+func (r *Recorder) record(stub string, method string, args []any) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	r.interactions = append(r.interactions, newInteraction(len(r.interactions)+1, stub, method, args))
+}
+
+// Interactions returns the calls recorded, in the order they were made.
+func (r *Recorder) Interactions() []Interaction {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	return append([]Interaction(nil), r.interactions...)
+}
+
+// Dump writes the calls recorded to w in format, FormatDOT or FormatJSON.
+func (r *Recorder) Dump(w io.Writer, format string) error {
+	return dumpInteractions(w, format, r.Interactions())
+}
+
+// DumpInteractions writes the calls made to the stub to w in format: a
+// Graphviz graph for FormatDOT, with a node for each call, linked in the
+// order they were made and grouped by stub, or a list of Interactions for
+// FormatJSON. If the stub shares a Recorder, the calls made to the other
+// stubs sharing it are written too.
+func (s *Stub) DumpInteractions(w io.Writer, format string) error {
+	s.mut.Lock()
+	recorder := s.recorder
+	var interactions []Interaction
+	if recorder == nil {
+		for i, call := range s.calls {
+			interactions = append(interactions, newInteraction(i+1, s.name, call.Method, call.Args))
+		}
+	}
+	s.mut.Unlock()
+
+	if recorder != nil {
+		return recorder.Dump(w, format)
+	}
+	return dumpInteractions(w, format, interactions)
+}
+
+// newInteraction returns the Interaction for a call to method with args.
+func newInteraction(seq int, stub string, method string, args []any) Interaction {
+	in := Interaction{Seq: seq, Stub: stub, Method: method, Args: make([]string, len(args))}
+	for i, arg := range args {
+		in.Args[i] = formatArg(arg)
+	}
+	return in
+}
+
+// dumpInteractions writes interactions to w in format.
+func dumpInteractions(w io.Writer, format string, interactions []Interaction) error {
+	switch format {
+	case FormatJSON:
+		if interactions == nil {
+			interactions = []Interaction{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(interactions)
+	case FormatDOT:
+		return dumpDOT(w, interactions)
+	}
+	return fmt.Errorf("unknown interactions format %q: want %q or %q", format, FormatDOT, FormatJSON)
+}
+
+// dumpDOT writes interactions to w as a Graphviz graph.
+func dumpDOT(w io.Writer, interactions []Interaction) error {
+	var stubs []string
+	byStub := make(map[string][]Interaction)
+	for _, in := range interactions {
+		if _, ok := byStub[in.Stub]; !ok {
+			stubs = append(stubs, in.Stub)
+		}
+		byStub[in.Stub] = append(byStub[in.Stub], in)
+	}
+
+	ew := &errWriter{w: w}
+	ew.printf("digraph interactions {\n")
+	ew.printf("\tnode [shape=box];\n")
+	for i, stub := range stubs {
+		ew.printf("\tsubgraph cluster_%d {\n", i)
+		ew.printf("\t\tlabel=%s;\n", strconv.Quote(stub))
+		for _, in := range byStub[stub] {
+			label := fmt.Sprintf("%d. %s(%s)", in.Seq, in.Method, strings.Join(in.Args, ", "))
+			ew.printf("\t\tcall%d [label=%s];\n", in.Seq, strconv.Quote(label))
+		}
+		ew.printf("\t}\n")
+	}
+	for i := 1; i < len(interactions); i++ {
+		ew.printf("\tcall%d -> call%d;\n", interactions[i-1].Seq, interactions[i].Seq)
+	}
+	ew.printf("}\n")
+	return ew.err
+}
+
+// errWriter writes to w until a write fails, keeping the error.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (e *errWriter) printf(format string, args ...any) {
+	if e.err == nil {
+		_, e.err = fmt.Fprintf(e.w, format, args...)
+	}
+}
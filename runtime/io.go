@@ -0,0 +1,49 @@
+package runtime
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// Content backs the Read and Write methods of stubs of io.Reader and
+// io.Writer when they aren't configured: reads consume the content set with
+// SetRead, and writes are appended to the bytes returned by Written. Its
+// zero value has nothing to read.
+type Content struct {
+	mut     sync.Mutex
+	read    bytes.Reader
+	written bytes.Buffer
+}
+
+// SetRead sets the content read, from its start.
+func (c *Content) SetRead(b []byte) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.read.Reset(bytes.Clone(b))
+}
+
+// Read reads from the content set with SetRead, returning io.EOF once it
+// is used up.
+func (c *Content) Read(p []byte) (int, error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if c.read.Len() == 0 {
+		return 0, io.EOF
+	}
+	return c.read.Read(p)
+}
+
+// Write appends p to the written bytes.
+func (c *Content) Write(p []byte) (int, error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return c.written.Write(p)
+}
+
+// Written returns a copy of the bytes written.
+func (c *Content) Written() []byte {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return bytes.Clone(c.written.Bytes())
+}
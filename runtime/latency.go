@@ -0,0 +1,87 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"time"
+)
+
+// Latency is a distribution of the latency of calls, drawing a duration
+// from r. FixedLatency, UniformLatency, NormalLatency and
+// PercentileLatency return common distributions.
+type Latency func(r *rand.Rand) time.Duration
+
+// FixedLatency returns the distribution of calls always taking d.
+func FixedLatency(d time.Duration) Latency {
+	return func(*rand.Rand) time.Duration { return d }
+}
+
+// UniformLatency returns the distribution of calls taking between min and
+// max, all as likely.
+func UniformLatency(min, max time.Duration) Latency {
+	if max < min {
+		panic(fmt.Sprintf("toe: UniformLatency: max %v is less than min %v", max, min))
+	}
+	return func(r *rand.Rand) time.Duration {
+		return min + time.Duration(r.Int64N(int64(max-min)+1))
+	}
+}
+
+// NormalLatency returns the normal distribution of calls with the mean and
+// standard deviation given, less the calls that would take negative time,
+// which take none.
+func NormalLatency(mean, stddev time.Duration) Latency {
+	return func(r *rand.Rand) time.Duration {
+		return max(0, mean+time.Duration(r.NormFloat64()*float64(stddev)))
+	}
+}
+
+// PercentileLatency returns the distribution of calls with the latencies
+// in table at the percentiles they're keyed by, such as
+// {50: 10 * time.Millisecond, 99: 200 * time.Millisecond}, interpolating
+// linearly between them. Calls below the lowest percentile take its
+// latency, and those above the highest its latency.
+func PercentileLatency(table map[float64]time.Duration) Latency {
+	if len(table) == 0 {
+		panic("toe: PercentileLatency: the table is empty")
+	}
+	percentiles := make([]float64, 0, len(table))
+	for p := range table {
+		if p < 0 || p > 100 {
+			panic(fmt.Sprintf("toe: PercentileLatency: percentile %v is not between 0 and 100", p))
+		}
+		percentiles = append(percentiles, p)
+	}
+	sort.Float64s(percentiles)
+
+	return func(r *rand.Rand) time.Duration {
+		u := r.Float64() * 100
+		i := sort.SearchFloat64s(percentiles, u)
+		switch {
+		case i == 0:
+			return table[percentiles[0]]
+		case i == len(percentiles):
+			return table[percentiles[i-1]]
+		}
+		lo, hi := percentiles[i-1], percentiles[i]
+		dlo, dhi := table[lo], table[hi]
+		return dlo + time.Duration((u-lo)/(hi-lo)*float64(dhi-dlo))
+	}
+}
+
+// sleep blocks until ctx, which may be nil, is done or wake fires,
+// returning ctx's error in the first case and nil in the second.
+func sleep(ctx context.Context, wake <-chan time.Time) error {
+	var done <-chan struct{}
+	if ctx != nil {
+		done = ctx.Done()
+	}
+	select {
+	case <-done:
+		return ctx.Err()
+	case <-wake:
+		return nil
+	}
+}
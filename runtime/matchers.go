@@ -0,0 +1,255 @@
+package runtime
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Matcher matches the argument of a call against an expectation.
+type Matcher interface {
+	// Matches reports whether arg matches.
+	Matches(arg any) bool
+	// String describes the arguments matched.
+	String() string
+}
+
+// matcherFor returns arg if it is a Matcher, and otherwise a matcher for
+// arguments equal to it.
+func matcherFor(arg any) Matcher {
+	if m, ok := arg.(Matcher); ok {
+		return m
+	}
+	return Eq(arg)
+}
+
+type anyMatcher struct{}
+
+// Any returns a Matcher matching every argument.
+func Any() Matcher {
+	return anyMatcher{}
+}
+
+func (anyMatcher) Matches(any) bool {
+	return true
+}
+
+func (anyMatcher) String() string {
+	return "any"
+}
+
+type eqMatcher struct {
+	want any
+}
+
+// Eq returns a Matcher matching arguments deeply equal to want.
+func Eq(want any) Matcher {
+	return eqMatcher{want: want}
+}
+
+func (m eqMatcher) Matches(arg any) bool {
+	return reflect.DeepEqual(arg, m.want)
+}
+
+func (m eqMatcher) String() string {
+	return fmt.Sprintf("%v", m.want)
+}
+
+type regexMatcher struct {
+	re *regexp.Regexp
+}
+
+// Regex returns a Matcher matching strings, byte slices and fmt.Stringers
+// matching the regular expression pattern. It panics if pattern doesn't
+// compile.
+func Regex(pattern string) Matcher {
+	return regexMatcher{re: regexp.MustCompile(pattern)}
+}
+
+func (m regexMatcher) Matches(arg any) bool {
+	switch arg := arg.(type) {
+	case string:
+		return m.re.MatchString(arg)
+	case []byte:
+		return m.re.Match(arg)
+	case fmt.Stringer:
+		return m.re.MatchString(arg.String())
+	}
+	return false
+}
+
+func (m regexMatcher) String() string {
+	return "matching /" + m.re.String() + "/"
+}
+
+type lenMatcher struct {
+	n int
+}
+
+// Len returns a Matcher matching slices, arrays, maps, strings and
+// channels of length n.
+func Len(n int) Matcher {
+	return lenMatcher{n: n}
+}
+
+func (m lenMatcher) Matches(arg any) bool {
+	v := reflect.ValueOf(arg)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.String, reflect.Chan:
+		return v.Len() == m.n
+	}
+	return false
+}
+
+func (m lenMatcher) String() string {
+	return fmt.Sprintf("of length %d", m.n)
+}
+
+type containsMatcher struct {
+	elem any
+}
+
+// Contains returns a Matcher matching slices and arrays with an element
+// matching elem, which is a Matcher or a value the element must equal, and
+// strings containing the string elem.
+func Contains(elem any) Matcher {
+	return containsMatcher{elem: elem}
+}
+
+func (m containsMatcher) Matches(arg any) bool {
+	if s, ok := arg.(string); ok {
+		sub, ok := m.elem.(string)
+		return ok && strings.Contains(s, sub)
+	}
+	v := reflect.ValueOf(arg)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return false
+	}
+	elem := matcherFor(m.elem)
+	for i := 0; i < v.Len(); i++ {
+		if elem.Matches(v.Index(i).Interface()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m containsMatcher) String() string {
+	return fmt.Sprintf("containing %v", matcherFor(m.elem))
+}
+
+type mapKeysMatcher struct {
+	keys []any
+}
+
+// MapKeys returns a Matcher matching maps whose keys are exactly keys, in
+// any order. Each of keys is a Matcher or a value the key must equal, and
+// must match a different key of the map.
+func MapKeys(keys ...any) Matcher {
+	return mapKeysMatcher{keys: keys}
+}
+
+func (m mapKeysMatcher) Matches(arg any) bool {
+	v := reflect.ValueOf(arg)
+	if v.Kind() != reflect.Map || v.Len() != len(m.keys) {
+		return false
+	}
+	remaining := v.MapKeys()
+	for _, key := range m.keys {
+		matcher := matcherFor(key)
+		found := false
+		for i, k := range remaining {
+			if matcher.Matches(k.Interface()) {
+				remaining = append(remaining[:i], remaining[i+1:]...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (m mapKeysMatcher) String() string {
+	keys := make([]string, len(m.keys))
+	for i, key := range m.keys {
+		keys[i] = matcherFor(key).String()
+	}
+	return "with keys [" + strings.Join(keys, ", ") + "]"
+}
+
+// OmegaMatcher is the method set of gomega's types.GomegaMatcher, which
+// every Gomega matcher implements, declared here so that the runtime
+// doesn't depend on Gomega.
+type OmegaMatcher interface {
+	Match(actual any) (success bool, err error)
+	FailureMessage(actual any) (message string)
+	NegatedFailureMessage(actual any) (message string)
+}
+
+type gomegaMatcher struct {
+	m OmegaMatcher
+}
+
+// Gomega returns a Matcher matching the arguments m succeeds on, so that
+// any Gomega matcher, such as gomega.HaveKeyWithValue("id", 7), can be
+// used as an argument matcher. Arguments m returns an error for don't
+// match.
+func Gomega(m OmegaMatcher) Matcher {
+	return gomegaMatcher{m: m}
+}
+
+func (m gomegaMatcher) Matches(arg any) bool {
+	success, err := m.m.Match(arg)
+	return err == nil && success
+}
+
+func (m gomegaMatcher) String() string {
+	// Gomega matchers describe themselves only in their failure messages,
+	// so the matcher's fields are shown instead, such as
+	// matchers.HaveKeyWithValueMatcher{Key:id Value:7}.
+	v := reflect.Indirect(reflect.ValueOf(m.m))
+	return fmt.Sprintf("%s%+v", v.Type(), v.Interface())
+}
+
+type captureMatcher[T any] struct {
+	ptr *T
+}
+
+// Capture returns a Matcher matching arguments of type T, which copies the
+// argument of each call it is used for into *ptr, for assertions on it
+// after the call.
+func Capture[T any](ptr *T) Matcher {
+	return captureMatcher[T]{ptr: ptr}
+}
+
+func (m captureMatcher[T]) Matches(arg any) bool {
+	if arg == nil {
+		// A nil interface argument.
+		return reflect.TypeOf(m.ptr).Elem().Kind() == reflect.Interface
+	}
+	_, ok := arg.(T)
+	return ok
+}
+
+func (m captureMatcher[T]) String() string {
+	return "capture " + reflect.TypeOf(m.ptr).Elem().String()
+}
+
+func (m captureMatcher[T]) capture(arg any) {
+	if arg == nil {
+		var zero T
+		*m.ptr = zero
+		return
+	}
+	*m.ptr = arg.(T)
+}
+
+// capturer is implemented by matchers that capture the arguments of the
+// calls they are used for.
+type capturer interface {
+	capture(arg any)
+}
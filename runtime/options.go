@@ -0,0 +1,83 @@
+package runtime
+
+// Option sets the behaviour of a stub, passed to the constructor of a
+// generated stub.
+type Option func(*Stub)
+
+// Nice makes the stub's methods return zero values when called without a
+// configured result. It is the default.
+func Nice() Option {
+	return func(s *Stub) { s.strict, s.fake = false, nil }
+}
+
+// Strict makes the stub's methods panic with an *ErrNotConfigured when
+// called without a configured result, other than those with a fallback:
+// methods backed by the stub's content or fake clock, and methods of stubs
+// generated with -error-unconfigured, which return the error instead.
+func Strict() Option {
+	return func(s *Stub) { s.strict, s.fake = true, nil }
+}
+
+// Fake makes the stub's methods return pseudo-random values derived from
+// seed when called without a configured result, instead of zero values:
+// non-empty strings, slices and maps, non-zero numbers and structs with
+// their exported fields populated. Errors, interfaces, funcs and channels
+// are left nil, so the calls succeed. The values depend only on the seed,
+// the method and how many times it was called without a configured result,
+// so a test sees the same values on every run.
+func Fake(seed uint64) Option {
+	return func(s *Stub) {
+		s.strict = false
+		s.fake = &fakeSource{seed: seed, calls: make(map[string]uint64)}
+	}
+}
+
+// WithT binds the stub to the test t, which fails when the stub is called
+// more often than an expectation's MaxTimes allows, and when the stub fails
+// Verify once t finishes. Without it, calls over MaxTimes panic.
+func WithT(t TB) Option {
+	return func(s *Stub) {
+		s.t = t
+		t.Cleanup(func() {
+			if err := s.Verify(); err != nil {
+				t.Errorf("%v", err)
+			}
+		})
+	}
+}
+
+// WithRecorder records the stub's calls in r too, along with those of the
+// other stubs sharing it.
+func WithRecorder(r *Recorder) Option {
+	return func(s *Stub) { s.recorder = r }
+}
+
+// WithClock measures the stub's rate limits and timeouts with clock rather
+// than the system clock, so that tests can move time deterministically.
+// Timeouts need a clock with an After method, as a *Clock has.
+func WithClock(clock Nower) Option {
+	return func(s *Stub) { s.clock = clock }
+}
+
+// Init sets the name of the generated stub type embedding s, used in the
+// errors for unconfigured calls, and applies opts to s.
+func (s *Stub) Init(name string, opts ...Option) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.name = name
+	for _, opt := range opts {
+		opt(s)
+	}
+}
+
+// Named sets the name of the generated stub type embedding s, as Init does,
+// unless it is already set, and returns s. Generated stubs call it before
+// using s, so that their zero values are ready to use.
+func (s *Stub) Named(name string) *Stub {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.name == "" {
+		s.name = name
+	}
+	return s
+}
@@ -0,0 +1,126 @@
+package runtime
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"time"
+	"unsafe"
+)
+
+// Nower is a clock, such as a *Clock, giving the time rate limits and
+// timeouts are measured with; see WithClock.
+type Nower interface {
+	Now() time.Time
+}
+
+// rateLimit is a token bucket added with RateLimit.
+type rateLimit struct {
+	// methods are those the limit applies to, or nil for every method.
+	methods []string
+	// n tokens are added every per, up to n, and each call takes one.
+	n      int
+	per    time.Duration
+	err    error
+	tokens float64
+	last   time.Time
+}
+
+// RateLimit limits the calls to the methods of the stub returning an error
+// to n every per, as a token bucket holding up to n calls that refills
+// steadily: calls over the limit are recorded, but skip the configured
+// results and return err, with zero values for the other results. The
+// limit is shared by methods, or the methods named, if any. Calls to
+// methods that don't return an error aren't limited.
+//
+// Time is measured with the clock set with WithClock, or else the system
+// clock.
+func (s *Stub) RateLimit(n int, per time.Duration, err error, methods ...string) {
+	if n <= 0 || per <= 0 {
+		panic(fmt.Sprintf("toe: RateLimit: %d calls every %v must be positive", n, per))
+	}
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.rateLimits = append(s.rateLimits, &rateLimit{
+		methods: methods,
+		n:       n,
+		per:     per,
+		err:     err,
+		tokens:  float64(n),
+		last:    s.now(),
+	})
+}
+
+// now returns the time rate limits are measured at. s.mut must be held.
+func (s *Stub) now() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
+	}
+	return time.Now()
+}
+
+// after returns a channel receiving the time once d passes on the stub's
+// clock: the clock set with WithClock if it has an After method, and
+// otherwise the system clock. s.mut must be held.
+func (s *Stub) after(d time.Duration) <-chan time.Time {
+	if clock, ok := s.clock.(interface {
+		After(time.Duration) <-chan time.Time
+	}); ok {
+		return clock.After(d)
+	}
+	return time.After(d)
+}
+
+// limit takes a token from each rate limit applying to method, returning
+// the first that has none left, in which case no tokens are taken, or nil.
+// s.mut must be held.
+func (s *Stub) limit(method string) *rateLimit {
+	var limits []*rateLimit
+	for _, l := range s.rateLimits {
+		if l.methods == nil || slices.Contains(l.methods, method) {
+			limits = append(limits, l)
+		}
+	}
+	if len(limits) == 0 {
+		return nil
+	}
+
+	now := s.now()
+	for _, l := range limits {
+		if elapsed := now.Sub(l.last); elapsed > 0 {
+			l.tokens = min(float64(l.n), l.tokens+elapsed.Seconds()/l.per.Seconds()*float64(l.n))
+			l.last = now
+		}
+	}
+	for _, l := range limits {
+		if l.tokens < 1 {
+			return l
+		}
+	}
+	for _, l := range limits {
+		l.tokens--
+	}
+	return nil
+}
+
+// errorType is the type of error values.
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// returnsError reports whether R, the Ret struct of a method, ends with an
+// error result.
+func returnsError[R any]() bool {
+	t := reflect.TypeOf((*R)(nil)).Elem()
+	return t.Kind() == reflect.Struct && t.NumField() > 0 && t.Field(t.NumField()-1).Type == errorType
+}
+
+// errorResult returns the results of a method returning err, and zero
+// values for the other results. R must end with an error; see
+// returnsError.
+func errorResult[R any](err error) R {
+	var ret R
+	v := reflect.ValueOf(&ret).Elem()
+	field := v.Field(v.NumField() - 1)
+	// The fields of named results are unexported.
+	reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem().Set(reflect.ValueOf(&err).Elem())
+	return ret
+}
@@ -0,0 +1,50 @@
+package runtime
+
+import (
+	"database/sql/driver"
+	"io"
+	"sync"
+)
+
+// Rows is a driver.Rows returning fixed values, as returned by the
+// ReturnRows configurator of stubs of database/sql/driver interfaces.
+type Rows struct {
+	mut     sync.Mutex
+	columns []string
+	rows    [][]any
+	closed  bool
+}
+
+// NewRows returns rows with columns, whose values are those of each row of
+// rows in turn. Each row must have a value for each column.
+func NewRows(columns []string, rows [][]any) *Rows {
+	return &Rows{columns: columns, rows: rows}
+}
+
+// Columns returns the names of the columns.
+func (r *Rows) Columns() []string {
+	return r.columns
+}
+
+// Close closes the rows, after which Next returns io.EOF.
+func (r *Rows) Close() error {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	r.closed = true
+	return nil
+}
+
+// Next copies the values of the next row into dest, returning io.EOF when
+// there are no more rows.
+func (r *Rows) Next(dest []driver.Value) error {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	if r.closed || len(r.rows) == 0 {
+		return io.EOF
+	}
+	for i, v := range r.rows[0] {
+		dest[i] = v
+	}
+	r.rows = r.rows[1:]
+	return nil
+}
@@ -0,0 +1,403 @@
+// Package runtime is the support library for stubs generated by toe.
+//
+// It holds the bookkeeping shared by every generated stub - locking, the
+// sequence of calls made to the stub and the expectations configured with
+// its OnX methods - so that generated code stays small, and fixes to this
+// package reach existing stubs without regenerating them.
+//
+// A Stub may be configured while other goroutines call it: its methods and
+// those of its expectations take its lock, and each call sees the
+// configuration as it was at some instant.
+//
+// Generated stubs are thin typed wrappers over the generic helpers here:
+// each method records its arguments, held in a <Method>Params struct, in a
+// Calls list and returns a <Method>Ret struct from the matching
+// Expectation.
+package runtime
+
+import (
+	"context"
+	"math/rand/v2"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Call records a call made to a stub.
+type Call struct {
+	Method string
+	Args   []any
+	// ContextValues are the values found in the call's context argument
+	// for the keys set with CaptureContextValues. Keys without a value are
+	// left out; it is nil when no keys are set or the call has no context.
+	ContextValues map[any]any
+}
+
+// Stub holds the state of a generated stub. Generated stubs embed a Stub as
+// an unexported field; its zero value is ready to use.
+type Stub struct {
+	mut sync.Mutex
+	// name is the name of the generated stub type, set by Init.
+	name string
+	// strict makes unconfigured calls panic; see Strict.
+	strict bool
+	// fake generates the results of unconfigured calls; see Fake.
+	fake *fakeSource
+	// t is the test failed by unexpected calls, set with WithT.
+	t            TB
+	calls        []Call
+	expectations map[string][]expectation
+	// verifications are the verifications added with Expect, by method.
+	verifications map[string][]*Verification
+	contextKeys   []any
+	// propagateContextErrors makes calls whose context is done short-circuit.
+	propagateContextErrors bool
+	// recorder records the calls in a timeline shared with other stubs;
+	// see WithRecorder.
+	recorder *Recorder
+	// rateLimits are the limits added with RateLimit, measured with clock,
+	// or the system clock if it is nil.
+	rateLimits []*rateLimit
+	clock      Nower
+	// sequence is the allowed order of calls; see AllowSequence.
+	sequence *sequence
+	// notify holds the functions called with the Params of each call, by
+	// method.
+	notify map[string]func(params any)
+}
+
+// CallChannelSize is the capacity of the channels given to NotifyCalls by
+// generated stubs.
+const CallChannelSize = 64
+
+// NotifyCalls makes the stub send the Params of each later call to method
+// on ch, without blocking: calls are dropped while ch is full.
+func NotifyCalls[P any](s *Stub, method string, ch chan<- P) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.notify == nil {
+		s.notify = make(map[string]func(any))
+	}
+	s.notify[method] = func(params any) {
+		select {
+		case ch <- params.(P):
+		default:
+		}
+	}
+}
+
+// CaptureContextValues makes the stub record the values of keys in the
+// context argument of each later call, in the call's ContextValues.
+func (s *Stub) CaptureContextValues(keys ...any) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.contextKeys = append([]any(nil), keys...)
+}
+
+// PropagateContextErrors sets whether calls whose context argument is done
+// short-circuit: they are recorded, but skip the configured results and
+// return zero values, along with the context's error from methods returning
+// an error.
+func (s *Stub) PropagateContextErrors(on bool) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.propagateContextErrors = on
+}
+
+// ContextErr returns ctx's error if the stub propagates context errors, and
+// nil otherwise.
+func (s *Stub) ContextErr(ctx context.Context) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if !s.propagateContextErrors || ctx == nil {
+		return nil
+	}
+	return ctx.Err()
+}
+
+// expectation is implemented by every instantiation of Expectation.
+type expectation interface {
+	matches(args []any) bool
+	catchAll() bool
+	// clone returns a copy of the expectation for s, with its own results.
+	clone(s *Stub) expectation
+}
+
+// Invoke records a call to method with params, appending them to calls, and
+// returns the next result of the expectation matching the call. It returns
+// the zero R if there is no such expectation or it has no results, or if
+// the call short-circuits; see PropagateContextErrors. In strict mode, it
+// panics with an *ErrNotConfigured instead, unless the call short-circuits,
+// and in fake mode it returns a generated R; see Fake.
+func Invoke[R any, P any](s *Stub, method string, calls *Calls[P], params P) R {
+	return InvokeDefault[R](s, method, calls, params, nil)
+}
+
+// InvokeDefault is Invoke for methods with results whose defaults aren't
+// their zero values: setDefaults sets them in the results of the calls
+// that would otherwise return the zero R, other than those that
+// short-circuit. setDefaults may be nil.
+func InvokeDefault[R any, P any](s *Stub, method string, calls *Calls[P], params P, setDefaults func(*R)) R {
+	ret, ok, shortCircuited := invoke[R](s, method, calls, params)
+	if !ok && !shortCircuited {
+		if s.isStrict() {
+			panic(NotConfigured(s.name, method, params))
+		}
+		if fake, ok := fakeResult[R](s, method); ok {
+			return fake
+		}
+		if setDefaults != nil {
+			setDefaults(&ret)
+		}
+	}
+	return ret
+}
+
+// InvokeConfigured is Invoke, also reporting whether the result was
+// configured: false when it is the zero R because there is no matching
+// expectation or it has no results, or because the call short-circuits. It
+// is used for methods with a fallback for unconfigured calls, and doesn't
+// panic in strict mode.
+func InvokeConfigured[R any, P any](s *Stub, method string, calls *Calls[P], params P) (R, bool) {
+	ret, ok, _ := invoke[R](s, method, calls, params)
+	return ret, ok
+}
+
+// invoke implements Invoke and InvokeConfigured, also reporting whether the
+// call short-circuited.
+func invoke[R any, P any](s *Stub, method string, calls *Calls[P], params P) (ret R, ok bool, shortCircuited bool) {
+	args := argsOf(params)
+	match := s.matchExpectation(method, args)
+	verified := s.matchVerifications(method, args)
+
+	s.mut.Lock()
+	*calls = append(*calls, params)
+	seqErr := s.record(method, params, args, verified)
+	t := s.t
+
+	if ctx := contextOf(args); s.propagateContextErrors && ctx != nil && ctx.Err() != nil {
+		s.mut.Unlock()
+		if seqErr != nil {
+			fail(t, seqErr)
+		}
+		return ret, false, true
+	}
+	if len(s.rateLimits) > 0 && returnsError[R]() {
+		if l := s.limit(method); l != nil {
+			s.mut.Unlock()
+			if seqErr != nil {
+				fail(t, seqErr)
+			}
+			return errorResult[R](l.err), true, false
+		}
+	}
+	var err error
+	var fn func(P) R
+	var release func()
+	var timeout, latency <-chan time.Time
+	if e, found := match.(*Expectation[P, R]); found {
+		if !e.acquire() {
+			// Calls turned away as busy never reach the configuration, so
+			// they don't capture or set arguments, or count towards
+			// MaxTimes.
+			ret, ok = e.busy, true
+		} else {
+			e.capture(args)
+			e.set(params)
+			release = e.release
+			switch {
+			case returnsError[R]() && e.fails():
+				ret, ok = errorResult[R](e.failErr), true
+			case e.hasTimeout && returnsError[R]():
+				timeout, ok = s.after(e.timeout), true
+			default:
+				ret, fn, ok = e.next()
+			}
+			if e.latency != nil && timeout == nil {
+				latency = s.after(e.latency(rand.New(e.latencyRand)))
+			}
+			err = e.count(s.name, method, args)
+		}
+	}
+	s.mut.Unlock()
+
+	if release != nil {
+		defer release()
+	}
+	if seqErr != nil {
+		fail(t, seqErr)
+	}
+	if err != nil {
+		fail(t, err)
+	}
+	if timeout != nil {
+		err := sleep(contextOf(args), timeout)
+		if err == nil {
+			err = context.DeadlineExceeded
+		}
+		return errorResult[R](err), true, false
+	}
+	if latency != nil {
+		if err := sleep(contextOf(args), latency); err != nil && returnsError[R]() {
+			return errorResult[R](err), true, false
+		}
+	}
+	if fn != nil {
+		ret = fn(params)
+	}
+	return ret, ok, false
+}
+
+// fail fails the test t with err, or panics with err if t is nil.
+func fail(t TB, err error) {
+	if t == nil {
+		panic(err)
+	}
+	t.Errorf("%v", err)
+}
+
+// isStrict reports whether the stub is in strict mode.
+func (s *Stub) isStrict() bool {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.strict
+}
+
+// matchExpectation returns the expectation for a call to method with args,
+// or nil if there is none. The matchers run against the expectations as
+// they are when it is called, without s.mut held, so that they may call the
+// stub.
+func (s *Stub) matchExpectation(method string, args []any) expectation {
+	s.mut.Lock()
+	exps := append([]expectation(nil), s.expectations[method]...)
+	s.mut.Unlock()
+
+	for i := len(exps) - 1; i >= 0; i-- {
+		if exps[i].matches(args) {
+			return exps[i]
+		}
+	}
+	return nil
+}
+
+// On adds an expectation for calls to method whose arguments match args.
+// Each of args is either a Matcher or a value that the argument must equal.
+// When several expectations with args match a call, the most recently added
+// is used.
+//
+// With no args, the expectation matches every call to method that no other
+// expectation matches, and replaces any previous such expectation.
+func On[P any, R any](s *Stub, method string, args ...any) *Expectation[P, R] {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	useMethod(s.name, method, configured)
+	e := &Expectation[P, R]{stub: s, maxTimes: -1}
+	for _, arg := range args {
+		e.matchers = append(e.matchers, matcherFor(arg))
+	}
+
+	if s.expectations == nil {
+		s.expectations = make(map[string][]expectation)
+	}
+
+	// The expectation matching every call is kept first, so that it is
+	// only used when no expectation with matchers applies.
+	exps := s.expectations[method]
+	switch {
+	case !e.catchAll():
+		exps = append(exps, e)
+	case len(exps) > 0 && exps[0].catchAll():
+		exps[0] = e
+	default:
+		exps = append([]expectation{e}, exps...)
+	}
+	s.expectations[method] = exps
+	return e
+}
+
+// Calls returns every call made to the stub, in the order they were made.
+func (s *Stub) Calls() []Call {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return append([]Call(nil), s.calls...)
+}
+
+// argsOf returns the fields of a Params struct, which are the arguments of
+// the call it records.
+func argsOf(params any) []any {
+	v := reflect.ValueOf(params)
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	args := make([]any, 0, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		if v.Field(i).CanInterface() {
+			args = append(args, v.Field(i).Interface())
+		}
+	}
+	return args
+}
+
+// Record records a call to method with params, appending them to calls,
+// without looking for an expectation. It is used by generated code that
+// delegates calls rather than stubbing them.
+func Record[P any](s *Stub, method string, calls *Calls[P], params P) {
+	args := argsOf(params)
+	verified := s.matchVerifications(method, args)
+
+	s.mut.Lock()
+	*calls = append(*calls, params)
+	err := s.record(method, params, args, verified)
+	t := s.t
+	s.mut.Unlock()
+
+	if err != nil {
+		fail(t, err)
+	}
+}
+
+// record appends a call to method with params, whose fields are args, to
+// the sequence of calls and to the verifications it matched, capturing the
+// values of the context keys from its first context argument, and notifies
+// the method's channel of it. It returns an error if the call is out of the
+// allowed sequence. s.mut must be held.
+func (s *Stub) record(method string, params any, args []any, verified []*Verification) error {
+	call := Call{Method: method, Args: args}
+	if ctx := contextOf(args); ctx != nil {
+		for _, key := range s.contextKeys {
+			if v := ctx.Value(key); v != nil {
+				if call.ContextValues == nil {
+					call.ContextValues = make(map[any]any)
+				}
+				call.ContextValues[key] = v
+			}
+		}
+	}
+	s.calls = append(s.calls, call)
+	useMethod(s.name, method, called)
+	if s.recorder != nil {
+		s.recorder.record(s.name, method, args)
+	}
+	for _, v := range verified {
+		v.calls = append(v.calls, args)
+	}
+	if notify := s.notify[method]; notify != nil {
+		notify(params)
+	}
+	if s.sequence != nil {
+		return s.sequence.call(s.name, method, args)
+	}
+	return nil
+}
+
+// contextOf returns the first of args that is a context.Context, or nil if
+// there is none.
+func contextOf(args []any) context.Context {
+	for _, arg := range args {
+		if ctx, ok := arg.(context.Context); ok {
+			return ctx
+		}
+	}
+	return nil
+}
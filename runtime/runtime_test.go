@@ -0,0 +1,1117 @@
+package runtime_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/phildrip/toe/runtime"
+)
+
+type getParams struct {
+	ID int
+}
+
+type getRet struct {
+	R0 string
+}
+
+func TestInvoke(t *testing.T) {
+	var stub runtime.Stub
+	var calls runtime.Calls[getParams]
+	get := func(id int) string {
+		return runtime.Invoke[getRet](&stub, "Get", &calls, getParams{ID: id}).R0
+	}
+
+	if ret := get(1); ret != "" {
+		t.Errorf("expected %q, got %q", "", ret)
+	}
+
+	runtime.On[getParams, getRet](&stub, "Get").Return(getRet{"any"})
+	runtime.On[getParams, getRet](&stub, "Get", 2).Return(getRet{"two"})
+	runtime.On[getParams, getRet](&stub, "Get", runtime.Eq(3)).Return(getRet{"three"})
+
+	for arg, expected := range map[int]string{1: "any", 2: "two", 3: "three"} {
+		if ret := get(arg); ret != expected {
+			t.Errorf("expected %v, got %v", expected, ret)
+		}
+	}
+
+	runtime.On[getParams, getRet](&stub, "Get").Return(getRet{"replaced"})
+	if ret := get(1); ret != "replaced" {
+		t.Errorf("expected %v, got %v", "replaced", ret)
+	}
+	if ret := get(2); ret != "two" {
+		t.Errorf("expected %v, got %v", "two", ret)
+	}
+
+	if calls.Len() != 6 || calls.Last().ID != 2 {
+		t.Errorf("expected 6 calls ending with ID 2, got %v", calls)
+	}
+}
+
+func TestInvokeDefault(t *testing.T) {
+	var stub runtime.Stub
+	var calls runtime.Calls[getParams]
+	get := func(id int) string {
+		return runtime.InvokeDefault[getRet](&stub, "Get", &calls, getParams{ID: id}, func(ret *getRet) {
+			ret.R0 = "none"
+		}).R0
+	}
+
+	if ret := get(1); ret != "none" {
+		t.Errorf("expected %q, got %q", "none", ret)
+	}
+	runtime.On[getParams, getRet](&stub, "Get", 2).Return(getRet{""})
+	if ret := get(2); ret != "" {
+		t.Errorf("expected %q, got %q", "", ret)
+	}
+}
+
+func TestInvokeConfigured(t *testing.T) {
+	var stub runtime.Stub
+	var calls runtime.Calls[getParams]
+
+	if _, ok := runtime.InvokeConfigured[getRet](&stub, "Get", &calls, getParams{ID: 1}); ok {
+		t.Errorf("expected %v, got %v", false, ok)
+	}
+	runtime.On[getParams, getRet](&stub, "Get", 2)
+	if _, ok := runtime.InvokeConfigured[getRet](&stub, "Get", &calls, getParams{ID: 2}); ok {
+		t.Errorf("expected %v, got %v", false, ok)
+	}
+	runtime.On[getParams, getRet](&stub, "Get").Return(getRet{})
+	if _, ok := runtime.InvokeConfigured[getRet](&stub, "Get", &calls, getParams{ID: 1}); !ok {
+		t.Errorf("expected %v, got %v", true, ok)
+	}
+	if calls.Len() != 3 {
+		t.Errorf("expected %v, got %v", 3, calls.Len())
+	}
+}
+
+func TestStrict(t *testing.T) {
+	var stub runtime.Stub
+	var calls runtime.Calls[getParams]
+	stub.Init("StubGetter", runtime.Strict())
+	runtime.On[getParams, getRet](&stub, "Get", 1).Return(getRet{R0: "one"})
+
+	if ret := runtime.Invoke[getRet](&stub, "Get", &calls, getParams{ID: 1}); ret.R0 != "one" {
+		t.Errorf("expected %v, got %v", "one", ret.R0)
+	}
+	func() {
+		defer func() {
+			want := "toe: StubGetter.Get(2) not configured"
+			if err, ok := recover().(*runtime.ErrNotConfigured); !ok || err.Error() != want {
+				t.Errorf("expected %v, got %v", want, err)
+			}
+		}()
+		runtime.Invoke[getRet](&stub, "Get", &calls, getParams{ID: 2})
+	}()
+
+	// Methods with a fallback don't panic.
+	if _, ok := runtime.InvokeConfigured[getRet](&stub, "Get", &calls, getParams{ID: 3}); ok {
+		t.Errorf("expected %v, got %v", false, ok)
+	}
+
+	stub.Init("StubGetter", runtime.Nice())
+	if ret := runtime.Invoke[getRet](&stub, "Get", &calls, getParams{ID: 2}); ret.R0 != "" {
+		t.Errorf("expected %v, got %v", "", ret.R0)
+	}
+}
+
+type fakeRet struct {
+	R0 struct {
+		Name  string
+		Tags  []string
+		Owner *struct{ ID int }
+	}
+	R1 error
+}
+
+func TestFake(t *testing.T) {
+	results := func(seed uint64) []fakeRet {
+		var stub runtime.Stub
+		var calls runtime.Calls[getParams]
+		stub.Init("StubGetter", runtime.Fake(seed))
+		runtime.On[getParams, fakeRet](&stub, "Get", 1).Return(fakeRet{})
+		return []fakeRet{
+			runtime.Invoke[fakeRet](&stub, "Get", &calls, getParams{ID: 1}),
+			runtime.Invoke[fakeRet](&stub, "Get", &calls, getParams{ID: 2}),
+			runtime.Invoke[fakeRet](&stub, "Get", &calls, getParams{ID: 3}),
+		}
+	}
+
+	rets := results(1)
+	if rets[0].R0.Name != "" {
+		t.Errorf("expected %v, got %v", "", rets[0].R0.Name)
+	}
+	for _, ret := range rets[1:] {
+		if ret.R0.Name == "" || len(ret.R0.Tags) == 0 || ret.R0.Owner == nil || ret.R0.Owner.ID == 0 {
+			t.Errorf("expected populated result, got %+v", ret.R0)
+		}
+		if ret.R1 != nil {
+			t.Errorf("expected %v, got %v", nil, ret.R1)
+		}
+	}
+	if rets[1].R0.Name == rets[2].R0.Name {
+		t.Errorf("expected different names, got %v twice", rets[1].R0.Name)
+	}
+	if again := results(1); !reflect.DeepEqual(again, rets) {
+		t.Errorf("expected %+v, got %+v", rets, again)
+	}
+	if other := results(2); reflect.DeepEqual(other, rets) {
+		t.Errorf("expected different results for another seed, got %+v", other)
+	}
+}
+
+// fakeTB records the failures of a test.
+type fakeTB struct {
+	errors []string
+}
+
+func (f *fakeTB) Cleanup(func()) {}
+
+func (f *fakeTB) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func TestMaxTimes(t *testing.T) {
+	var tb fakeTB
+	var stub runtime.Stub
+	var calls runtime.Calls[getParams]
+	stub.Init("StubGetter", runtime.WithT(&tb))
+	runtime.On[getParams, getRet](&stub, "Get").MaxTimes(1)
+
+	runtime.Invoke[getRet](&stub, "Get", &calls, getParams{ID: 1})
+	if len(tb.errors) != 0 {
+		t.Errorf("expected %v, got %v", 0, tb.errors)
+	}
+	runtime.Invoke[getRet](&stub, "Get", &calls, getParams{ID: 2})
+	want := "toe: StubGetter.Get(2) called 2 times, more than the maximum of 1"
+	if len(tb.errors) != 1 || tb.errors[0] != want {
+		t.Errorf("expected %v, got %v", want, tb.errors)
+	}
+}
+
+func TestNever(t *testing.T) {
+	var stub runtime.Stub
+	var calls runtime.Calls[getParams]
+	stub.Init("StubGetter")
+	runtime.Expect(&stub, "Get", 2).Never()
+
+	runtime.Invoke[getRet](&stub, "Get", &calls, getParams{ID: 1})
+	if err := stub.Verify(); err != nil {
+		t.Errorf("expected %v, got %v", nil, err)
+	}
+	runtime.Invoke[getRet](&stub, "Get", &calls, getParams{ID: 2})
+	want := "toe: StubGetter.Get expected never to be called, but was called 1 times:\n\tStubGetter.Get(2)"
+	if err := stub.Verify(); err == nil || err.Error() != want {
+		t.Errorf("expected %v, got %v", want, err)
+	}
+}
+
+func TestAllowSequence(t *testing.T) {
+	var tb fakeTB
+	var stub runtime.Stub
+	var opens, writes, closes, stats runtime.Calls[getParams]
+	stub.Init("StubFile", runtime.WithT(&tb))
+	stub.AllowSequence([]string{"Open", "Write", "Close", "Stat"}, "Open", "Write*", "Close")
+
+	runtime.Invoke[getRet](&stub, "Open", &opens, getParams{ID: 1})
+	runtime.Invoke[getRet](&stub, "Stat", &stats, getParams{ID: 1})
+	runtime.Invoke[getRet](&stub, "Write", &writes, getParams{ID: 1})
+	runtime.Invoke[getRet](&stub, "Write", &writes, getParams{ID: 2})
+	want := "toe: StubFile's calls stopped before the end of the allowed sequence Open, Write*, Close, after Open, Write, Write"
+	if err := stub.Verify(); err == nil || err.Error() != want {
+		t.Errorf("expected %v, got %v", want, err)
+	}
+	runtime.Invoke[getRet](&stub, "Close", &closes, getParams{ID: 1})
+	if len(tb.errors) != 0 {
+		t.Errorf("expected %v, got %v", nil, tb.errors)
+	}
+	if err := stub.Verify(); err != nil {
+		t.Errorf("expected %v, got %v", nil, err)
+	}
+
+	runtime.Invoke[getRet](&stub, "Write", &writes, getParams{ID: 3})
+	want = "toe: StubFile.Write(3) called out of the allowed sequence Open, Write*, Close, after Open, Write, Write, Close"
+	if len(tb.errors) != 1 || tb.errors[0] != want {
+		t.Errorf("expected %v, got %v", want, tb.errors)
+	}
+
+	// Write+ needs a Write before Close.
+	tb.errors = nil
+	stub.AllowSequence([]string{"Open", "Write", "Close"}, "Open", "Write+", "Close")
+	runtime.Invoke[getRet](&stub, "Open", &opens, getParams{ID: 2})
+	runtime.Invoke[getRet](&stub, "Close", &closes, getParams{ID: 2})
+	if len(tb.errors) != 1 {
+		t.Errorf("expected %v, got %v", 1, len(tb.errors))
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("expected a panic for an unknown method")
+			}
+		}()
+		stub.AllowSequence([]string{"Open"}, "Open", "Seek+")
+	}()
+}
+
+func TestDumpInteractions(t *testing.T) {
+	var recorder runtime.Recorder
+	var getter, putter runtime.Stub
+	var gets, puts runtime.Calls[getParams]
+	getter.Init("StubGetter", runtime.WithRecorder(&recorder))
+	putter.Init("StubPutter", runtime.WithRecorder(&recorder))
+
+	runtime.Invoke[getRet](&getter, "Get", &gets, getParams{ID: 1})
+	runtime.Invoke[getRet](&putter, "Put", &puts, getParams{ID: 2})
+	runtime.Invoke[getRet](&getter, "Get", &gets, getParams{ID: 3})
+
+	var dot strings.Builder
+	if err := getter.DumpInteractions(&dot, runtime.FormatDOT); err != nil {
+		t.Fatal(err)
+	}
+	want := `digraph interactions {
+	node [shape=box];
+	subgraph cluster_0 {
+		label="StubGetter";
+		call1 [label="1. Get(1)"];
+		call3 [label="3. Get(3)"];
+	}
+	subgraph cluster_1 {
+		label="StubPutter";
+		call2 [label="2. Put(2)"];
+	}
+	call1 -> call2;
+	call2 -> call3;
+}
+`
+	if dot.String() != want {
+		t.Errorf("expected %v, got %v", want, dot.String())
+	}
+
+	// Without a recorder, only the stub's own calls are written.
+	var stub runtime.Stub
+	var calls runtime.Calls[getParams]
+	stub.Init("StubGetter")
+	runtime.Invoke[getRet](&stub, "Get", &calls, getParams{ID: 1})
+	var js strings.Builder
+	if err := stub.DumpInteractions(&js, runtime.FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	want = `[
+  {
+    "seq": 1,
+    "stub": "StubGetter",
+    "method": "Get",
+    "args": [
+      "1"
+    ]
+  }
+]
+`
+	if js.String() != want {
+		t.Errorf("expected %v, got %v", want, js.String())
+	}
+
+	if err := stub.DumpInteractions(io.Discard, "svg"); err == nil {
+		t.Errorf("expected an error for an unknown format")
+	}
+}
+
+func TestUsage(t *testing.T) {
+	runtime.CollectUsage()
+	var stub runtime.Stub
+	var calls runtime.Calls[getParams]
+	stub.Init("StubUsage")
+	runtime.On[getParams, getRet](&stub, "Get").Return(getRet{R0: "one"})
+	runtime.Expect(&stub, "Put")
+	runtime.Invoke[getRet](&stub, "Get", &calls, getParams{ID: 1})
+	runtime.Invoke[getRet](&stub, "Get", &calls, getParams{ID: 2})
+	runtime.AssertCalledWith(t, &stub, "Get", &calls, getParams{ID: 2})
+
+	var b strings.Builder
+	if err := runtime.WriteUsage(&b); err != nil {
+		t.Fatal(err)
+	}
+	var report []runtime.MethodUsage
+	if err := json.Unmarshal([]byte(b.String()), &report); err != nil {
+		t.Fatal(err)
+	}
+	var got []runtime.MethodUsage
+	for _, u := range report {
+		if u.Stub == "StubUsage" {
+			got = append(got, u)
+		}
+	}
+	want := []runtime.MethodUsage{
+		{Stub: "StubUsage", Method: "Get", Configured: 1, Called: 2, Asserted: 1},
+		{Stub: "StubUsage", Method: "Put", Asserted: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestCapture(t *testing.T) {
+	var stub runtime.Stub
+	var calls runtime.Calls[getParams]
+	var got int
+	runtime.On[getParams, getRet](&stub, "Get", runtime.Capture(&got)).Return(getRet{R0: "captured"})
+
+	ret := runtime.Invoke[getRet](&stub, "Get", &calls, getParams{ID: 7})
+	if got != 7 || ret.R0 != "captured" {
+		t.Errorf("expected %v, got %v", "7 captured", []any{got, ret.R0})
+	}
+	if m := runtime.Capture(new(string)); m.Matches(1) {
+		t.Errorf("expected %v to not match %v", m, 1)
+	}
+}
+
+func TestMatchers(t *testing.T) {
+	for _, tt := range []struct {
+		matcher runtime.Matcher
+		arg     any
+		matches bool
+	}{
+		{runtime.Regex("^a+$"), "aaa", true},
+		{runtime.Regex("^a+$"), []byte("ab"), false},
+		{runtime.Regex("^1s$"), time.Second, true},
+		{runtime.Regex("1"), 1, false},
+		{runtime.Len(2), []int{1, 2}, true},
+		{runtime.Len(2), map[string]int{"a": 1}, false},
+		{runtime.Len(3), "abc", true},
+		{runtime.Len(0), 0, false},
+		{runtime.Contains(2), []int{1, 2}, true},
+		{runtime.Contains(runtime.Regex("^b")), []string{"a", "bc"}, true},
+		{runtime.Contains(3), [2]int{1, 2}, false},
+		{runtime.Contains("ell"), "hello", true},
+		{runtime.MapKeys("a", "b"), map[string]int{"b": 1, "a": 2}, true},
+		{runtime.MapKeys("a"), map[string]int{"a": 1, "b": 2}, false},
+		{runtime.MapKeys(runtime.Any(), runtime.Any()), map[int]bool{1: true, 2: false}, true},
+		{runtime.MapKeys("a", "a"), map[string]int{"a": 1, "b": 2}, false},
+	} {
+		if matches := tt.matcher.Matches(tt.arg); matches != tt.matches {
+			t.Errorf("expected %v matching %v to be %v, got %v", tt.matcher, tt.arg, tt.matches, matches)
+		}
+	}
+}
+
+// funcMatcher is a matcher calling match.
+type funcMatcher func(arg any) bool
+
+func (m funcMatcher) Matches(arg any) bool {
+	return m(arg)
+}
+
+func (funcMatcher) String() string {
+	return "func"
+}
+
+func TestMatchersCallStub(t *testing.T) {
+	var stub runtime.Stub
+	var calls runtime.Calls[getParams]
+	get := func(id int) string {
+		return runtime.Invoke[getRet](&stub, "Get", &calls, getParams{ID: id}).R0
+	}
+
+	// The matchers call the stub, as matchers looking up other state
+	// might.
+	runtime.On[getParams, getRet](&stub, "Get", funcMatcher(func(arg any) bool {
+		return arg.(int) > 1 && get(arg.(int)-1) == "big"
+	})).Return(getRet{"big"})
+	runtime.On[getParams, getRet](&stub, "Get", 1).Return(getRet{"big"})
+	v := runtime.Expect(&stub, "Get", funcMatcher(func(arg any) bool {
+		return len(stub.Calls()) >= 0
+	}))
+	v.Never()
+
+	done := make(chan string)
+	go func() { done <- get(2) }()
+	select {
+	case ret := <-done:
+		if ret != "big" {
+			t.Errorf("expected %q, got %q", "big", ret)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the call to return, but it deadlocked")
+	}
+	if n := len(stub.Calls()); n != 2 {
+		t.Errorf("expected %v, got %v", 2, n)
+	}
+	if err := stub.Verify(); err == nil {
+		t.Errorf("expected an error for the calls matching the verification")
+	}
+}
+
+// beEvenMatcher is a matcher implementing gomega's types.GomegaMatcher.
+type beEvenMatcher struct{}
+
+func (beEvenMatcher) Match(actual any) (bool, error) {
+	n, ok := actual.(int)
+	if !ok {
+		return false, fmt.Errorf("expected an int, got %T", actual)
+	}
+	return n%2 == 0, nil
+}
+
+func (beEvenMatcher) FailureMessage(actual any) string {
+	return fmt.Sprintf("expected %v to be even", actual)
+}
+
+func (beEvenMatcher) NegatedFailureMessage(actual any) string {
+	return fmt.Sprintf("expected %v not to be even", actual)
+}
+
+func TestGomega(t *testing.T) {
+	var stub runtime.Stub
+	var calls runtime.Calls[getParams]
+	runtime.On[getParams, getRet](&stub, "Get", runtime.Gomega(beEvenMatcher{})).Return(getRet{R0: "even"})
+
+	for id, expected := range map[int]string{2: "even", 3: ""} {
+		if ret := runtime.Invoke[getRet](&stub, "Get", &calls, getParams{ID: id}); ret.R0 != expected {
+			t.Errorf("expected %v, got %v", expected, ret.R0)
+		}
+	}
+	if m := runtime.Gomega(beEvenMatcher{}); m.Matches("2") || m.String() != "runtime_test.beEvenMatcher{}" {
+		t.Errorf("expected %v not to match %v", m, "2")
+	}
+}
+
+type loadParams struct {
+	ID  int
+	Out *string
+}
+
+func TestSetArg(t *testing.T) {
+	var stub runtime.Stub
+	var calls runtime.Calls[loadParams]
+	runtime.On[loadParams, getRet](&stub, "Load").SetArg(1, "loaded")
+
+	var out string
+	runtime.Invoke[getRet](&stub, "Load", &calls, loadParams{ID: 1, Out: &out})
+	if out != "loaded" {
+		t.Errorf("expected %v, got %v", "loaded", out)
+	}
+	runtime.Invoke[getRet](&stub, "Load", &calls, loadParams{ID: 2})
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected a panic setting a non-pointer argument")
+		}
+	}()
+	runtime.On[loadParams, getRet](&stub, "Load").SetArg(0, 1)
+}
+
+func TestAssertCalledWith(t *testing.T) {
+	var tb fakeTB
+	var stub runtime.Stub
+	var calls runtime.Calls[loadParams]
+	stub.Init("StubLoader")
+	runtime.Invoke[getRet](&stub, "Load", &calls, loadParams{ID: 1})
+	runtime.Invoke[getRet](&stub, "Load", &calls, loadParams{ID: 2})
+
+	runtime.AssertCalledWith(&tb, &stub, "Load", &calls, loadParams{ID: 2})
+	if len(tb.errors) != 0 {
+		t.Errorf("expected %v, got %v", 0, tb.errors)
+	}
+	runtime.AssertCalledWith(&tb, &stub, "Load", &calls, loadParams{ID: 3})
+	if len(tb.errors) != 1 || !strings.Contains(tb.errors[0], "StubLoader.Load(3, <nil>) not called") ||
+		!strings.Contains(tb.errors[0], "ID:") {
+		t.Errorf("expected a diff of ID, got %v", tb.errors)
+	}
+}
+
+func TestNotConfigured(t *testing.T) {
+	err := runtime.NotConfigured("StubThinger", "ThingWithParams", struct {
+		Arg1 int
+		Arg2 string
+	}{1, "a"})
+
+	want := `toe: StubThinger.ThingWithParams(1, "a") not configured`
+	if err.Error() != want {
+		t.Errorf("expected %v, got %v", want, err)
+	}
+	var notConfigured *runtime.ErrNotConfigured
+	if !errors.As(err, &notConfigured) || notConfigured.Method != "ThingWithParams" {
+		t.Errorf("expected %v, got %v", "an ErrNotConfigured for ThingWithParams", err)
+	}
+}
+
+func TestReturnFunc(t *testing.T) {
+	var stub runtime.Stub
+	var calls runtime.Calls[getParams]
+	get := func(id int) string {
+		return runtime.Invoke[getRet](&stub, "Get", &calls, getParams{ID: id}).R0
+	}
+
+	exp := runtime.On[getParams, getRet](&stub, "Get")
+	exp.ReturnOnce(getRet{"once"})
+	exp.ReturnFunc(func(p getParams) getRet {
+		return getRet{fmt.Sprint(p.ID)}
+	})
+	for _, expected := range []string{"once", "2", "3"} {
+		if ret := get(len(calls) + 1); ret != expected {
+			t.Errorf("expected %v, got %v", expected, ret)
+		}
+	}
+
+	exp.Return(getRet{"fixed"})
+	if ret := get(4); ret != "fixed" {
+		t.Errorf("expected %v, got %v", "fixed", ret)
+	}
+}
+
+func TestMaxConcurrent(t *testing.T) {
+	var stub runtime.Stub
+	var calls runtime.Calls[getParams]
+	started, unblock := make(chan struct{}), make(chan struct{})
+	exp := runtime.On[getParams, getRet](&stub, "Get")
+	exp.ReturnFunc(func(p getParams) getRet {
+		started <- struct{}{}
+		<-unblock
+		return getRet{"done"}
+	})
+	exp.MaxConcurrent(2, getRet{"busy"})
+
+	results := make(chan string, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			results <- runtime.Invoke[getRet](&stub, "Get", &calls, getParams{ID: 1}).R0
+		}()
+		<-started
+	}
+	if ret := runtime.Invoke[getRet](&stub, "Get", &calls, getParams{ID: 2}).R0; ret != "busy" {
+		t.Errorf("expected %v, got %v", "busy", ret)
+	}
+	close(unblock)
+	for i := 0; i < 2; i++ {
+		if ret := <-results; ret != "done" {
+			t.Errorf("expected %v, got %v", "done", ret)
+		}
+	}
+	go func() { <-started }()
+	if ret := runtime.Invoke[getRet](&stub, "Get", &calls, getParams{ID: 3}).R0; ret != "done" {
+		t.Errorf("expected %v, got %v", "done", ret)
+	}
+}
+
+func TestMaxConcurrentBusyCalls(t *testing.T) {
+	var tb fakeTB
+	var stub runtime.Stub
+	var calls runtime.Calls[getParams]
+	stub.Init("StubGetter", runtime.WithT(&tb))
+	started, unblock := make(chan struct{}), make(chan struct{})
+	var captured int
+	exp := runtime.On[getParams, getRet](&stub, "Get", runtime.Capture(&captured))
+	exp.ReturnFunc(func(p getParams) getRet {
+		started <- struct{}{}
+		<-unblock
+		return getRet{"done"}
+	})
+	exp.MaxConcurrent(1, getRet{"busy"})
+	exp.MaxTimes(2)
+
+	done := make(chan string)
+	go func() {
+		done <- runtime.Invoke[getRet](&stub, "Get", &calls, getParams{ID: 1}).R0
+	}()
+	<-started
+	if ret := runtime.Invoke[getRet](&stub, "Get", &calls, getParams{ID: 2}).R0; ret != "busy" {
+		t.Errorf("expected %v, got %v", "busy", ret)
+	}
+	if captured != 1 {
+		t.Errorf("expected %v, got %v", 1, captured)
+	}
+	close(unblock)
+	<-done
+
+	// The busy call doesn't count towards MaxTimes, so the third call is
+	// the second one allowed.
+	go func() { <-started }()
+	if ret := runtime.Invoke[getRet](&stub, "Get", &calls, getParams{ID: 3}).R0; ret != "done" {
+		t.Errorf("expected %v, got %v", "done", ret)
+	}
+	if len(tb.errors) != 0 {
+		t.Errorf("expected %v, got %v", 0, tb.errors)
+	}
+	if captured != 3 {
+		t.Errorf("expected %v, got %v", 3, captured)
+	}
+}
+
+type putRet struct {
+	err error
+}
+
+func TestRateLimit(t *testing.T) {
+	var clock runtime.Clock
+	var stub runtime.Stub
+	var gets, puts runtime.Calls[getParams]
+	errLimited := errors.New("rate limited")
+	stub.Init("StubStore", runtime.WithClock(&clock))
+	stub.RateLimit(2, time.Second, errLimited, "Put")
+	put := func() error {
+		return runtime.Invoke[putRet](&stub, "Put", &puts, getParams{ID: 1}).err
+	}
+
+	for i, want := range []error{nil, nil, errLimited} {
+		if err := put(); err != want {
+			t.Errorf("call %d: expected %v, got %v", i, want, err)
+		}
+	}
+	// Get isn't limited, and doesn't return an error anyway.
+	runtime.Invoke[getRet](&stub, "Get", &gets, getParams{ID: 1})
+	clock.Advance(500 * time.Millisecond)
+	if err := put(); err != nil {
+		t.Errorf("expected %v, got %v", nil, err)
+	}
+	if err := put(); err != errLimited {
+		t.Errorf("expected %v, got %v", errLimited, err)
+	}
+	if puts.Len() != 5 {
+		t.Errorf("expected %v, got %v", 5, puts.Len())
+	}
+}
+
+func TestFailRandomly(t *testing.T) {
+	errFlaky := errors.New("flaky")
+	failures := func(seed uint64) []int {
+		var stub runtime.Stub
+		var calls runtime.Calls[getParams]
+		runtime.On[getParams, putRet](&stub, "Put").FailRandomly(0.3, errFlaky, seed)
+		var failed []int
+		for i := 0; i < 100; i++ {
+			if err := runtime.Invoke[putRet](&stub, "Put", &calls, getParams{ID: i}).err; err != nil {
+				if err != errFlaky {
+					t.Errorf("expected %v, got %v", errFlaky, err)
+				}
+				failed = append(failed, i)
+			}
+		}
+		return failed
+	}
+
+	failed := failures(1)
+	if len(failed) < 15 || len(failed) > 45 {
+		t.Errorf("expected about 30 failures, got %v", len(failed))
+	}
+	if again := failures(1); !reflect.DeepEqual(again, failed) {
+		t.Errorf("expected %v, got %v", failed, again)
+	}
+	if other := failures(2); reflect.DeepEqual(other, failed) {
+		t.Errorf("expected different failures for another seed, got %v", other)
+	}
+}
+
+func TestConfigureConcurrently(t *testing.T) {
+	var stub runtime.Stub
+	var calls runtime.Calls[getParams]
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					runtime.Invoke[getRet](&stub, "Get", &calls, getParams{ID: 1})
+				}
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		e := runtime.On[getParams, getRet](&stub, "Get")
+		e.Return(getRet{R0: "a"})
+		e.ReturnOnce(getRet{R0: "b"})
+		runtime.On[getParams, getRet](&stub, "Get", 1).MaxTimes(-1)
+	}
+	close(stop)
+	wg.Wait()
+
+	// A function set with ReturnFunc can reconfigure the stub.
+	runtime.On[getParams, getRet](&stub, "Get").ReturnFunc(func(getParams) getRet {
+		runtime.On[getParams, getRet](&stub, "Get").Return(getRet{R0: "next"})
+		return getRet{R0: "first"}
+	})
+	for _, want := range []string{"first", "next"} {
+		if ret := runtime.Invoke[getRet](&stub, "Get", &calls, getParams{ID: 2}); ret.R0 != want {
+			t.Errorf("expected %v, got %v", want, ret.R0)
+		}
+	}
+}
+
+func TestReturnOnce(t *testing.T) {
+	var stub runtime.Stub
+	var calls runtime.Calls[getParams]
+	get := func(id int) string {
+		return runtime.Invoke[getRet](&stub, "Get", &calls, getParams{ID: id}).R0
+	}
+
+	exp := runtime.On[getParams, getRet](&stub, "Get")
+	exp.Return(getRet{"always"})
+	exp.ReturnOnce(getRet{"first"})
+	exp.ReturnOnce(getRet{"second"})
+
+	for _, expected := range []string{"first", "second", "always", "always"} {
+		if ret := get(1); ret != expected {
+			t.Errorf("expected %v, got %v", expected, ret)
+		}
+	}
+}
+
+func TestCalls(t *testing.T) {
+	var stub runtime.Stub
+	var a, b runtime.Calls[getParams]
+	runtime.Invoke[getRet](&stub, "A", &a, getParams{ID: 1})
+	runtime.Invoke[getRet](&stub, "B", &b, getParams{ID: 2})
+
+	calls := stub.Calls()
+	if len(calls) != 2 || calls[0].Method != "A" || calls[1].Method != "B" {
+		t.Errorf("expected calls to A then B, got %v", calls)
+	}
+	if calls[1].Args[0] != 2 {
+		t.Errorf("expected %v, got %v", 2, calls[1].Args[0])
+	}
+}
+
+type ctxParams struct {
+	Ctx context.Context
+}
+
+type ctxKey struct{}
+
+func TestCaptureContextValues(t *testing.T) {
+	var stub runtime.Stub
+	var calls runtime.Calls[ctxParams]
+	ctx := context.WithValue(context.Background(), ctxKey{}, "req-1")
+
+	runtime.Record(&stub, "Do", &calls, ctxParams{Ctx: ctx})
+	stub.CaptureContextValues(ctxKey{}, "missing")
+	runtime.Record(&stub, "Do", &calls, ctxParams{Ctx: ctx})
+
+	seq := stub.Calls()
+	if seq[0].ContextValues != nil {
+		t.Errorf("expected %v, got %v", nil, seq[0].ContextValues)
+	}
+	if len(seq[1].ContextValues) != 1 || seq[1].ContextValues[ctxKey{}] != "req-1" {
+		t.Errorf("expected %v, got %v", map[any]any{ctxKey{}: "req-1"}, seq[1].ContextValues)
+	}
+}
+
+func TestTimeoutAfter(t *testing.T) {
+	var clock runtime.Clock
+	var stub runtime.Stub
+	var calls runtime.Calls[ctxParams]
+	stub.Init("StubDoer", runtime.WithClock(&clock))
+	runtime.On[ctxParams, putRet](&stub, "Do").TimeoutAfter(time.Second)
+
+	errs := make(chan error)
+	go func() {
+		errs <- runtime.Invoke[putRet](&stub, "Do", &calls, ctxParams{Ctx: context.Background()}).err
+	}()
+	clock.WaitForTimers(1)
+	clock.Advance(time.Second)
+	if err := <-errs; err != context.DeadlineExceeded {
+		t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	// A context done first ends the call with its error.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := runtime.Invoke[putRet](&stub, "Do", &calls, ctxParams{Ctx: ctx}).err; err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestLatency(t *testing.T) {
+	ms := time.Millisecond
+	r := rand.New(rand.NewPCG(1, 0))
+	for _, tt := range []struct {
+		name     string
+		latency  runtime.Latency
+		min, max time.Duration
+	}{
+		{"fixed", runtime.FixedLatency(5 * ms), 5 * ms, 5 * ms},
+		{"uniform", runtime.UniformLatency(10*ms, 20*ms), 10 * ms, 20 * ms},
+		{"normal", runtime.NormalLatency(10*ms, 100*ms), 0, time.Hour},
+		{"percentile", runtime.PercentileLatency(map[float64]time.Duration{50: 10 * ms, 99: 100 * ms}), 10 * ms, 100 * ms},
+	} {
+		for i := 0; i < 100; i++ {
+			if d := tt.latency(r); d < tt.min || d > tt.max {
+				t.Errorf("%s: expected between %v and %v, got %v", tt.name, tt.min, tt.max, d)
+			}
+		}
+	}
+
+	var clock runtime.Clock
+	var stub runtime.Stub
+	var calls runtime.Calls[ctxParams]
+	stub.Init("StubDoer", runtime.WithClock(&clock))
+	runtime.On[ctxParams, putRet](&stub, "Do").Latency(runtime.FixedLatency(time.Second), 1)
+
+	errs := make(chan error)
+	go func() {
+		errs <- runtime.Invoke[putRet](&stub, "Do", &calls, ctxParams{Ctx: context.Background()}).err
+	}()
+	clock.WaitForTimers(1)
+	select {
+	case err := <-errs:
+		t.Errorf("expected the call to wait for the clock, got %v", err)
+	default:
+	}
+	clock.Advance(time.Second)
+	if err := <-errs; err != nil {
+		t.Errorf("expected %v, got %v", nil, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := runtime.Invoke[putRet](&stub, "Do", &calls, ctxParams{Ctx: ctx}).err; err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestPropagateContextErrors(t *testing.T) {
+	var stub runtime.Stub
+	var calls runtime.Calls[ctxParams]
+	runtime.On[ctxParams, getRet](&stub, "Do").Return(getRet{"done"})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if ret := runtime.Invoke[getRet](&stub, "Do", &calls, ctxParams{Ctx: ctx}); ret.R0 != "done" {
+		t.Errorf("expected %v, got %v", "done", ret.R0)
+	}
+	if err := stub.ContextErr(ctx); err != nil {
+		t.Errorf("expected %v, got %v", nil, err)
+	}
+
+	stub.PropagateContextErrors(true)
+	if ret := runtime.Invoke[getRet](&stub, "Do", &calls, ctxParams{Ctx: ctx}); ret.R0 != "" {
+		t.Errorf("expected %q, got %q", "", ret.R0)
+	}
+	if err := stub.ContextErr(ctx); err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+	if calls.Len() != 2 {
+		t.Errorf("expected %v, got %v", 2, calls.Len())
+	}
+}
+
+func TestContent(t *testing.T) {
+	var c runtime.Content
+	if n, err := c.Read(make([]byte, 4)); n != 0 || err != io.EOF {
+		t.Errorf("expected %v, got %v", io.EOF, err)
+	}
+
+	c.SetRead([]byte("hello"))
+	b, err := io.ReadAll(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "hello" {
+		t.Errorf("expected %q, got %q", "hello", b)
+	}
+
+	buf := []byte("ab")
+	c.Write(buf)
+	buf[0] = 'x'
+	c.Write(buf)
+	if got := string(c.Written()); got != "abxb" {
+		t.Errorf("expected %q, got %q", "abxb", got)
+	}
+}
+
+func TestClock(t *testing.T) {
+	var c runtime.Clock
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.Set(start)
+
+	late := c.After(2 * time.Minute)
+	early := c.After(time.Minute)
+	c.WaitForTimers(2)
+	c.Advance(90 * time.Second)
+	if got := <-early; !got.Equal(start.Add(time.Minute)) {
+		t.Errorf("expected %v, got %v", start.Add(time.Minute), got)
+	}
+	select {
+	case got := <-late:
+		t.Errorf("expected no time, got %v", got)
+	default:
+	}
+
+	c.Advance(30 * time.Second)
+	if got := <-late; !got.Equal(start.Add(2 * time.Minute)) {
+		t.Errorf("expected %v, got %v", start.Add(2*time.Minute), got)
+	}
+	if got := c.Since(start); got != 2*time.Minute {
+		t.Errorf("expected %v, got %v", 2*time.Minute, got)
+	}
+}
+
+func TestRows(t *testing.T) {
+	fixture := [][]any{{int64(1), "ada"}, {int64(2), "bob"}}
+	rows := runtime.NewRows([]string{"id", "name"}, fixture)
+
+	var names []any
+	dest := make([]driver.Value, 2)
+	for rows.Next(dest) == nil {
+		names = append(names, dest[1])
+	}
+	if len(names) != 2 || names[0] != "ada" || names[1] != "bob" {
+		t.Errorf("expected %v, got %v", []any{"ada", "bob"}, names)
+	}
+	if err := rows.Next(dest); err != io.EOF {
+		t.Errorf("expected %v, got %v", io.EOF, err)
+	}
+	if len(fixture) != 2 {
+		t.Errorf("expected %v, got %v", 2, len(fixture))
+	}
+}
+
+func TestNotifyCalls(t *testing.T) {
+	var stub runtime.Stub
+	var calls runtime.Calls[getParams]
+	ch := make(chan getParams, 1)
+	runtime.NotifyCalls(&stub, "Get", ch)
+
+	runtime.Invoke[getRet](&stub, "Get", &calls, getParams{ID: 1})
+	runtime.Invoke[getRet](&stub, "Get", &calls, getParams{ID: 2})
+	if call := <-ch; call.ID != 1 {
+		t.Errorf("expected %v, got %v", 1, call.ID)
+	}
+	if len(ch) != 0 {
+		t.Errorf("expected %v, got %v", 0, len(ch))
+	}
+}
+
+func TestScope(t *testing.T) {
+	var stub runtime.Stub
+	var calls runtime.Calls[getParams]
+	get := func(id int) string {
+		return runtime.Invoke[getRet](&stub, "Get", &calls, getParams{ID: id}).R0
+	}
+	runtime.On[getParams, getRet](&stub, "Get").ReturnOnce(getRet{"once"})
+	get(1)
+	runtime.On[getParams, getRet](&stub, "Get").Return(getRet{"parent"})
+
+	t.Run("sub", func(t *testing.T) {
+		stub.Scope(t, runtime.ResetCalls(&calls))
+		if calls.Len() != 0 || len(stub.Calls()) != 0 {
+			t.Errorf("expected %v, got %v", 0, calls.Len())
+		}
+		runtime.On[getParams, getRet](&stub, "Get").Return(getRet{"sub"})
+		if ret := get(2); ret != "sub" {
+			t.Errorf("expected %v, got %v", "sub", ret)
+		}
+	})
+
+	if ret := get(3); ret != "parent" {
+		t.Errorf("expected %v, got %v", "parent", ret)
+	}
+	if calls.Len() != 2 || len(stub.Calls()) != 2 {
+		t.Errorf("expected %v, got %v", 2, calls.Len())
+	}
+}
+
+func TestScopeVerifications(t *testing.T) {
+	var stub runtime.Stub
+	var calls runtime.Calls[getParams]
+	get := func(id int) {
+		runtime.Invoke[getRet](&stub, "Get", &calls, getParams{ID: id})
+	}
+	stub.Init("StubGetter")
+	runtime.Expect(&stub, "Get", 1).Never()
+	runtime.Expect(&stub, "Get", 2).Never()
+	get(1)
+
+	t.Run("sub", func(t *testing.T) {
+		stub.Scope(t)
+		if err := stub.Verify(); err != nil {
+			t.Errorf("expected %v, got %v", nil, err)
+		}
+		get(2)
+	})
+
+	// The call made in the subtest is forgotten, and the one before it
+	// restored.
+	want := "toe: StubGetter.Get expected never to be called, but was called 1 times:\n\tStubGetter.Get(1)"
+	if err := stub.Verify(); err == nil || err.Error() != want {
+		t.Errorf("expected %v, got %v", want, err)
+	}
+}
+
+func TestSnapshotConfig(t *testing.T) {
+	var stub runtime.Stub
+	var calls runtime.Calls[getParams]
+	get := func(id int) string {
+		return runtime.Invoke[getRet](&stub, "Get", &calls, getParams{ID: id}).R0
+	}
+	e := runtime.On[getParams, getRet](&stub, "Get")
+	e.ReturnOnce(getRet{"once"})
+	e.Return(getRet{"baseline"})
+	baseline := stub.SnapshotConfig()
+
+	for i := 0; i < 2; i++ {
+		if ret := get(1); ret != "once" {
+			t.Errorf("expected %v, got %v", "once", ret)
+		}
+		runtime.On[getParams, getRet](&stub, "Get").Return(getRet{"changed"})
+		if ret := get(2); ret != "changed" {
+			t.Errorf("expected %v, got %v", "changed", ret)
+		}
+		stub.RestoreConfig(baseline)
+		if ret := get(3); ret != "once" {
+			t.Errorf("expected %v, got %v", "once", ret)
+		}
+		if ret := get(4); ret != "baseline" {
+			t.Errorf("expected %v, got %v", "baseline", ret)
+		}
+		stub.RestoreConfig(baseline)
+	}
+	if calls.Len() != 8 || len(stub.Calls()) != 8 {
+		t.Errorf("expected %v, got %v", 8, calls.Len())
+	}
+}
+
+func TestCloneTo(t *testing.T) {
+	var base runtime.Stub
+	var baseCalls runtime.Calls[getParams]
+	runtime.On[getParams, getRet](&base, "Get").Return(getRet{"base"})
+	runtime.Expect(&base, "Get", 2).Never()
+	runtime.Invoke[getRet](&base, "Get", &baseCalls, getParams{ID: 1})
+
+	var clone runtime.Stub
+	var calls runtime.Calls[getParams]
+	base.CloneTo(&clone)
+	if len(clone.Calls()) != 0 {
+		t.Errorf("expected %v, got %v", 0, len(clone.Calls()))
+	}
+	runtime.On[getParams, getRet](&clone, "Get").Return(getRet{"clone"})
+	if ret := runtime.Invoke[getRet](&clone, "Get", &calls, getParams{ID: 2}); ret.R0 != "clone" {
+		t.Errorf("expected %v, got %v", "clone", ret.R0)
+	}
+	if err := clone.Verify(); err == nil {
+		t.Errorf("expected an error, got nil")
+	}
+
+	if ret := runtime.Invoke[getRet](&base, "Get", &baseCalls, getParams{ID: 3}); ret.R0 != "base" {
+		t.Errorf("expected %v, got %v", "base", ret.R0)
+	}
+	if err := base.Verify(); err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+}
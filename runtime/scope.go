@@ -0,0 +1,186 @@
+package runtime
+
+// TB is the part of testing.TB that stubs use, implemented by *testing.T
+// and *testing.B.
+type TB interface {
+	Cleanup(func())
+	Errorf(format string, args ...any)
+}
+
+// Scope scopes the stub to the test t, usually a subtest: the calls it
+// recorded are cleared, along with those its verifications matched and
+// those reset by resets, and once t finishes they are restored, along with
+// the stub's expectations and other configuration as they were when Scope
+// was called. Each of resets clears a method's calls and returns a function
+// restoring them; see ResetCalls.
+//
+// Tests sharing a stub can't run in parallel.
+func (s *Stub) Scope(t TB, resets ...func() func()) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	saved := s.snapshot()
+	restores := make([]func(), len(resets))
+	for i, reset := range resets {
+		restores[i] = reset()
+	}
+	s.calls = nil
+	for _, vs := range s.verifications {
+		for _, v := range vs {
+			v.calls = nil
+		}
+	}
+	if s.sequence != nil {
+		s.sequence.restart()
+	}
+
+	t.Cleanup(func() {
+		s.mut.Lock()
+		defer s.mut.Unlock()
+		s.restore(saved)
+		for _, restore := range restores {
+			restore()
+		}
+	})
+}
+
+// Config is a snapshot of the configuration of a stub, taken with
+// SnapshotConfig.
+type Config struct {
+	state stubState
+}
+
+// SnapshotConfig returns a snapshot of the stub's configuration: its
+// expectations and verifications and the other settings made with its
+// methods, but not the options it was initialized with.
+func (s *Stub) SnapshotConfig() Config {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return Config{state: s.snapshot()}
+}
+
+// RestoreConfig restores the stub's configuration to the snapshot c,
+// keeping the calls recorded. c may be restored again later.
+func (s *Stub) RestoreConfig(c Config) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	current := s.snapshot()
+	s.restore(c.state)
+	// Restore copies, leaving c as it is for later restores.
+	s.restore(s.snapshot())
+	s.calls = current.calls
+	for v, calls := range current.verified {
+		v.calls = calls
+	}
+}
+
+// CloneTo makes c, a Stub not yet used, a copy of s without its recorded
+// calls: it has the options s was initialized with, and copies of its
+// expectations, verifications and other settings, so that configuring or
+// calling either stub doesn't affect the other. The functions notified of
+// calls aren't copied, and the allowed sequence of calls starts afresh.
+func (s *Stub) CloneTo(c *Stub) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	c.name = s.name
+	c.strict = s.strict
+	if s.fake != nil {
+		c.fake = &fakeSource{seed: s.fake.seed, calls: make(map[string]uint64)}
+	}
+	c.recorder = s.recorder
+	c.clock = s.clock
+	c.contextKeys = s.contextKeys
+	c.propagateContextErrors = s.propagateContextErrors
+	c.expectations = make(map[string][]expectation)
+	for method, exps := range s.expectations {
+		for _, e := range exps {
+			c.expectations[method] = append(c.expectations[method], e.clone(c))
+		}
+	}
+	c.verifications = make(map[string][]*Verification)
+	for method, vs := range s.verifications {
+		for _, v := range vs {
+			c.verifications[method] = append(c.verifications[method],
+				&Verification{stub: c, method: v.method, matchers: v.matchers, never: v.never})
+		}
+	}
+	if s.sequence != nil {
+		c.sequence = s.sequence.clone()
+		c.sequence.restart()
+	}
+	for _, l := range s.rateLimits {
+		limit := *l
+		c.rateLimits = append(c.rateLimits, &limit)
+	}
+	if s.t != nil {
+		WithT(s.t)(c)
+	}
+}
+
+// stubState is a snapshot of the state of a Stub, other than its lock.
+type stubState struct {
+	calls                  []Call
+	expectations           map[string][]expectation
+	verifications          map[string][]*Verification
+	contextKeys            []any
+	propagateContextErrors bool
+	notify                 map[string]func(any)
+	sequence               *sequence
+	rateLimits             []*rateLimit
+	// verified holds the arguments of the calls each verification
+	// matched, which are recorded in the verification itself.
+	verified map[*Verification][][]any
+}
+
+// snapshot returns a copy of the state of s. s.mut must be held.
+func (s *Stub) snapshot() stubState {
+	state := stubState{
+		calls:                  s.calls,
+		contextKeys:            s.contextKeys,
+		propagateContextErrors: s.propagateContextErrors,
+		notify:                 make(map[string]func(any)),
+		expectations:           make(map[string][]expectation),
+		verifications:          make(map[string][]*Verification),
+		verified:               make(map[*Verification][][]any),
+	}
+	for method, exps := range s.expectations {
+		for _, e := range exps {
+			state.expectations[method] = append(state.expectations[method], e.clone(s))
+		}
+	}
+	for method, vs := range s.verifications {
+		state.verifications[method] = append([]*Verification(nil), vs...)
+		for _, v := range vs {
+			state.verified[v] = append([][]any(nil), v.calls...)
+		}
+	}
+	for method, fn := range s.notify {
+		state.notify[method] = fn
+	}
+	if s.sequence != nil {
+		state.sequence = s.sequence.clone()
+	}
+	for _, l := range s.rateLimits {
+		c := *l
+		state.rateLimits = append(state.rateLimits, &c)
+	}
+	return state
+}
+
+// restore restores the state of s from a snapshot. s.mut must be held.
+func (s *Stub) restore(state stubState) {
+	s.calls = state.calls
+	s.expectations = state.expectations
+	s.verifications = state.verifications
+	for v, calls := range state.verified {
+		v.calls = calls
+	}
+	s.contextKeys = state.contextKeys
+	s.propagateContextErrors = state.propagateContextErrors
+	s.notify = state.notify
+	s.sequence = state.sequence
+	s.rateLimits = state.rateLimits
+}
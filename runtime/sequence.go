@@ -0,0 +1,139 @@
+package runtime
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// sequence is the allowed order of calls set with AllowSequence, as a
+// nondeterministic automaton over its steps: state i is before step i, and
+// state len(steps) the end of the sequence.
+type sequence struct {
+	steps []sequenceStep
+	// text is the sequence as given to AllowSequence, for errors.
+	text string
+	// states are those the calls so far may have led to, and calls the
+	// methods called, for errors.
+	states []bool
+	calls  []string
+}
+
+// sequenceStep is a step of a sequence: a call to method, which may be
+// skipped if optional and repeated if repeated.
+type sequenceStep struct {
+	method   string
+	optional bool
+	repeated bool
+}
+
+// AllowSequence restricts the order in which the stub's methods named in
+// steps may be called. Each step is a method name, optionally followed by
+// "?" if the call is optional, "*" if it may be made any number of times or
+// "+" if it must be made at least once. A call to one of the methods that
+// can't continue the sequence fails the test bound with WithT, or panics if
+// there is none, and Verify fails if the calls stop before the end of the
+// sequence. Calls to the methods not named in steps aren't restricted.
+//
+// methods are the stub's methods; AllowSequence panics if steps name any
+// other, or a step is malformed.
+func (s *Stub) AllowSequence(methods []string, steps ...string) {
+	seq := &sequence{text: strings.Join(steps, ", ")}
+	for _, step := range steps {
+		st := sequenceStep{method: step}
+		switch {
+		case strings.HasSuffix(step, "?"):
+			st.method, st.optional = step[:len(step)-1], true
+		case strings.HasSuffix(step, "*"):
+			st.method, st.optional, st.repeated = step[:len(step)-1], true, true
+		case strings.HasSuffix(step, "+"):
+			// Once, then any number of times.
+			st.method = step[:len(step)-1]
+			seq.steps = append(seq.steps, st)
+			st.optional, st.repeated = true, true
+		}
+		if !slices.Contains(methods, st.method) {
+			panic(fmt.Sprintf("toe: invalid sequence step %q: no method %s", step, st.method))
+		}
+		seq.steps = append(seq.steps, st)
+	}
+	seq.restart()
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.sequence = seq
+}
+
+// restart returns the sequence to its start, before any calls.
+func (q *sequence) restart() {
+	q.states = make([]bool, len(q.steps)+1)
+	q.states[0] = true
+	q.closure(q.states)
+	q.calls = nil
+}
+
+// clone returns a copy of the sequence, with its own progress.
+func (q *sequence) clone() *sequence {
+	c := *q
+	c.states = slices.Clone(q.states)
+	c.calls = slices.Clone(q.calls)
+	return &c
+}
+
+// closure adds to states those reached from them by skipping optional
+// steps, returning states.
+func (q *sequence) closure(states []bool) []bool {
+	for i, step := range q.steps {
+		if states[i] && step.optional {
+			states[i+1] = true
+		}
+	}
+	return states
+}
+
+// call advances the sequence with a call to method, returning an error if
+// the call can't continue it. The sequence is left as it was then.
+func (q *sequence) call(stub string, method string, args []any) error {
+	if !slices.ContainsFunc(q.steps, func(step sequenceStep) bool { return step.method == method }) {
+		return nil
+	}
+
+	next := make([]bool, len(q.states))
+	ok := false
+	for i, step := range q.steps {
+		if !q.states[i] || step.method != method {
+			continue
+		}
+		if step.repeated {
+			next[i] = true
+		} else {
+			next[i+1] = true
+		}
+		ok = true
+	}
+	if !ok {
+		return fmt.Errorf("toe: %s called out of the allowed sequence %s, after %s",
+			formatCall(stub, method, args), q.text, q.history())
+	}
+	q.states = q.closure(next)
+	q.calls = append(q.calls, method)
+	return nil
+}
+
+// err returns an error if the calls so far stop before the end of the
+// sequence, and nil otherwise.
+func (q *sequence) err(stub string) error {
+	if q.states[len(q.steps)] {
+		return nil
+	}
+	return fmt.Errorf("toe: %s's calls stopped before the end of the allowed sequence %s, after %s",
+		stub, q.text, q.history())
+}
+
+// history describes the calls made in the sequence.
+func (q *sequence) history() string {
+	if len(q.calls) == 0 {
+		return "no calls"
+	}
+	return strings.Join(q.calls, ", ")
+}
@@ -0,0 +1,80 @@
+package runtime
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+)
+
+// MethodUsage is how a method of a stub was used by the tests of a run,
+// reported by WriteUsage.
+type MethodUsage struct {
+	Stub   string `json:"stub"`
+	Method string `json:"method"`
+	// Configured is the number of expectations added with On<Method>,
+	// Called the number of calls, and Asserted the number of checks on the
+	// calls: verifications added with Expect<Method> and calls to
+	// Assert<Method>CalledWith.
+	Configured int `json:"configured"`
+	Called     int `json:"called"`
+	Asserted   int `json:"asserted"`
+}
+
+// usage collects the MethodUsage of every stub once CollectUsage is called.
+var usage struct {
+	mut     sync.Mutex
+	methods map[[2]string]*MethodUsage
+}
+
+// CollectUsage starts collecting how the methods of every stub are used,
+// for WriteUsage, usually from TestMain before running the tests.
+func CollectUsage() {
+	usage.mut.Lock()
+	defer usage.mut.Unlock()
+	if usage.methods == nil {
+		usage.methods = make(map[[2]string]*MethodUsage)
+	}
+}
+
+// WriteUsage writes the usage collected since CollectUsage was called to w,
+// as a JSON list of MethodUsage sorted by stub and method.
+func WriteUsage(w io.Writer) error {
+	usage.mut.Lock()
+	report := make([]MethodUsage, 0, len(usage.methods))
+	for _, u := range usage.methods {
+		report = append(report, *u)
+	}
+	usage.mut.Unlock()
+
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].Stub != report[j].Stub {
+			return report[i].Stub < report[j].Stub
+		}
+		return report[i].Method < report[j].Method
+	})
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// useMethod counts a use of method of the stub named stub with count, if
+// usage is being collected.
+func useMethod(stub string, method string, count func(*MethodUsage)) {
+	usage.mut.Lock()
+	defer usage.mut.Unlock()
+	if usage.methods == nil {
+		return
+	}
+	key := [2]string{stub, method}
+	u, ok := usage.methods[key]
+	if !ok {
+		u = &MethodUsage{Stub: stub, Method: method}
+		usage.methods[key] = u
+	}
+	count(u)
+}
+
+func configured(u *MethodUsage) { u.Configured++ }
+func called(u *MethodUsage)     { u.Called++ }
+func asserted(u *MethodUsage)   { u.Asserted++ }
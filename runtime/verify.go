@@ -0,0 +1,106 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Verification is a check on the calls to a method whose arguments match,
+// made by Verify. Unlike an Expectation, it doesn't affect the results of
+// the calls.
+type Verification struct {
+	stub     *Stub
+	method   string
+	matchers []Matcher
+	never    bool
+	// calls holds the arguments of each matching call.
+	calls [][]any
+}
+
+// Expect adds a verification of the calls to method whose arguments match
+// args, which are as for On. With no args, every call to method is matched.
+func Expect(s *Stub, method string, args ...any) *Verification {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	useMethod(s.name, method, asserted)
+	v := &Verification{stub: s, method: method}
+	for _, arg := range args {
+		v.matchers = append(v.matchers, matcherFor(arg))
+	}
+	if s.verifications == nil {
+		s.verifications = make(map[string][]*Verification)
+	}
+	s.verifications[method] = append(s.verifications[method], v)
+	return v
+}
+
+// Never makes Verify fail if any matching call is made.
+func (v *Verification) Never() {
+	v.stub.mut.Lock()
+	defer v.stub.mut.Unlock()
+	v.never = true
+}
+
+// err returns the error describing how the calls fail the verification, or
+// nil if they pass. The stub's lock must be held.
+func (v *Verification) err() error {
+	if !v.never || len(v.calls) == 0 {
+		return nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "toe: %s.%s expected never to be called, but was called %d times:",
+		v.stub.name, v.method, len(v.calls))
+	for _, args := range v.calls {
+		b.WriteString("\n\t" + formatCall(v.stub.name, v.method, args))
+	}
+	return errors.New(b.String())
+}
+
+// Verify checks the calls made to the stub against the verifications added
+// with Expect, and that they reached the end of the sequence set with
+// AllowSequence, returning an error describing those that fail, or nil. Stubs
+// bound to a test with WithT are verified when the test finishes.
+func (s *Stub) Verify() error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	var methods []string
+	for method := range s.verifications {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	var errs []error
+	for _, method := range methods {
+		for _, v := range s.verifications[method] {
+			if err := v.err(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if s.sequence != nil {
+		if err := s.sequence.err(s.name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// matchVerifications returns the verifications a call to method with args
+// matches. As with matchExpectation, the matchers run without s.mut held.
+func (s *Stub) matchVerifications(method string, args []any) []*Verification {
+	s.mut.Lock()
+	verifications := append([]*Verification(nil), s.verifications[method]...)
+	s.mut.Unlock()
+
+	var verified []*Verification
+	for _, v := range verifications {
+		if matchAll(v.matchers, args) {
+			verified = append(verified, v)
+		}
+	}
+	return verified
+}
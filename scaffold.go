@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/phildrip/toe/generator"
+)
+
+// runScaffold implements the scaffold-test command, which writes a
+// table-driven test skeleton for a service, wired with the stubs of its
+// dependencies.
+func runScaffold(args []string) {
+	fs := flag.NewFlagSet("scaffold-test", flag.ExitOnError)
+	var outputFile string
+	var deps string
+	var postCmd string
+	var assertions string
+	var configFile string
+	fs.StringVar(&outputFile, "o", "", "output file name")
+	fs.StringVar(&deps, "deps", "", "comma-separated interfaces whose stubs the service is created with")
+	fs.StringVar(&postCmd, "post-cmd", "",
+		"command run with sh after the output file is written; {{.Output}} expands to its name")
+	fs.StringVar(&assertions, "assertions", "",
+		"library the tests check results with: std (the default), testify, cmp or quicktest")
+	fs.StringVar(&configFile, "config", "", "JSON file of generation settings")
+	addBuildFlags(fs)
+	args = parseInterspersed(fs, args)
+
+	if len(args) != 2 {
+		fmt.Fprintf(os.Stderr,
+			"Usage: %s scaffold-test [-deps <interface>,...] -o <output_test.go> <input_directory> <type>\n",
+			os.Args[0])
+		os.Exit(1)
+	}
+
+	if assertions == "" && configFile != "" {
+		cfg, err := loadConfig(configFile)
+		if err != nil {
+			fatal("loading config", err)
+		}
+		assertions = cfg.Assertions
+	}
+
+	model, err := generator.Load(args[0])
+	if err != nil {
+		fatal("finding service", err)
+	}
+	files, err := generator.Scaffold(model, args[1], splitList(deps),
+		generator.Options{Output: outputFile, Assertions: assertions})
+	if err != nil {
+		fatal("generating test", err)
+	}
+	writeFiles(files, postCmd)
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/phildrip/toe/server"
+)
+
+// runServe implements the serve command, which answers JSON-RPC requests on
+// stdin and stdout until stdin is closed.
+func runServe(args []string) {
+	if len(args) != 0 {
+		fmt.Fprintf(os.Stderr, "Usage: %s serve\n", os.Args[0])
+		os.Exit(1)
+	}
+	server.New().ServeConn(stdio{os.Stdin, os.Stdout})
+}
+
+// stdio is a connection reading from stdin and writing to stdout.
+type stdio struct {
+	io.Reader
+	io.Writer
+}
+
+func (stdio) Close() error {
+	return nil
+}
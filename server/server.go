@@ -0,0 +1,382 @@
+// Package server answers toe requests over JSON-RPC, keeping loaded
+// packages in memory between requests so that editor plugins and watch
+// tooling don't pay for loading a package on every request.
+//
+// The server implements the JSON-RPC 1.0 protocol of net/rpc/jsonrpc, with
+// the methods Toe.List, Toe.Generate, Toe.Check and Toe.CodeAction.
+package server
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phildrip/toe/generator"
+	"github.com/phildrip/toe/model"
+)
+
+// Server answers requests, caching the packages it loads.
+type Server struct {
+	mu sync.Mutex
+	// pkgs holds the loaded packages by directory, and dirs the
+	// directories of the packages loaded by import path.
+	pkgs map[string]*cachedPackage
+	dirs map[string]string
+}
+
+type cachedPackage struct {
+	pkg *model.Package
+	// modTimes are the modification times of the package's Go files
+	// when it was loaded.
+	modTimes map[string]time.Time
+}
+
+// New returns a new server with an empty cache.
+func New() *Server {
+	return &Server{
+		pkgs: make(map[string]*cachedPackage),
+		dirs: make(map[string]string),
+	}
+}
+
+// ServeConn answers the requests read from conn until it is closed.
+func (s *Server) ServeConn(conn io.ReadWriteCloser) {
+	srv := rpc.NewServer()
+	if err := srv.RegisterName("Toe", &service{s}); err != nil {
+		panic(err)
+	}
+	srv.ServeCodec(jsonrpc.NewServerCodec(conn))
+}
+
+// Load returns the package in dir, loading it if it isn't cached or any
+// of its Go files have changed since it was. Packages are loaded without
+// holding the server's lock, so that requests for other packages aren't
+// held up meanwhile.
+func (s *Server) Load(dir string) (*model.Package, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	cached, ok := s.pkgs[dir]
+	s.mu.Unlock()
+	if ok && !cached.stale() {
+		return cached.pkg, nil
+	}
+	// The files are checked before loading, so that changes made while
+	// the package loads make it stale.
+	times := modTimes(dir)
+	pkg, err := model.Load(dir)
+	if err != nil {
+		return nil, err
+	}
+	s.store(pkg, times)
+	return pkg, nil
+}
+
+// LoadImport returns the package with the given import path resolved from
+// dir, like Load.
+func (s *Server) LoadImport(dir string, path string) (*model.Package, error) {
+	s.mu.Lock()
+	cached, ok := s.pkgs[s.dirs[path]]
+	s.mu.Unlock()
+	if ok && !cached.stale() {
+		return cached.pkg, nil
+	}
+	pkg, err := model.LoadImport(dir, path)
+	if err != nil {
+		return nil, err
+	}
+	s.store(pkg, modTimes(pkg.Dir))
+	return pkg, nil
+}
+
+// store caches pkg, whose Go files had the modification times times when
+// it was loaded.
+func (s *Server) store(pkg *model.Package, times map[string]time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pkgs[pkg.Dir] = &cachedPackage{pkg: pkg, modTimes: times}
+	s.dirs[pkg.Path] = pkg.Dir
+}
+
+// stale reports whether a Go file in the package's directory has been
+// added, removed or modified since it was loaded. Changes to its
+// dependencies are not detected.
+func (c *cachedPackage) stale() bool {
+	current := modTimes(c.pkg.Dir)
+	if len(current) != len(c.modTimes) {
+		return true
+	}
+	for name, t := range current {
+		if !c.modTimes[name].Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// modTimes returns the modification times of the Go files in dir.
+func modTimes(dir string) map[string]time.Time {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	times := make(map[string]time.Time)
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			times[file] = info.ModTime()
+		}
+	}
+	return times
+}
+
+// service holds the methods exposed over RPC.
+type service struct {
+	s *Server
+}
+
+// ListArgs are the arguments of Toe.List.
+type ListArgs struct {
+	Dir string `json:"dir"`
+}
+
+// ListReply is the result of Toe.List.
+type ListReply struct {
+	Package *model.Package `json:"package"`
+}
+
+// List returns the interfaces of the package in args.Dir.
+func (svc *service) List(args ListArgs, reply *ListReply) error {
+	pkg, err := svc.s.Load(args.Dir)
+	if err != nil {
+		return err
+	}
+	reply.Package = pkg
+	return nil
+}
+
+// GenerateArgs are the arguments of Toe.Generate.
+type GenerateArgs struct {
+	Dir     string            `json:"dir"`
+	Options generator.Options `json:"options"`
+	// Write writes the generated files as well as returning them. Their
+	// names are relative to Dir.
+	Write bool `json:"write"`
+}
+
+// File is a generated file.
+type File struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// GenerateReply is the result of Toe.Generate.
+type GenerateReply struct {
+	Files []File `json:"files"`
+}
+
+// Generate generates code for an interface in the package in args.Dir.
+func (svc *service) Generate(args GenerateArgs, reply *GenerateReply) error {
+	pkg, err := svc.s.Load(args.Dir)
+	if err != nil {
+		return err
+	}
+	files, err := generator.Generate(pkg, args.Options)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if args.Write {
+			name := f.Name
+			if !filepath.IsAbs(name) {
+				name = filepath.Join(args.Dir, name)
+			}
+			if existing, err := os.ReadFile(name); err == nil {
+				if f.Content, err = generator.Keep(name, existing, f.Content); err != nil {
+					return err
+				}
+			}
+			if err := os.WriteFile(name, f.Content, 0644); err != nil {
+				return err
+			}
+		}
+		reply.Files = append(reply.Files, File{Name: f.Name, Content: string(f.Content)})
+	}
+	return nil
+}
+
+// CheckArgs are the arguments of Toe.Check.
+type CheckArgs struct {
+	Dir string `json:"dir"`
+}
+
+// StaleFile is a generated file whose interface has changed since it was
+// generated.
+type StaleFile struct {
+	File string `json:"file"`
+	// Interface is the interface's import path and name.
+	Interface string `json:"interface"`
+	// Pos is the position of the interface's declaration, if it still
+	// exists.
+	Pos string `json:"pos,omitempty"`
+}
+
+// CheckReply is the result of Toe.Check.
+type CheckReply struct {
+	Stale []StaleFile `json:"stale"`
+}
+
+// Check returns the files in args.Dir generated from interfaces that have
+// changed since.
+func (svc *service) Check(args CheckArgs, reply *CheckReply) error {
+	files, err := filepath.Glob(filepath.Join(args.Dir, "*.go"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	reply.Stale = []StaleFile{}
+	fset := token.NewFileSet()
+	for _, file := range files {
+		f, err := parser.ParseFile(fset, file, nil, parser.PackageClauseOnly|parser.ParseComments)
+		if err != nil {
+			return err
+		}
+		h, ok := generator.ParseHeader(f)
+		if !ok {
+			continue
+		}
+		for _, recorded := range h.Interfaces() {
+			stale := StaleFile{File: file, Interface: recorded.Path + "." + recorded.Name}
+
+			pkg, err := svc.s.LoadImport(args.Dir, recorded.Path)
+			if err != nil {
+				reply.Stale = append(reply.Stale, stale)
+				continue
+			}
+			iface, err := pkg.Lookup(recorded.Name)
+			if err != nil {
+				reply.Stale = append(reply.Stale, stale)
+				continue
+			}
+			if iface.Hash != recorded.Hash {
+				stale.Pos = iface.Pos.String()
+				reply.Stale = append(reply.Stale, stale)
+			}
+		}
+	}
+	return nil
+}
+
+// CodeActionArgs are the arguments of Toe.CodeAction.
+type CodeActionArgs struct {
+	File string `json:"file"`
+	// Line and Column are the 1-based position of the cursor in File.
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	// Options are the options to generate code with. Interface and Output
+	// are set by the code action.
+	Options generator.Options `json:"options"`
+}
+
+// CodeActionReply is the result of Toe.CodeAction.
+type CodeActionReply struct {
+	// Interface is the name of the interface enclosing the cursor.
+	Interface string `json:"interface"`
+	// Output is the suggested name of the generated file, next to File.
+	Output string `json:"output"`
+	Files  []File `json:"files"`
+}
+
+// CodeAction calls Server.CodeAction.
+func (svc *service) CodeAction(args CodeActionArgs, reply *CodeActionReply) error {
+	result, err := svc.s.CodeAction(args)
+	if err != nil {
+		return err
+	}
+	*reply = *result
+	return nil
+}
+
+// CodeAction generates code for the interface declaration enclosing the
+// cursor, for editors' "Generate stub" code actions. The files are not
+// written.
+func (s *Server) CodeAction(args CodeActionArgs) (*CodeActionReply, error) {
+	name, err := InterfaceAt(args.File, args.Line, args.Column)
+	if err != nil {
+		return nil, err
+	}
+	pkg, err := s.Load(filepath.Dir(args.File))
+	if err != nil {
+		return nil, err
+	}
+
+	opts := args.Options
+	if opts.Style == "" {
+		opts.Style = "stub"
+	}
+	opts.Interface = name
+	opts.Output = filepath.Join(filepath.Dir(args.File),
+		strings.ReplaceAll(opts.Style, "-", "_")+"_"+strings.ToLower(name)+".go")
+	files, err := generator.Generate(pkg, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	reply := &CodeActionReply{Interface: name, Output: opts.Output}
+	for _, f := range files {
+		reply.Files = append(reply.Files, File{Name: f.Name, Content: string(f.Content)})
+	}
+	return reply, nil
+}
+
+// InterfaceAt returns the name of the interface whose declaration
+// encloses the 1-based line and column in file.
+func InterfaceAt(file string, line int, column int) (string, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, file, nil, parser.ParseComments)
+	if err != nil {
+		return "", err
+	}
+
+	tf := fset.File(f.Pos())
+	if line < 1 || line > tf.LineCount() {
+		return "", fmt.Errorf("%s has no line %d", file, line)
+	}
+	pos := tf.LineStart(line) + token.Pos(column-1)
+
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			ts := spec.(*ast.TypeSpec)
+			if _, ok := ts.Type.(*ast.InterfaceType); !ok {
+				continue
+			}
+			// A lone spec's declaration includes its doc comment and the
+			// type keyword.
+			start, end := ts.Pos(), ts.End()
+			if len(gen.Specs) == 1 {
+				start, end = gen.Pos(), gen.End()
+				if gen.Doc != nil {
+					start = gen.Doc.Pos()
+				}
+			}
+			if start <= pos && pos <= end {
+				return ts.Name.Name, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%s:%d:%d is not in an interface declaration", file, line, column)
+}
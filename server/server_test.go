@@ -0,0 +1,94 @@
+package server_test
+
+import (
+	"net"
+	"net/rpc/jsonrpc"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/phildrip/toe/server"
+)
+
+func TestServer(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	go server.New().ServeConn(serverConn)
+	client := jsonrpc.NewClient(clientConn)
+	defer client.Close()
+
+	var list server.ListReply
+	if err := client.Call("Toe.List", server.ListArgs{Dir: "../ref"}, &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Package.Interfaces) != 1 || list.Package.Interfaces[0].Name != "Thinger" {
+		t.Errorf("expected %v, got %+v", "Thinger", list.Package.Interfaces)
+	}
+
+	var gen server.GenerateReply
+	args := server.GenerateArgs{Dir: "../ref"}
+	args.Options.Interface = "Thinger"
+	args.Options.Style = "noop"
+	if err := client.Call("Toe.Generate", args, &gen); err != nil {
+		t.Fatal(err)
+	}
+	if len(gen.Files) != 1 {
+		t.Errorf("expected %v, got %v", 1, len(gen.Files))
+	}
+
+	var check server.CheckReply
+	if err := client.Call("Toe.Check", server.CheckArgs{Dir: "../ref/stubs"}, &check); err != nil {
+		t.Fatal(err)
+	}
+	if len(check.Stale) != 0 {
+		t.Errorf("expected %v, got %+v", 0, check.Stale)
+	}
+}
+
+func TestServerGenerateWrite(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	go server.New().ServeConn(serverConn)
+	client := jsonrpc.NewClient(clientConn)
+	defer client.Close()
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"go.mod":   "module example.com/store\n\ngo 1.22\n",
+		"store.go": "package store\n\ntype Store interface {\n\tGet(key string) (string, error)\n}\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The file is written into the package's directory, not the server's.
+	var gen server.GenerateReply
+	args := server.GenerateArgs{Dir: dir, Write: true}
+	args.Options.Interface = "Store"
+	args.Options.Style = "noop"
+	args.Options.Output = "noop_store.go"
+	if err := client.Call("Toe.Generate", args, &gen); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "noop_store.go"))
+	if err != nil || len(gen.Files) != 1 || string(b) != gen.Files[0].Content {
+		t.Errorf("expected %v, got %q (%v)", "the generated file", b, err)
+	}
+	if _, err := os.Stat("noop_store.go"); !os.IsNotExist(err) {
+		t.Errorf("expected %v, got %v", "no file in the working directory", err)
+	}
+}
+
+func TestInterfaceAt(t *testing.T) {
+	// Line 9 is inside the declaration of Thinger.
+	name, err := server.InterfaceAt("../ref/thinger.go", 9, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "Thinger" {
+		t.Errorf("expected %v, got %v", "Thinger", name)
+	}
+
+	if _, err := server.InterfaceAt("../ref/thinger.go", 1, 1); err == nil {
+		t.Errorf("expected an error outside an interface declaration")
+	}
+}
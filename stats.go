@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/phildrip/toe/generator"
+	"github.com/phildrip/toe/model"
+)
+
+// stats is the inventory of the generated files in a tree, printed by the
+// stats command.
+type stats struct {
+	// Interfaces is the number of distinct interfaces code was generated
+	// from, and Files the number of generated files.
+	Interfaces int            `json:"interfaces"`
+	Files      int            `json:"files"`
+	Packages   []packageStats `json:"packages"`
+	// Styles holds the number of generated files of each style.
+	Styles map[string]int `json:"styles"`
+	Stale  []staleFile    `json:"stale"`
+}
+
+// packageStats counts the generated files in a package directory.
+type packageStats struct {
+	Dir        string `json:"dir"`
+	Interfaces int    `json:"interfaces"`
+	Files      int    `json:"files"`
+}
+
+// staleFile is a generated file whose interface has changed or is gone.
+type staleFile struct {
+	File      string `json:"file"`
+	Interface string `json:"interface"`
+	Reason    string `json:"reason"`
+}
+
+// runStats implements the stats command, which reports how many interfaces
+// have stubs generated by toe in a tree, by package and by style, and which
+// of the generated files are stale.
+func runStats(args []string) {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	var asJSON bool
+	fs.BoolVar(&asJSON, "json", false, "print the statistics as JSON")
+	addBuildFlags(fs)
+	args = parseInterspersed(fs, args)
+
+	if len(args) > 1 {
+		fmt.Fprintf(os.Stderr, "Usage: %s stats [-json] [<directory>]\n", os.Args[0])
+		os.Exit(1)
+	}
+	root := "."
+	if len(args) == 1 {
+		root = args[0]
+	}
+
+	s, err := collectStats(root)
+	if err != nil {
+		fatal("collecting statistics", err)
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(s); err != nil {
+			fatal("writing statistics", err)
+		}
+		return
+	}
+
+	fmt.Printf("%d interfaces stubbed in %d files across %d packages\n", s.Interfaces, s.Files, len(s.Packages))
+	if len(s.Packages) > 0 {
+		fmt.Println("packages:")
+		for _, p := range s.Packages {
+			fmt.Printf("  %s: %d interfaces, %d files\n", p.Dir, p.Interfaces, p.Files)
+		}
+	}
+	if len(s.Styles) > 0 {
+		fmt.Println("styles:")
+		styles := make([]string, 0, len(s.Styles))
+		for style := range s.Styles {
+			styles = append(styles, style)
+		}
+		sort.Strings(styles)
+		for _, style := range styles {
+			fmt.Printf("  %s: %d files\n", style, s.Styles[style])
+		}
+	}
+	if len(s.Stale) > 0 {
+		fmt.Printf("stale: %d files\n", len(s.Stale))
+		for _, f := range s.Stale {
+			fmt.Printf("  %s: %s %s\n", f.File, f.Interface, f.Reason)
+		}
+	}
+}
+
+// collectStats reads the headers of the Go files under root, skipping
+// directories the go command ignores, and checks each generated file's
+// interface against its recorded hash.
+func collectStats(root string) (*stats, error) {
+	s := &stats{Styles: make(map[string]int), Packages: []packageStats{}, Stale: []staleFile{}}
+	interfaces := make(map[string]bool)
+	dirInterfaces := make(map[string]map[string]bool)
+	dirFiles := make(map[string]int)
+	pkgs := make(map[string]*model.Package)
+	fset := token.NewFileSet()
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != root && (name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+		f, err := parser.ParseFile(fset, path, nil, parser.PackageClauseOnly|parser.ParseComments)
+		if err != nil {
+			return err
+		}
+		h, ok := generator.ParseHeader(f)
+		if !ok {
+			return nil
+		}
+
+		dir := filepath.ToSlash(filepath.Dir(path))
+		s.Files++
+		s.Styles[h.Style]++
+		dirFiles[dir]++
+		if dirInterfaces[dir] == nil {
+			dirInterfaces[dir] = make(map[string]bool)
+		}
+		for _, recorded := range h.Interfaces() {
+			iface := recorded.Path + "." + recorded.Name
+			interfaces[iface] = true
+			dirInterfaces[dir][iface] = true
+
+			pkg, ok := pkgs[recorded.Path]
+			if !ok {
+				pkg, _ = model.LoadImport(filepath.Dir(path), recorded.Path)
+				pkgs[recorded.Path] = pkg
+			}
+			stale := staleFile{File: filepath.ToSlash(path), Interface: iface}
+			if pkg == nil {
+				stale.Reason = "cannot be loaded"
+				s.Stale = append(s.Stale, stale)
+				continue
+			}
+			current, err := pkg.Lookup(recorded.Name)
+			switch {
+			case err != nil:
+				stale.Reason = "no longer exists"
+				s.Stale = append(s.Stale, stale)
+			case current.Hash != recorded.Hash:
+				stale.Reason = "has changed"
+				s.Stale = append(s.Stale, stale)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.Interfaces = len(interfaces)
+	for dir, files := range dirFiles {
+		s.Packages = append(s.Packages, packageStats{Dir: dir, Interfaces: len(dirInterfaces[dir]), Files: files})
+	}
+	sort.Slice(s.Packages, func(i, j int) bool { return s.Packages[i].Dir < s.Packages[j].Dir })
+	return s, nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/phildrip/toe/generator"
+)
+
+// runTemplate implements the template command, whose check subcommand
+// generates code with a template for synthetic interfaces and type-checks
+// it, so that template authors get fast feedback.
+func runTemplate(args []string) {
+	if len(args) == 0 || args[0] != "check" {
+		fmt.Fprintf(os.Stderr, "Usage: %s template check [-style <style>] [-template-dir <dir>] "+
+			"[-template-module <path@version>] [-funcs <plugin.so>] [<template.tmpl>]\n", os.Args[0])
+		os.Exit(1)
+	}
+	fs := flag.NewFlagSet("template check", flag.ExitOnError)
+	var opts generator.Options
+	fs.StringVar(&opts.Style, "style", "stub", "style the template is for")
+	fs.StringVar(&opts.PartialsDir, "template-dir", "", "directory of partial templates overriding parts of the template")
+	fs.StringVar(&opts.TemplateModule, "template-module", "",
+		"module, as path@version, providing the templates; the template and -template-dir are relative to its root")
+	fs.StringVar(&opts.FuncsPlugin, "funcs", "", "Go plugin adding functions to the templates")
+	fs.StringVar(&opts.ArgNaming, "arg-naming", "", "argument naming scheme: param, arg or camel")
+	addBuildFlags(fs)
+	args = parseInterspersed(fs, args[1:])
+
+	if len(args) > 1 {
+		fmt.Fprintf(os.Stderr, "%s template check: at most one template may be given\n", os.Args[0])
+		os.Exit(1)
+	}
+	if len(args) == 1 {
+		opts.TemplateFile = args[0]
+	}
+	if err := generator.CheckTemplate(opts, "."); err != nil {
+		fatal("checking template", err)
+	}
+	fmt.Println("the template generates code that type-checks")
+}
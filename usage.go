@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/phildrip/toe/runtime"
+)
+
+// runUsage implements the usage command, which merges the usage reports
+// written by runtime.WriteUsage, such as from the tests of several
+// packages, and lists the stubbed methods whose calls are never asserted.
+func runUsage(args []string) {
+	fs := flag.NewFlagSet("usage", flag.ExitOnError)
+	var asJSON, all bool
+	fs.BoolVar(&asJSON, "json", false, "print the merged report as JSON")
+	fs.BoolVar(&all, "all", false, "list every method, not only those never asserted")
+	args = parseInterspersed(fs, args)
+
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Usage: %s usage [-json] [-all] <report.json>...\n", os.Args[0])
+		os.Exit(1)
+	}
+
+	report, err := mergeUsage(args)
+	if err != nil {
+		fatal("reading usage reports", err)
+	}
+	if !all {
+		var unasserted []runtime.MethodUsage
+		for _, u := range report {
+			if u.Asserted == 0 {
+				unasserted = append(unasserted, u)
+			}
+		}
+		report = unasserted
+	}
+
+	if asJSON {
+		if report == nil {
+			report = []runtime.MethodUsage{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fatal("writing usage report", err)
+		}
+		return
+	}
+	if len(report) == 0 {
+		fmt.Println("every stubbed method used is asserted")
+		return
+	}
+	for _, u := range report {
+		fmt.Printf("%s.%s: configured %d times, called %d times, asserted %d times\n",
+			u.Stub, u.Method, u.Configured, u.Called, u.Asserted)
+	}
+}
+
+// mergeUsage reads the usage reports in files, adding up the usage of each
+// method, sorted by stub and method.
+func mergeUsage(files []string) ([]runtime.MethodUsage, error) {
+	merged := make(map[[2]string]*runtime.MethodUsage)
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var report []runtime.MethodUsage
+		if err := json.Unmarshal(b, &report); err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		for _, u := range report {
+			key := [2]string{u.Stub, u.Method}
+			m, ok := merged[key]
+			if !ok {
+				m = &runtime.MethodUsage{Stub: u.Stub, Method: u.Method}
+				merged[key] = m
+			}
+			m.Configured += u.Configured
+			m.Called += u.Called
+			m.Asserted += u.Asserted
+		}
+	}
+
+	report := make([]runtime.MethodUsage, 0, len(merged))
+	for _, u := range merged {
+		report = append(report, *u)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].Stub != report[j].Stub {
+			return report[i].Stub < report[j].Stub
+		}
+		return report[i].Method < report[j].Method
+	})
+	return report, nil
+}